@@ -24,20 +24,27 @@ import (
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
 // MeshServiceSpec defines the desired state of MeshService
+// +kubebuilder:validation:XValidation:rule="!has(self.subsets) || self.subsets.all(s, !has(s.weight)) || self.subsets.map(s, has(s.weight) ? s.weight : 0).sum() == 100",message="subset weights must sum to 100 when any weight is set"
 type MeshServiceSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
 	// ServiceName is the name of the Kubernetes Service
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
 	ServiceName string `json:"serviceName"`
 
-	// Namespace where the service is deployed
-	Namespace string `json:"namespace"`
+	// Namespace where the service is deployed, defaults to the namespace of the MeshService
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
 
 	// Ports defines the service ports
+	// +kubebuilder:validation:MinItems=1
 	Ports []ServicePort `json:"ports"`
 
 	// Hosts for the VirtualService
+	// +kubebuilder:validation:items:MaxLength=253
+	// +kubebuilder:validation:items:Pattern=`^(\*|(\*\.)?[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*)$`
 	Hosts []string `json:"hosts"`
 
 	// Gateway reference
@@ -47,17 +54,32 @@ type MeshServiceSpec struct {
 	TrafficPolicy *TrafficPolicy `json:"trafficPolicy,omitempty"`
 
 	// Subsets for destination rules
+	// +kubebuilder:validation:MaxItems=32
+	// +listType=map
+	// +listMapKey=name
 	Subsets []Subset `json:"subsets,omitempty"`
 }
 
 type ServicePort struct {
-	Name       string `json:"name"`
-	Port       int32  `json:"port"`
-	TargetPort int32  `json:"targetPort"`
-	Protocol   string `json:"protocol"`
+	// Name defaults to the protocol and port, e.g. http-80
+	// +optional
+	Name string `json:"name,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+	// TargetPort defaults to Port
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	TargetPort int32 `json:"targetPort,omitempty"`
+	// Protocol is an Istio port protocol, case-insensitive as in Istio, defaults to TCP
+	// +kubebuilder:validation:XValidation:rule="self.upperAscii() in ['HTTP', 'HTTPS', 'HTTP2', 'GRPC', 'GRPC-WEB', 'TCP', 'TLS', 'MONGO', 'MYSQL', 'REDIS', 'UDP']",message="unsupported Istio port protocol"
+	// +optional
+	Protocol string `json:"protocol,omitempty"`
 }
 
 type GatewayReference struct {
+	// +kubebuilder:validation:MinLength=1
 	Name      string `json:"name"`
 	Namespace string `json:"namespace"`
 }
@@ -67,13 +89,17 @@ type TrafficPolicy struct {
 }
 
 type LoadBalancerSettings struct {
+	// +kubebuilder:validation:Enum=UNSPECIFIED;RANDOM;PASSTHROUGH;ROUND_ROBIN;LEAST_REQUEST;LEAST_CONN
 	Simple string `json:"simple,omitempty"` // ROUND_ROBIN, LEAST_CONN, etc.
 }
 
 type Subset struct {
+	// +kubebuilder:validation:MinLength=1
 	Name   string            `json:"name"`
 	Labels map[string]string `json:"labels"`
-	Weight int32             `json:"weight,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Weight int32 `json:"weight,omitempty"`
 }
 
 // MeshServiceStatus defines the observed state of MeshService
@@ -119,6 +145,8 @@ type RepairAction struct {
 	Resource string `json:"resource"`
 	Action   string `json:"action"`
 	Reason   string `json:"reason"`
+	// Manifest is the YAML of the object a Create action adds
+	Manifest string `json:"manifest,omitempty"`
 }
 
 type ManagedResource struct {
@@ -0,0 +1,240 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+	integrityreport "github.com/mdarin/istio-integrity-operator/internal/integrity/report"
+)
+
+// runCheck runs the integrity checks against the cluster, exits with 1 on Error violations
+func runCheck(args []string, stdout, stderr io.Writer) int {
+	var opts options
+	flags := newFlagSet("check", stderr, &opts)
+	positional, code, ok := parseArgs(flags, args, &opts)
+	if !ok {
+		return code
+	}
+	if len(positional) > 0 {
+		fmt.Fprintln(stderr, "kubectl-mesh: check takes no arguments")
+		return exitFailure
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clusterTimeout)
+	defer cancel()
+	db, report, err := opts.checkedDB(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "kubectl-mesh: %v\n", err)
+		return exitFailure
+	}
+	defer db.Close()
+
+	if opts.output == outputJSON {
+		err = integrityreport.WriteJSON(stdout, report, nil)
+	} else {
+		for _, violation := range report.Violations {
+			fmt.Fprintf(stdout, "%s: %s %s: %s\n", strings.ToLower(violation.Severity), violation.Type, violation.Resource, violation.Message)
+		}
+		for _, repair := range report.RepairPlans {
+			fmt.Fprintf(stdout, "repair: %s %s: %s\n", repair.Type, repair.Resource, repair.Action)
+		}
+		errorCount := countErrors(report.Violations)
+		fmt.Fprintf(stdout, "%d error(s), %d warning(s), %d repair action(s)\n",
+			errorCount, len(report.Violations)-errorCount, len(report.RepairPlans))
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "kubectl-mesh: %v\n", err)
+		return exitFailure
+	}
+	if countErrors(report.Violations) > 0 {
+		return exitViolations
+	}
+	return exitOK
+}
+
+// runWhy explains the violations, repair plans and references of one resource
+func runWhy(args []string, stdout, stderr io.Writer) int {
+	var opts options
+	flags := newFlagSet("why", stderr, &opts)
+	positional, code, ok := parseArgs(flags, args, &opts)
+	if !ok {
+		return code
+	}
+	resource, err := resourceArg(positional)
+	if err != nil {
+		fmt.Fprintf(stderr, "kubectl-mesh: %v\n", err)
+		return exitFailure
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clusterTimeout)
+	defer cancel()
+	db, report, err := opts.checkedDB(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "kubectl-mesh: %v\n", err)
+		return exitFailure
+	}
+	defer db.Close()
+	explanation, err := integrity.NewSQLiteIntegrityOperator(nil).Explain(ctx, db, report, resource)
+	if err != nil {
+		fmt.Fprintf(stderr, "kubectl-mesh: %v\n", err)
+		return exitFailure
+	}
+
+	if opts.output == outputJSON {
+		return writeJSON(stdout, stderr, explanation)
+	}
+	fmt.Fprintln(stdout, header(explanation.Resource, explanation.Missing))
+	section := func(title string, lines []string) {
+		fmt.Fprintf(stdout, "%s:\n", title)
+		if len(lines) == 0 {
+			fmt.Fprintln(stdout, "  none")
+		}
+		for _, line := range lines {
+			fmt.Fprintf(stdout, "  %s\n", line)
+		}
+	}
+	section("Violations", violationLines(explanation.Violations, false))
+	section("Violations naming it", violationLines(explanation.Referencing, true))
+	var repairs []string
+	for _, repair := range explanation.RepairPlans {
+		repairs = append(repairs, fmt.Sprintf("%s: %s", repair.Type, repair.Action))
+	}
+	section("Repair plans", repairs)
+	section("References", referenceLines(explanation.References))
+	section("Referenced by", referenceLines(explanation.ReferencedBy))
+	return exitOK
+}
+
+// runDeps prints the traffic path through a resource: upstream to the gateways, downstream to the pods
+func runDeps(args []string, stdout, stderr io.Writer) int {
+	var opts options
+	flags := newFlagSet("deps", stderr, &opts)
+	positional, code, ok := parseArgs(flags, args, &opts)
+	if !ok {
+		return code
+	}
+	resource, err := resourceArg(positional)
+	if err != nil {
+		fmt.Fprintf(stderr, "kubectl-mesh: %v\n", err)
+		return exitFailure
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clusterTimeout)
+	defer cancel()
+	db, err := opts.load(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "kubectl-mesh: %v\n", err)
+		return exitFailure
+	}
+	defer db.Close()
+	tree, err := integrity.NewSQLiteIntegrityOperator(nil).BuildDependencyTree(ctx, db, resource)
+	if err != nil {
+		fmt.Fprintf(stderr, "kubectl-mesh: %v\n", err)
+		return exitFailure
+	}
+
+	if opts.output == outputJSON {
+		return writeJSON(stdout, stderr, tree)
+	}
+	fmt.Fprintln(stdout, header(tree.Resource, tree.Missing))
+	fmt.Fprintln(stdout, "Upstream, towards gateways:")
+	printTree(stdout, tree.Upstream, "")
+	fmt.Fprintln(stdout, "Downstream, towards pods:")
+	printTree(stdout, tree.Downstream, "")
+	return exitOK
+}
+
+func writeJSON(stdout, stderr io.Writer, value any) int {
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		fmt.Fprintf(stderr, "kubectl-mesh: %v\n", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+func header(resource string, missing bool) string {
+	if missing {
+		return resource + " (not found)"
+	}
+	return resource
+}
+
+// countErrors returns the number of Error violations
+func countErrors(violations []meshv1alpha1.ConstraintViolation) int {
+	count := 0
+	for _, violation := range violations {
+		if violation.Severity == "Error" {
+			count++
+		}
+	}
+	return count
+}
+
+func violationLines(violations []meshv1alpha1.ConstraintViolation, withResource bool) []string {
+	var lines []string
+	for _, violation := range violations {
+		if withResource {
+			lines = append(lines, fmt.Sprintf("%s: %s %s: %s", strings.ToLower(violation.Severity), violation.Type, violation.Resource, violation.Message))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s: %s: %s", strings.ToLower(violation.Severity), violation.Type, violation.Message))
+		}
+	}
+	return lines
+}
+
+func referenceLines(references []integrity.Reference) []string {
+	var lines []string
+	for _, reference := range references {
+		line := fmt.Sprintf("%s via %s", reference.Resource, reference.Reference)
+		if reference.Missing {
+			line += " (not found)"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// printTree draws the nodes with box-drawing branches, like tree(1)
+func printTree(w io.Writer, nodes []*integrity.TrafficNode, indent string) {
+	if indent == "" && len(nodes) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	for i, node := range nodes {
+		branch, next := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, next = "└── ", "    "
+		}
+		line := fmt.Sprintf("%s%s via %s", branch, node.Resource, node.Reference)
+		if node.Missing {
+			line += " (not found)"
+		}
+		if node.Cycle {
+			line += " (cycle)"
+		}
+		fmt.Fprintf(w, "%s%s\n", indent, line)
+		printTree(w, node.Children, indent+next)
+	}
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-mesh is a kubectl plugin that queries the relational model of the mesh:
+// kubectl mesh check, kubectl mesh why and kubectl mesh deps
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/go-logr/logr"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity/cluster"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity/loader"
+)
+
+// Exit codes of kubectl-mesh, the same as of mesh-integrity
+const (
+	exitOK         = 0
+	exitViolations = 1 // найдены нарушения с severity Error
+	exitFailure    = 2 // неверные аргументы или ошибка чтения состояния
+)
+
+// Output formats
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// clusterTimeout limits reading the cluster state
+const clusterTimeout = 2 * time.Minute
+
+// command is a subcommand of kubectl-mesh
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands = []command{
+	{name: "check", usage: "check", summary: "run the integrity checks against the cluster", run: runCheck},
+	{name: "why", usage: "why Kind/namespace/name", summary: "explain the violations and references of a resource", run: runWhy},
+	{name: "deps", usage: "deps Kind/namespace/name", summary: "print the traffic path up to gateways and down to pods", run: runDeps},
+}
+
+func main() {
+	// Логи оператора не нужны в выводе плагина, ошибки возвращаются командам
+	ctrllog.SetLogger(logr.Discard())
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		usage(stderr)
+		return exitFailure
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:], stdout, stderr)
+		}
+	}
+	fmt.Fprintf(stderr, "kubectl-mesh: unknown command %q\n\n", args[0])
+	usage(stderr)
+	return exitFailure
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: kubectl mesh <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-26s %s\n", cmd.usage, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Resources are Kind/namespace/name, the kind case-insensitive and optionally qualified")
+	fmt.Fprintln(w, `with its group, e.g. Gateway.gateway.networking.k8s.io/infra/public. Run "kubectl mesh`)
+	fmt.Fprintln(w, `<command> -h" for the flags of a command.`)
+}
+
+// options are the flags shared by the commands: the cluster or snapshot to read and the output format
+type options struct {
+	kubeconfig string
+	context    string
+	snapshot   string
+	output     string
+}
+
+func newFlagSet(name string, stderr io.Writer, opts *options) *flag.FlagSet {
+	flags := flag.NewFlagSet("kubectl mesh "+name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.kubeconfig, "kubeconfig", "", "Path to the kubeconfig of the cluster")
+	flags.StringVar(&opts.context, "context", "", "Kubeconfig context of the cluster, the current context by default")
+	flags.StringVar(&opts.snapshot, "snapshot", "", "Read a snapshot written by mesh-integrity snapshot save instead of the cluster")
+	flags.StringVar(&opts.output, "o", outputText, "Output format: text or json")
+	return flags
+}
+
+// parseArgs parses flags placed before and after the arguments, as kubectl does:
+// kubectl mesh why Service/shop/web --context prod
+func parseArgs(flags *flag.FlagSet, args []string, opts *options) ([]string, int, bool) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, exitOK, false
+			}
+			return nil, exitFailure, false
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if opts.output != outputText && opts.output != outputJSON {
+		fmt.Fprintf(flags.Output(), "kubectl-mesh: unknown output format %q, expected text or json\n", opts.output)
+		return nil, exitFailure, false
+	}
+	return positional, exitOK, true
+}
+
+// resourceArg parses the single Kind/namespace/name argument into the form of the model
+func resourceArg(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("expected a single resource, Kind/namespace/name")
+	}
+	obj, err := loader.NewObject(args[0])
+	if err != nil {
+		return "", err
+	}
+	if obj.GetNamespace() == "" {
+		return "", fmt.Errorf("%s: expected Kind/namespace/name", args[0])
+	}
+	return integrity.ObjectResource(obj), nil
+}
+
+// load reads the snapshot or the cluster into an in-memory database
+func (o *options) load(ctx context.Context) (*sql.DB, error) {
+	operator := integrity.NewSQLiteIntegrityOperator(nil)
+	if o.snapshot != "" {
+		return operator.LoadSnapshot(ctx, o.snapshot)
+	}
+	model, err := cluster.Model(ctx, o.kubeconfig, o.context)
+	if err != nil {
+		return nil, err
+	}
+	return operator.CreateInMemoryDB(model)
+}
+
+// checkedDB loads the state and runs the integrity checks with repair plans over it
+func (o *options) checkedDB(ctx context.Context) (*sql.DB, *integrity.IntegrityReport, error) {
+	db, err := o.load(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	operator := integrity.NewSQLiteIntegrityOperator(nil)
+	report, err := operator.CheckIntegrity(db)
+	if err == nil {
+		report.RepairPlans, err = operator.ComputeRepairPlans(db, report)
+	}
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return db, report, nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity/loader"
+)
+
+func runTest(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// trafficSnapshot saves the model of the traffic testdata as a snapshot in place of a cluster
+func trafficSnapshot(t *testing.T) string {
+	t.Helper()
+	return testdataSnapshot(t, "traffic-resources.yaml")
+}
+
+// testdataSnapshot saves the model of an integrity testdata file as a snapshot
+func testdataSnapshot(t *testing.T, name string) string {
+	t.Helper()
+	objects, err := loader.LoadPaths([]string{filepath.Join("../../internal/integrity/testdata", name)}, nil)
+	if err != nil {
+		t.Fatalf("Failed to load testdata: %v", err)
+	}
+	model := &integrity.RelationalModel{}
+	for _, obj := range objects {
+		if err := model.AddManifestObject(obj.Object); err != nil {
+			t.Fatalf("Failed to add %s: %v", obj.Source, err)
+		}
+	}
+	operator := integrity.NewSQLiteIntegrityOperator(nil)
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := operator.SaveSnapshot(context.Background(), db, path, integrity.SnapshotJSON); err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+	return path
+}
+
+func TestCheck(t *testing.T) {
+	snapshot := trafficSnapshot(t)
+
+	code, stdout, stderr := runTest("check", "--snapshot", snapshot)
+	if code != exitViolations {
+		t.Fatalf("Expected exit %d, got %d:\n%s%s", exitViolations, code, stdout, stderr)
+	}
+	for _, want := range []string{
+		"error: ForeignKeyViolation VirtualService/shop/shop: References non-existent Gateway/istio-system/internal",
+		"repair: Delete VirtualService/shop/shop",
+		"1 error(s), 0 warning(s), 1 repair action(s)",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in the output:\n%s", want, stdout)
+		}
+	}
+
+	if code, stdout, _ := runTest("check", "--snapshot", snapshot, "-o", "json"); code != exitViolations || !strings.Contains(stdout, `"version": "mesh-integrity/v1"`) {
+		t.Errorf("Expected a JSON report, got %d:\n%s", code, stdout)
+	}
+}
+
+func TestWhy(t *testing.T) {
+	snapshot := trafficSnapshot(t)
+
+	// Флаги допускаются и после ресурса, как в kubectl
+	code, stdout, stderr := runTest("why", "virtualservice/shop/shop", "--snapshot", snapshot)
+	if code != exitOK {
+		t.Fatalf("Expected exit %d, got %d:\n%s%s", exitOK, code, stdout, stderr)
+	}
+	for _, want := range []string{
+		"VirtualService/shop/shop\n",
+		"  error: ForeignKeyViolation: References non-existent Gateway/istio-system/internal",
+		"  Delete: Delete broken VirtualService reference",
+		"  Gateway/istio-system/internal via spec.gateways (not found)",
+		"  VirtualService/shop/cart via http[0].delegate",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in the output:\n%s", want, stdout)
+		}
+	}
+
+	// Отсутствующий шлюз объясняется через нарушения, которые на него ссылаются
+	code, stdout, _ = runTest("why", "--snapshot", snapshot, "Gateway/istio-system/internal")
+	if code != exitOK || !strings.Contains(stdout, "Gateway/istio-system/internal (not found)") ||
+		!strings.Contains(stdout, "error: ForeignKeyViolation VirtualService/shop/shop:") {
+		t.Errorf("Expected the violations naming the missing gateway, got %d:\n%s", code, stdout)
+	}
+
+	// Нарушения Gateway API Gateway и маршрутов, которые его упоминают, называют его с группой
+	code, stdout, _ = runTest("why", "--snapshot", testdataSnapshot(t, "gateway-api-resources.yaml"), "Gateway.gateway.networking.k8s.io/infra/public")
+	for _, want := range []string{
+		"Gateway.gateway.networking.k8s.io/infra/public\n",
+		"  error: ReferenceGrantViolation: listeners[https].tls.certificateRefs[0] to Secret/certs/wildcard-cert is not permitted",
+		"  error: VisibilityViolation HTTPRoute/shop/secure:",
+	} {
+		if code != exitOK || !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in the output, got %d:\n%s", want, code, stdout)
+		}
+	}
+
+	code, stdout, _ = runTest("why", "--snapshot", snapshot, "-o", "json", "VirtualService/shop/shop")
+	var explanation integrity.Explanation
+	if code != exitOK || json.Unmarshal([]byte(stdout), &explanation) != nil || len(explanation.Violations) != 1 {
+		t.Errorf("Expected a JSON explanation with one violation, got %d:\n%s", code, stdout)
+	}
+}
+
+func TestDeps(t *testing.T) {
+	snapshot := trafficSnapshot(t)
+
+	code, stdout, stderr := runTest("deps", "--snapshot", snapshot, "Service/shop/web")
+	if code != exitOK {
+		t.Fatalf("Expected exit %d, got %d:\n%s%s", exitOK, code, stdout, stderr)
+	}
+	want := `Service/shop/web
+Upstream, towards gateways:
+├── HTTPRoute/shop/web via rules[0].backendRefs[0]
+│   └── Gateway.gateway.networking.k8s.io/infra/public via parentRefs[0]
+└── VirtualService/shop/shop via http[1].route[0].destination.host
+    ├── Gateway/istio-system/internal via spec.gateways (not found)
+    └── Gateway/istio-system/public via spec.gateways
+Downstream, towards pods:
+└── Pod/shop/web-1 via spec.selector
+`
+	if stdout != want {
+		t.Errorf("Unexpected tree:\n%s\nexpected:\n%s", stdout, want)
+	}
+
+	code, stdout, _ = runTest("deps", "--snapshot", snapshot, "-o", "json", "Gateway/istio-system/public")
+	var tree integrity.DependencyTree
+	if code != exitOK || json.Unmarshal([]byte(stdout), &tree) != nil || len(tree.Downstream) != 1 {
+		t.Errorf("Expected a JSON tree with one downstream node, got %d:\n%s", code, stdout)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	snapshot := trafficSnapshot(t)
+	for _, args := range [][]string{
+		{},
+		{"status"},
+		{"check", "--snapshot", snapshot, "extra"},
+		{"check", "--snapshot", snapshot, "-o", "xml"},
+		{"why", "--snapshot", snapshot},
+		{"why", "--snapshot", snapshot, "Service/shop/web", "Service/shop/cart"},
+		{"deps", "--snapshot", snapshot, "Unknown/shop/web"},
+		{"deps", "--snapshot", snapshot, "Service/web"},
+		{"deps", "--snapshot", filepath.Join(t.TempDir(), "does-not-exist.json"), "Service/shop/web"},
+	} {
+		if code, _, _ := runTest(args...); code != exitFailure {
+			t.Errorf("%v: expected exit %d, got %d", args, exitFailure, code)
+		}
+	}
+}
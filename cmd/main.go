@@ -39,6 +39,9 @@ import (
 
 	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
 	"github.com/mdarin/istio-integrity-operator/internal/controller"
+	"github.com/mdarin/istio-integrity-operator/internal/httpapi"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+	integritywebhook "github.com/mdarin/istio-integrity-operator/internal/webhook"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -51,6 +54,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(meshv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(integrity.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -63,6 +67,7 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var integrityWebhookPolicy string
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -81,6 +86,9 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&integrityWebhookPolicy, "integrity-webhook-policy", string(integritywebhook.PolicyDeny),
+		"How the integrity webhook treats changes that introduce violations: deny rejects Error violations, "+
+			"warn admits the change and returns admission warnings.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -209,8 +217,47 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "MeshService")
 		os.Exit(1)
 	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		policy, err := integritywebhook.ParsePolicy(integrityWebhookPolicy)
+		if err != nil {
+			setupLog.Error(err, "invalid integrity webhook policy")
+			os.Exit(1)
+		}
+		if err = integritywebhook.SetupIntegrityWebhookWithManager(mgr, policy); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Integrity")
+			os.Exit(1)
+		}
+		if err = integritywebhook.SetupMeshServiceWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "MeshService")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
+	// Анализ последствий, отчет о целостности, снимки и SQL-запросы обслуживаются metrics server и защищены его authn/authz.
+	// Без --metrics-secure фильтра нет, и эндпоинты не регистрируются
+	if secureMetrics {
+		if err := mgr.AddMetricsServerExtraHandler(httpapi.ImpactPath, httpapi.NewImpactHandler(mgr.GetClient())); err != nil {
+			setupLog.Error(err, "unable to add impact analysis endpoint")
+			os.Exit(1)
+		}
+		if err := mgr.AddMetricsServerExtraHandler(httpapi.ReportPath, httpapi.NewReportHandler(mgr.GetClient())); err != nil {
+			setupLog.Error(err, "unable to add integrity report endpoint")
+			os.Exit(1)
+		}
+		if err := mgr.AddMetricsServerExtraHandler(httpapi.SnapshotPath, httpapi.NewSnapshotHandler(mgr.GetClient())); err != nil {
+			setupLog.Error(err, "unable to add snapshot endpoint")
+			os.Exit(1)
+		}
+		if err := mgr.AddMetricsServerExtraHandler(httpapi.QueryPath, httpapi.NewQueryHandler(mgr.GetClient())); err != nil {
+			setupLog.Error(err, "unable to add query endpoint")
+			os.Exit(1)
+		}
+	} else {
+		setupLog.Info("Impact, report, snapshot and query endpoints are disabled because metrics serving is not secure")
+	}
+
 	if metricsCertWatcher != nil {
 		setupLog.Info("Adding metrics certificate watcher to manager")
 		if err := mgr.Add(metricsCertWatcher); err != nil {
@@ -0,0 +1,177 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity/loader"
+)
+
+// runDiff compares two models: snapshots, manifest files or directories, or git revisions
+// of a manifests repository, and reports the violations the new model introduces
+func runDiff(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: mesh-integrity diff [flags] old new")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Each side is a snapshot, a manifest file, a directory or a git revision of the repository:")
+		fmt.Fprintln(stderr, "rev reads every manifest of the tree, rev:path a directory or a file of it. Reports the")
+		fmt.Fprintln(stderr, "introduced, resolved and unchanged violations and the added, modified and removed")
+		fmt.Fprintln(stderr, "resources. Exits with 1 when Error violations are introduced.")
+		flags.PrintDefaults()
+	}
+	var quiet bool
+	var format, repo string
+	flags.BoolVar(&quiet, "quiet", false, "Print only the summary line")
+	flags.StringVar(&format, "format", formatText, "Output format: text or json")
+	flags.StringVar(&repo, "repo", ".", "Git repository git revisions are read from")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitFailure
+	}
+	if format != formatText && format != formatJSON {
+		fmt.Fprintf(stderr, "mesh-integrity: unknown format %q, expected text or json\n", format)
+		return exitFailure
+	}
+	if flags.NArg() != 2 {
+		fmt.Fprintln(stderr, "mesh-integrity: expected the old and the new side")
+		return exitFailure
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), liveTimeout)
+	defer cancel()
+	operator := integrity.NewSQLiteIntegrityOperator(nil)
+	oldDB, ok := loadDiffSide(ctx, repo, flags.Arg(0), stderr)
+	if !ok {
+		return exitFailure
+	}
+	defer oldDB.Close()
+	newDB, ok := loadDiffSide(ctx, repo, flags.Arg(1), stderr)
+	if !ok {
+		return exitFailure
+	}
+	defer newDB.Close()
+
+	diff, err := operator.DiffModels(ctx, oldDB, newDB)
+	if err != nil {
+		fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+		return exitFailure
+	}
+	if format == formatJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diff); err != nil {
+			fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+			return exitFailure
+		}
+	} else {
+		if !quiet {
+			printDiffText(stdout, diff)
+		}
+		printDiffSummary(stdout, diff)
+	}
+	if countErrors(diff.Report.Violations) > 0 {
+		return exitViolations
+	}
+	return exitOK
+}
+
+// loadDiffSide loads a side of diff into an in-memory database. Manifests keep Services
+// without the mesh annotation like lint; snapshots are imported as saved.
+func loadDiffSide(ctx context.Context, repo, side string, stderr io.Writer) (*sql.DB, bool) {
+	operator := integrity.NewSQLiteIntegrityOperator(nil)
+	var objects []loader.Object
+	var err error
+	if _, statErr := os.Stat(side); statErr == nil {
+		if data, readErr := os.ReadFile(side); readErr == nil && integrity.IsSnapshot(data) {
+			db, err := operator.LoadSnapshot(ctx, side)
+			if err != nil {
+				fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+				return nil, false
+			}
+			return db, true
+		}
+		objects, _, err = loadInput(side, nil)
+	} else {
+		// Путь не существует: сторона читается как ревизия git
+		objects, _, err = loadRevision(ctx, repo, side)
+	}
+
+	var parseErrors loader.ParseErrors
+	if errors.As(err, &parseErrors) {
+		for _, parseError := range parseErrors {
+			fmt.Fprintf(stderr, "mesh-integrity: %v\n", parseError)
+		}
+		return nil, false
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "mesh-integrity: %s is neither a snapshot, a manifest nor a git revision: %v\n", side, err)
+		return nil, false
+	}
+
+	model, err := manifestModel(objects)
+	if err == nil {
+		var db *sql.DB
+		if db, err = operator.CreateInMemoryDB(model); err == nil {
+			return db, true
+		}
+	}
+	fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+	return nil, false
+}
+
+// printDiffText prints a line per changed resource, violation and repair plan
+func printDiffText(w io.Writer, diff *integrity.ModelDiff) {
+	for _, change := range diff.Resources {
+		fmt.Fprintf(w, "%s: %s\n", change.Change, change.Resource)
+	}
+	printViolations := func(state string, violations []meshv1alpha1.ConstraintViolation) {
+		for _, violation := range violations {
+			fmt.Fprintf(w, "%s %s: %s %s: %s\n", state, strings.ToLower(violation.Severity), violation.Type, violation.Resource, violation.Message)
+		}
+	}
+	printViolations("introduced", diff.Report.Violations)
+	printViolations("resolved", diff.Resolved)
+	printViolations("unchanged", diff.Unchanged)
+	for _, repair := range diff.Report.RepairPlans {
+		fmt.Fprintf(w, "repair: %s %s: %s\n", repair.Type, repair.Resource, repair.Action)
+	}
+}
+
+func printDiffSummary(w io.Writer, diff *integrity.ModelDiff) {
+	changes := map[integrity.ResourceChangeType]int{}
+	for _, change := range diff.Resources {
+		changes[change.Change]++
+	}
+	fmt.Fprintf(w, "%d resource(s) changed (%d added, %d modified, %d removed): %d introduced, %d resolved, %d unchanged violation(s), %d introduced error(s)\n",
+		len(diff.Resources), changes[integrity.ResourceAdded], changes[integrity.ResourceModified], changes[integrity.ResourceRemoved],
+		len(diff.Report.Violations), len(diff.Resolved), len(diff.Unchanged), countErrors(diff.Report.Violations))
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	valid := "../../internal/integrity/testdata/valid-mesh-resources.yaml"
+	invalid := "../../internal/integrity/testdata/invalid-mesh-resources.yaml"
+	dir := t.TempDir()
+	snapshot := filepath.Join(dir, "valid.json")
+	if code, _, stderr := runTest("", "snapshot", "save", "-o", snapshot, valid); code != exitOK {
+		t.Fatalf("Failed to save snapshot: %s", stderr)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		code     int
+		expected []string
+	}{
+		{"files", []string{"diff", valid, invalid}, exitViolations, []string{
+			"added: VirtualService/default/broken-vs",
+			"removed: VirtualService/default/web-vs",
+			"introduced error: ForeignKeyViolation VirtualService/default/broken-vs",
+			"resolved error: ForeignKeyViolation VirtualService/default/web-vs",
+			"4 resource(s) changed (2 added, 0 modified, 2 removed): 3 introduced, 1 resolved, 0 unchanged violation(s)",
+		}},
+		// Снимок и манифесты, из которых он сохранен, не отличаются
+		{"snapshot", []string{"diff", snapshot, valid}, exitOK, []string{
+			"unchanged error: ForeignKeyViolation VirtualService/default/web-vs",
+			"0 resource(s) changed",
+		}},
+		{"reverse", []string{"diff", invalid, valid}, exitViolations, []string{"resolved error: ForeignKeyViolation VirtualService/default/broken-vs"}},
+		{"json", []string{"diff", "--format", "json", valid, invalid}, exitViolations, []string{`"change": "added"`, `"resolved": [`}},
+	}
+	for _, tt := range tests {
+		code, stdout, stderr := runTest("", tt.args...)
+		if code != tt.code {
+			t.Errorf("%s: expected exit %d, got %d:\n%s%s", tt.name, tt.code, code, stdout, stderr)
+		}
+		for _, expected := range tt.expected {
+			if !strings.Contains(stdout, expected) {
+				t.Errorf("%s: expected %q in:\n%s", tt.name, expected, stdout)
+			}
+		}
+	}
+
+	for _, args := range [][]string{
+		{"diff", valid},
+		{"diff", "--format", "sarif", valid, invalid},
+		{"diff", "--repo", dir, "does-not-exist", valid},
+	} {
+		if code, _, _ := runTest("", args...); code != exitFailure {
+			t.Errorf("%v: expected exit %d, got %d", args, exitFailure, code)
+		}
+	}
+}
+
+func TestDiffGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := t.TempDir()
+	commit := func(source string) {
+		t.Helper()
+		data, err := os.ReadFile(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.MkdirAll(filepath.Join(repo, "manifests"), 0o755)
+		os.WriteFile(filepath.Join(repo, "manifests", "app.yaml"), data, 0o644)
+		os.WriteFile(filepath.Join(repo, "README.md"), []byte("kind: Gateway"), 0o644)
+		for _, args := range [][]string{{"add", "-A"}, {"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", source}} {
+			if out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput(); err != nil {
+				t.Fatalf("git %v: %v\n%s", args, err, out)
+			}
+		}
+	}
+	if out, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	commit("../../internal/integrity/testdata/valid-mesh-resources.yaml")
+	commit("../../internal/integrity/testdata/invalid-mesh-resources.yaml")
+
+	for _, sides := range [][2]string{
+		{"HEAD~1", "HEAD"},
+		{"HEAD~1:manifests", "HEAD:manifests"},
+		{"HEAD~1:manifests/app.yaml", filepath.Join(repo, "manifests", "app.yaml")},
+	} {
+		code, stdout, stderr := runTest("", "diff", "--repo", repo, sides[0], sides[1])
+		if code != exitViolations || !strings.Contains(stdout, "introduced error: ForeignKeyViolation VirtualService/default/broken-vs") {
+			t.Errorf("%v: expected the violations of the new revision, got %d:\n%s%s", sides, code, stdout, stderr)
+		}
+	}
+
+	// Ошибка разбора сообщается с ревизией и путем файла
+	os.WriteFile(filepath.Join(repo, "manifests", "broken.yaml"), []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: [api\n"), 0o644)
+	commit(filepath.Join(repo, "manifests", "broken.yaml"))
+	if code, _, stderr := runTest("", "diff", "--repo", repo, "HEAD~1", "HEAD"); code != exitFailure || !strings.Contains(stderr, "HEAD:manifests/broken.yaml:1: yaml: line 4") {
+		t.Errorf("Expected exit %d with the location of the parse error, got %d:\n%s", exitFailure, code, stderr)
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+	integrityreport "github.com/mdarin/istio-integrity-operator/internal/integrity/report"
+)
+
+// Output formats of the report
+const (
+	formatText  = "text"
+	formatTable = "table"
+	formatJSON  = "json"
+	formatJUnit = "junit"
+	formatSARIF = "sarif"
+)
+
+var formats = []string{formatText, formatTable, formatJSON, formatJUnit, formatSARIF}
+
+// writeReport prints the report in the format; quiet applies to the human formats only
+func writeReport(w io.Writer, format string, report *integrity.IntegrityReport, locations integrityreport.Locations, inputs int, quiet bool) error {
+	switch format {
+	case formatJSON:
+		return integrityreport.WriteJSON(w, report, locations)
+	case formatJUnit:
+		return integrityreport.WriteJUnit(w, report, locations)
+	case formatSARIF:
+		return integrityreport.WriteSARIF(w, report, locations)
+	case formatTable:
+		if !quiet {
+			if err := printReportTable(w, report, locations); err != nil {
+				return err
+			}
+		}
+	default:
+		if !quiet {
+			printReportText(w, report)
+		}
+	}
+	printSummary(w, report, inputs)
+	return nil
+}
+
+// countErrors returns the number of Error violations
+func countErrors(violations []meshv1alpha1.ConstraintViolation) int {
+	count := 0
+	for _, violation := range violations {
+		if violation.Severity == "Error" {
+			count++
+		}
+	}
+	return count
+}
+
+// printReportText prints a line per violation and repair plan
+func printReportText(w io.Writer, report *integrity.IntegrityReport) {
+	for _, violation := range report.Violations {
+		fmt.Fprintf(w, "%s: %s %s: %s\n", strings.ToLower(violation.Severity), violation.Type, violation.Resource, violation.Message)
+	}
+	for _, repair := range report.RepairPlans {
+		fmt.Fprintf(w, "repair: %s %s: %s\n", repair.Type, repair.Resource, repair.Action)
+	}
+}
+
+// printReportTable prints violations and repair plans as aligned columns with the
+// manifest of the resource
+func printReportTable(w io.Writer, report *integrity.IntegrityReport, locations integrityreport.Locations) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if len(report.Violations) > 0 {
+		fmt.Fprintln(table, "SEVERITY\tRULE\tRESOURCE\tLOCATION\tMESSAGE")
+		for _, violation := range report.Violations {
+			location := "-"
+			if sources := locations[violation.Resource]; len(sources) > 0 {
+				location = sources[0].String()
+			}
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", violation.Severity, violation.Type, violation.Resource, location, violation.Message)
+		}
+	}
+	if len(report.RepairPlans) > 0 {
+		if len(report.Violations) > 0 {
+			fmt.Fprintln(table)
+		}
+		fmt.Fprintln(table, "REPAIR\tRESOURCE\tACTION")
+		for _, repair := range report.RepairPlans {
+			fmt.Fprintf(table, "%s\t%s\t%s\n", repair.Type, repair.Resource, repair.Action)
+		}
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	if len(report.Violations) > 0 || len(report.RepairPlans) > 0 {
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+func printSummary(w io.Writer, report *integrity.IntegrityReport, inputs int) {
+	errorCount := countErrors(report.Violations)
+	fmt.Fprintf(w, "%d input(s) checked: %d error(s), %d warning(s), %d repair action(s)\n",
+		inputs, errorCount, len(report.Violations)-errorCount, len(report.RepairPlans))
+}
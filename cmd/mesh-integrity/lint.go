@@ -0,0 +1,192 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity/loader"
+	integrityreport "github.com/mdarin/istio-integrity-operator/internal/integrity/report"
+)
+
+// runLint checks manifests the way the operator checks the cluster:
+// builds the relational model, runs CheckIntegrity and ComputeRepairPlans.
+// In live mode the manifests are overlaid on the cluster state instead.
+func runLint(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: mesh-integrity lint [flags] [file | directory | -]...")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Checks YAML and JSON manifests, including List objects. Directories are read recursively")
+		fmt.Fprintln(stderr, `(*.yaml, *.yml, *.json), "-" or no arguments read stdin. Exits with 1 when Error violations`)
+		fmt.Fprintln(stderr, "are found and with 2 when a manifest cannot be decoded.")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "--helm and --kustomize render charts and kustomizations in-process and check the output")
+		fmt.Fprintln(stderr, "together with the manifests.")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "With --live, --kubeconfig, --context or --live-snapshot the manifests are overlaid on the")
+		fmt.Fprintln(stderr, "live state and only the violations they introduce are reported.")
+		flags.PrintDefaults()
+	}
+	var quiet bool
+	var format string
+	var live liveOptions
+	var rendered renderOptions
+	flags.BoolVar(&quiet, "quiet", false, "Print only the summary line")
+	flags.StringVar(&format, "format", formatText, "Output format: "+strings.Join(formats, ", "))
+	live.register(flags)
+	rendered.register(flags)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitFailure
+	}
+	if !slices.Contains(formats, format) {
+		fmt.Fprintf(stderr, "mesh-integrity: unknown format %q, expected one of %s\n", format, strings.Join(formats, ", "))
+		return exitFailure
+	}
+	if len(live.deletes) > 0 && !live.active() {
+		fmt.Fprintln(stderr, "mesh-integrity: --delete requires the live state: --live, --kubeconfig, --context or --live-snapshot")
+		return exitFailure
+	}
+	if err := rendered.validate(); err != nil {
+		fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+		return exitFailure
+	}
+
+	inputs := flags.Args()
+	// Только удаления и отрисованные чарты не требуют манифестов из stdin
+	if len(inputs) == 0 && len(live.deletes) == 0 && !rendered.active() {
+		inputs = []string{loader.StdinName}
+	}
+	objects, files, ok := loadInputs(inputs, stdin, stderr)
+	if !ok {
+		return exitFailure
+	}
+	renderedObjects, renderedInputs, ok := rendered.load(stderr)
+	if !ok {
+		return exitFailure
+	}
+	objects = append(objects, renderedObjects...)
+	files += renderedInputs
+
+	var report *integrity.IntegrityReport
+	var err error
+	if live.active() {
+		report, err = live.overlay(objects, stderr)
+	} else {
+		report, err = checkManifests(objects)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+		return exitFailure
+	}
+
+	if err := writeReport(stdout, format, report, integrityreport.LocationsOf(objects), files, quiet); err != nil {
+		fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+		return exitFailure
+	}
+	if countErrors(report.Violations) > 0 {
+		return exitViolations
+	}
+	return exitOK
+}
+
+// loadInputs decodes every input and returns the objects and the number of inputs read.
+// Parse errors are printed with their file and line; ok is false when anything failed,
+// a manifest that was not read is not checked and the result would be incomplete.
+func loadInputs(inputs []string, stdin io.Reader, stderr io.Writer) ([]loader.Object, int, bool) {
+	var objects []loader.Object
+	var parseErrors loader.ParseErrors
+	files := 0
+	for _, input := range inputs {
+		loaded, count, err := loadInput(input, stdin)
+		var errs loader.ParseErrors
+		if errors.As(err, &errs) {
+			parseErrors = append(parseErrors, errs...)
+		} else if err != nil {
+			fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+			return nil, 0, false
+		}
+		files += count
+		objects = append(objects, loaded...)
+	}
+	for _, err := range parseErrors {
+		fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+	}
+	return objects, files, len(parseErrors) == 0
+}
+
+// manifestModel builds the relational model of the manifests, Services without the mesh
+// annotation included
+func manifestModel(objects []loader.Object) (*integrity.RelationalModel, error) {
+	model := &integrity.RelationalModel{}
+	for _, obj := range objects {
+		if err := model.AddManifestObject(obj.Object); err != nil {
+			return nil, fmt.Errorf("%s: %w", obj.Source, err)
+		}
+	}
+	return model, nil
+}
+
+// checkManifests builds the model of the manifests and checks it
+func checkManifests(objects []loader.Object) (*integrity.IntegrityReport, error) {
+	model, err := manifestModel(objects)
+	if err != nil {
+		return nil, err
+	}
+
+	operator := integrity.NewSQLiteIntegrityOperator(nil)
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	report, err := operator.CheckIntegrity(db)
+	if err != nil {
+		return nil, err
+	}
+	if report.RepairPlans, err = operator.ComputeRepairPlans(db, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// loadInput decodes a file, every manifest of a directory or stdin
+// and returns the number of inputs read
+func loadInput(input string, stdin io.Reader) ([]loader.Object, int, error) {
+	if input == loader.StdinName {
+		objects, err := loader.LoadPaths([]string{input}, stdin)
+		return objects, 1, err
+	}
+
+	files, err := loader.ManifestFiles(input)
+	if err != nil {
+		return nil, 0, err
+	}
+	objects, err := loader.LoadPaths(files, nil)
+	return objects, len(files), err
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testGatewayManifest = `apiVersion: networking.istio.io/v1beta1
+kind: Gateway
+metadata:
+  name: public-gateway
+  namespace: istio-system
+`
+
+func runTest(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestLint(t *testing.T) {
+	valid, err := os.ReadFile("../../internal/integrity/testdata/valid-mesh-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read testdata: %v", err)
+	}
+
+	// Директория: манифесты читаются рекурсивно, посторонние файлы пропускаются
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "gateways"), 0o755)
+	os.WriteFile(filepath.Join(dir, "app.yaml"), valid, 0o644)
+	os.WriteFile(filepath.Join(dir, "gateways", "public.yml"), []byte(testGatewayManifest), 0o644)
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("kind: Gateway"), 0o644)
+
+	tests := []struct {
+		name     string
+		stdin    string
+		args     []string
+		code     int
+		expected string
+	}{
+		{"invalid file", "", []string{"lint", "../../internal/integrity/testdata/invalid-mesh-resources.yaml"}, exitViolations,
+			"error: ForeignKeyViolation VirtualService/default/broken-vs"},
+		{"directory", "", []string{"lint", dir}, exitOK, "2 input(s) checked: 0 error(s)"},
+		{"stdin", string(valid), []string{"lint"}, exitViolations, "References non-existent Gateway/istio-system/public-gateway"},
+		{"stdin and file", string(valid), []string{"lint", "-", filepath.Join(dir, "gateways", "public.yml")}, exitOK, "0 error(s)"},
+		{"repair plans", "", []string{"lint", "../../internal/integrity/testdata/invalid-mesh-resources.yaml"}, exitViolations,
+			"repair: Delete VirtualService/default/broken-vs"},
+		{"quiet", "", []string{"lint", "--quiet", "../../internal/integrity/testdata/invalid-mesh-resources.yaml"}, exitViolations,
+			"1 input(s) checked: 3 error(s)"},
+		{"sarif", "", []string{"lint", "--format", "sarif", "../../internal/integrity/testdata/invalid-mesh-resources.yaml"}, exitViolations,
+			`"uri": "../../internal/integrity/testdata/invalid-mesh-resources.yaml"`},
+		{"json", "", []string{"lint", "--format", "json", "../../internal/integrity/testdata/invalid-mesh-resources.yaml"}, exitViolations,
+			`"version": "mesh-integrity/v1"`},
+		{"junit", "", []string{"lint", "--format", "junit", "../../internal/integrity/testdata/invalid-mesh-resources.yaml"}, exitViolations,
+			`<testcase name="VirtualService/default/broken-vs" classname="ForeignKeyViolation"`},
+		{"table", "", []string{"lint", "--format", "table", "../../internal/integrity/testdata/invalid-mesh-resources.yaml"}, exitViolations,
+			"invalid-mesh-resources.yaml:16"},
+	}
+
+	for _, tt := range tests {
+		code, stdout, stderr := runTest(tt.stdin, tt.args...)
+		if code != tt.code || !strings.Contains(stdout, tt.expected) {
+			t.Errorf("%s: expected exit %d with %q, got %d:\n%s%s", tt.name, tt.code, tt.expected, code, stdout, stderr)
+		}
+	}
+
+	if _, stdout, _ := runTest("", "lint", "--quiet", "../../internal/integrity/testdata/invalid-mesh-resources.yaml"); strings.Contains(stdout, "error:") {
+		t.Errorf("Expected --quiet to print only the summary, got:\n%s", stdout)
+	}
+}
+
+func TestLintFailures(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"unknown"},
+		{"lint", "does-not-exist.yaml"},
+		{"lint", "--unknown-flag"},
+		{"lint", "--format", "xml"},
+		{"lint", "--delete", "VirtualService/default/broken-vs"},
+		{"lint", "--live-snapshot", "../../internal/integrity/testdata/invalid-mesh-resources.yaml", "--delete", "ConfigMap/default/config"},
+		{"lint", "--kubeconfig", "does-not-exist.kubeconfig", "--delete", "VirtualService/default/broken-vs"},
+	} {
+		if code, _, _ := runTest("", args...); code != exitFailure {
+			t.Errorf("%v: expected exit %d, got %d", args, exitFailure, code)
+		}
+	}
+
+	// Ошибка разбора сообщается со строкой документа и строкой ошибки, остальные манифесты не проверяются
+	broken := testGatewayManifest + "---\napiVersion: v1\nkind: Service\nmetadata:\n  name: [api\n"
+	if code, _, stderr := runTest(broken, "lint"); code != exitFailure || !strings.Contains(stderr, "-:7: yaml: line 10:") {
+		t.Errorf("Expected exit %d with the line of the parse error, got %d:\n%s", exitFailure, code, stderr)
+	}
+}
+
+func TestLintLive(t *testing.T) {
+	snapshot := "../../internal/integrity/testdata/invalid-mesh-resources.yaml"
+	newVS := `apiVersion: networking.istio.io/v1
+kind: VirtualService
+metadata:
+  name: new-vs
+  namespace: default
+spec:
+  hosts: ["new.example.com"]
+  gateways: ["istio-system/missing-gateway"]
+`
+
+	tests := []struct {
+		name     string
+		stdin    string
+		args     []string
+		code     int
+		expected string
+	}{
+		// Нарушения живого состояния не сообщаются, только внесенные изменением
+		{"introduced", newVS, []string{"lint", "--live-snapshot", snapshot}, exitViolations,
+			"error: ForeignKeyViolation VirtualService/default/new-vs"},
+		{"unchanged", testGatewayManifest, []string{"lint", "--live-snapshot", snapshot}, exitOK, "0 error(s)"},
+		{"deleted", "", []string{"lint", "--live-snapshot", snapshot, "--delete", "VirtualService/default/broken-vs"}, exitOK,
+			"0 input(s) checked: 0 error(s)"},
+	}
+	for _, tt := range tests {
+		code, stdout, stderr := runTest(tt.stdin, tt.args...)
+		if code != tt.code || !strings.Contains(stdout, tt.expected) || strings.Contains(stdout, "broken-vs") {
+			t.Errorf("%s: expected exit %d with %q, got %d:\n%s%s", tt.name, tt.code, tt.expected, code, stdout, stderr)
+		}
+	}
+}
+
+func TestLintRender(t *testing.T) {
+	chart := "../../internal/integrity/render/testdata/chart"
+	kustomize := "../../internal/integrity/render/testdata/kustomize"
+
+	tests := []struct {
+		name     string
+		args     []string
+		code     int
+		expected string
+	}{
+		{"chart", []string{"lint", "--helm", chart, "--namespace", "bookinfo"}, exitOK, "1 input(s) checked: 0 error(s)"},
+		{"values file", []string{"lint", "--helm", chart, "-f", chart + "/values-broken.yaml", "--format", "table"}, exitViolations,
+			"VirtualService/default/reviews  ../../internal/integrity/render/testdata/chart/templates/routing.yaml:2"},
+		{"set", []string{"lint", "--helm", chart, "--values", chart + "/values-broken.yaml", "--set", "gateway.create=true"}, exitOK, "0 error(s)"},
+		{"kustomization", []string{"lint", "--kustomize", kustomize + "/base"}, exitOK, "1 input(s) checked: 0 error(s)"},
+		{"overlay", []string{"lint", "--kustomize", kustomize + "/overlays/broken"}, exitViolations,
+			"error: ForeignKeyViolation VirtualService/bookinfo/reviews: References non-existent Gateway/bookinfo/public-gateway"},
+		// Отрисованные объекты проверяются вместе с манифестами: Gateway чарта устраняет нарушение манифеста
+		{"chart and manifest", []string{"lint", "--helm", chart, "--namespace", "istio-system",
+			"../../internal/integrity/testdata/valid-mesh-resources.yaml"}, exitOK, "2 input(s) checked: 0 error(s)"},
+	}
+	for _, tt := range tests {
+		code, stdout, stderr := runTest("", tt.args...)
+		if code != tt.code || !strings.Contains(stdout, tt.expected) {
+			t.Errorf("%s: expected exit %d with %q, got %d:\n%s%s", tt.name, tt.code, tt.expected, code, stdout, stderr)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"lint", "-f", chart + "/values-broken.yaml", "../../internal/integrity/testdata/valid-mesh-resources.yaml"},
+		{"lint", "--helm", "does-not-exist"},
+		{"lint", "--kustomize", chart},
+	} {
+		if code, _, _ := runTest("", args...); code != exitFailure {
+			t.Errorf("%v: expected exit %d, got %d", args, exitFailure, code)
+		}
+	}
+}
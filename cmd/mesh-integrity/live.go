@@ -0,0 +1,130 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity/cluster"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity/loader"
+)
+
+// liveTimeout limits reading the cluster state
+const liveTimeout = 2 * time.Minute
+
+// liveOptions select the live state local manifests are overlaid on: the cluster of a
+// kubeconfig context or a snapshot of it
+type liveOptions struct {
+	live       bool
+	kubeconfig string
+	context    string
+	snapshot   string
+	deletes    []string
+}
+
+func (l *liveOptions) register(flags *flag.FlagSet) {
+	flags.BoolVar(&l.live, "live", false, "Overlay the manifests on the cluster of the current kubeconfig context")
+	flags.StringVar(&l.kubeconfig, "kubeconfig", "", "Path to the kubeconfig of the live cluster, implies --live")
+	flags.StringVar(&l.context, "context", "", "Kubeconfig context of the live cluster, implies --live")
+	flags.StringVar(&l.snapshot, "live-snapshot", "", "Read the live state from a snapshot or manifests of the cluster instead of a cluster")
+	flags.Func("delete", "Resource deleted by the change, Kind/namespace/name; may be repeated", func(value string) error {
+		l.deletes = append(l.deletes, value)
+		return nil
+	})
+}
+
+func (l *liveOptions) active() bool {
+	return l.live || l.kubeconfig != "" || l.context != "" || l.snapshot != ""
+}
+
+// overlay applies the manifests and deletions to the live state and reports the violations they introduce
+func (l *liveOptions) overlay(objects []loader.Object, stderr io.Writer) (*integrity.IntegrityReport, error) {
+	overlay := integrity.Overlay{Apply: loader.ClientObjects(objects)}
+	for _, resource := range l.deletes {
+		obj, err := loader.NewObject(resource)
+		if err != nil {
+			return nil, fmt.Errorf("--delete %w", err)
+		}
+		overlay.Delete = append(overlay.Delete, obj)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), liveTimeout)
+	defer cancel()
+	base, err := l.baseDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer base.Close()
+	result, err := integrity.NewSQLiteIntegrityOperator(nil).AnalyzeOverlayDB(ctx, base, overlay)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Resolved) > 0 {
+		fmt.Fprintf(stderr, "mesh-integrity: the change resolves %d violation(s) of the live state\n", len(result.Resolved))
+	}
+	return result.Report, nil
+}
+
+// baseDB loads the live state into an in-memory database
+func (l *liveOptions) baseDB(ctx context.Context) (*sql.DB, error) {
+	if l.snapshot != "" {
+		return loadSnapshot(ctx, l.snapshot)
+	}
+	model, err := cluster.Model(ctx, l.kubeconfig, l.context)
+	if err != nil {
+		return nil, err
+	}
+	return integrity.NewSQLiteIntegrityOperator(nil).CreateInMemoryDB(model)
+}
+
+// loadSnapshot imports a snapshot written by mesh-integrity snapshot save or, for other
+// files, builds the model from manifests of the cluster objects
+func loadSnapshot(ctx context.Context, path string) (*sql.DB, error) {
+	operator := integrity.NewSQLiteIntegrityOperator(nil)
+	data, err := os.ReadFile(path)
+	if err == nil && integrity.IsSnapshot(data) {
+		return operator.LoadSnapshot(ctx, path)
+	}
+	model, err := snapshotModel(path)
+	if err != nil {
+		return nil, err
+	}
+	return operator.CreateInMemoryDB(model)
+}
+
+// snapshotModel builds the live model from manifests of the cluster objects, e.g. the output
+// of kubectl get -o yaml. Like BuildRelationalModel it keeps only Services with the mesh annotation.
+func snapshotModel(path string) (*integrity.RelationalModel, error) {
+	objects, err := loader.LoadPaths([]string{path}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read live snapshot: %w", err)
+	}
+	model := &integrity.RelationalModel{}
+	for _, obj := range objects {
+		if err := model.AddObject(obj.Object); err != nil {
+			return nil, fmt.Errorf("%s: %w", obj.Source, err)
+		}
+	}
+	return model, nil
+}
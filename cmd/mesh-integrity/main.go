@@ -0,0 +1,80 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// mesh-integrity runs the integrity checks of the operator outside of a cluster
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-logr/logr"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Exit codes of mesh-integrity
+const (
+	exitOK         = 0
+	exitViolations = 1 // найдены нарушения с severity Error
+	exitFailure    = 2 // неверные аргументы или ошибка чтения входных данных
+)
+
+// command is a subcommand of mesh-integrity
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+}
+
+var commands = []command{
+	{name: "lint", summary: "check YAML manifests from files, directories or stdin", run: runLint},
+	{name: "snapshot", summary: "save the relational model to a file or check a saved snapshot", run: runSnapshot},
+	{name: "diff", summary: "compare the violations of two snapshots, directories or git revisions", run: runDiff},
+	{name: "query", summary: "run a read-only SQL SELECT over the relational model", run: runQuery},
+}
+
+func main() {
+	// Логи оператора не нужны в выводе CLI, ошибки возвращаются командам
+	ctrllog.SetLogger(logr.Discard())
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		usage(stderr)
+		return exitFailure
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:], stdin, stdout, stderr)
+		}
+	}
+	fmt.Fprintf(stderr, "mesh-integrity: unknown command %q\n\n", args[0])
+	usage(stderr)
+	return exitFailure
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: mesh-integrity <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "mesh-integrity <command> -h" for the flags of a command.`)
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity/cluster"
+)
+
+// runQuery runs a read-only SELECT over the relational model of a cluster, a snapshot or manifests
+func runQuery(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("query", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: mesh-integrity query [flags] statement [file | directory | -]...")
+		fmt.Fprintln(stderr, "       mesh-integrity query [flags] --schema [file | directory | -]...")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Runs a single SELECT over the relational model of the manifests when given, of --snapshot")
+		fmt.Fprintln(stderr, "or of the cluster. The statement - is read from stdin. --schema prints the tables of the")
+		fmt.Fprintln(stderr, "model from sqlite_master.")
+		flags.PrintDefaults()
+	}
+	var schema bool
+	var format, snapshot, kubeconfig, kubeContext string
+	opts := integrity.QueryOptions{}
+	flags.BoolVar(&schema, "schema", false, "Print the CREATE statements of the model instead of running a statement")
+	flags.StringVar(&format, "format", formatText, "Output format: text or json")
+	flags.StringVar(&snapshot, "snapshot", "", "Query a snapshot or manifests of the cluster objects")
+	flags.StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig of the cluster")
+	flags.StringVar(&kubeContext, "context", "", "Kubeconfig context of the cluster")
+	flags.IntVar(&opts.MaxRows, "max-rows", integrity.DefaultQueryMaxRows, "Maximum number of rows to print")
+	flags.IntVar(&opts.MaxBytes, "max-bytes", integrity.DefaultQueryMaxBytes, "Maximum size in bytes of the values to print")
+	flags.DurationVar(&opts.Timeout, "timeout", integrity.DefaultQueryTimeout, "Maximum execution time of the statement")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitFailure
+	}
+	if format != formatText && format != formatJSON {
+		fmt.Fprintf(stderr, "mesh-integrity: unknown format %q, expected text or json\n", format)
+		return exitFailure
+	}
+
+	inputs := flags.Args()
+	statement := integrity.SchemaQuery
+	if !schema {
+		if len(inputs) == 0 {
+			fmt.Fprintln(stderr, "mesh-integrity: expected a statement or --schema")
+			return exitFailure
+		}
+		statement, inputs = inputs[0], inputs[1:]
+		if statement == "-" {
+			data, err := io.ReadAll(io.LimitReader(stdin, integrity.MaxQueryLength+1))
+			if err != nil {
+				fmt.Fprintf(stderr, "mesh-integrity: failed to read statement: %v\n", err)
+				return exitFailure
+			}
+			statement = string(data)
+		}
+	}
+	// Оператор проверяется до чтения кластера, чтобы не ждать его ради отказа
+	if _, err := integrity.ValidateQuery(statement); err != nil {
+		fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+		return exitFailure
+	}
+	if snapshot != "" && len(inputs) > 0 {
+		fmt.Fprintln(stderr, "mesh-integrity: --snapshot and manifests are mutually exclusive")
+		return exitFailure
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), liveTimeout)
+	defer cancel()
+	db, ok := queryDB(ctx, inputs, snapshot, kubeconfig, kubeContext, stdin, stderr)
+	if !ok {
+		return exitFailure
+	}
+	defer db.Close()
+	result, err := integrity.NewSQLiteIntegrityOperator(nil).Query(ctx, db, statement, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+		return exitFailure
+	}
+
+	switch {
+	case format == formatJSON:
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case schema:
+		for _, row := range result.Rows {
+			fmt.Fprintf(stdout, "%s;\n\n", strings.TrimSpace(fmt.Sprint(row[3])))
+		}
+	default:
+		err = printQueryTable(stdout, result)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+		return exitFailure
+	}
+	if result.Truncated {
+		fmt.Fprintf(stderr, "mesh-integrity: the result is truncated to %d rows, raise --max-rows or --max-bytes\n", len(result.Rows))
+	}
+	return exitOK
+}
+
+// queryDB loads the model to query: the manifests, the snapshot or the cluster
+func queryDB(ctx context.Context, inputs []string, snapshot, kubeconfig, kubeContext string, stdin io.Reader, stderr io.Writer) (*sql.DB, bool) {
+	if snapshot != "" {
+		db, err := loadSnapshot(ctx, snapshot)
+		if err != nil {
+			fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+			return nil, false
+		}
+		return db, true
+	}
+
+	var model *integrity.RelationalModel
+	var err error
+	if len(inputs) > 0 {
+		objects, _, ok := loadInputs(inputs, stdin, stderr)
+		if !ok {
+			return nil, false
+		}
+		model, err = manifestModel(objects)
+	} else {
+		model, err = cluster.Model(ctx, kubeconfig, kubeContext)
+	}
+	if err == nil {
+		var db *sql.DB
+		if db, err = integrity.NewSQLiteIntegrityOperator(nil).CreateInMemoryDB(model); err == nil {
+			return db, true
+		}
+	}
+	fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+	return nil, false
+}
+
+// printQueryTable prints the rows as aligned columns, NULL for missing values
+func printQueryTable(w io.Writer, result *integrity.QueryResult) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, strings.Join(result.Columns, "\t"))
+	for _, row := range result.Rows {
+		values := make([]string, len(row))
+		for i, value := range row {
+			if value == nil {
+				values[i] = "NULL"
+			} else {
+				// Табуляции и переводы строк внутри значения сломали бы колонки
+				values[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(fmt.Sprint(value))
+			}
+		}
+		fmt.Fprintln(table, strings.Join(values, "\t"))
+	}
+	return table.Flush()
+}
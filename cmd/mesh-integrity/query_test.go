@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+)
+
+func TestQuery(t *testing.T) {
+	traffic := "../../internal/integrity/testdata/traffic-resources.yaml"
+
+	code, stdout, stderr := runTest("", "query", "SELECT namespace, name, canonical_host FROM virtual_service_destinations ORDER BY name", traffic)
+	want := `namespace  name  canonical_host
+shop       cart  cart.shop.svc.cluster.local
+shop       shop  web.shop.svc.cluster.local
+`
+	if code != exitOK || stdout != want {
+		t.Errorf("Expected exit %d with the table:\n%s\ngot %d:\n%s%s", exitOK, want, code, stdout, stderr)
+	}
+
+	// VirtualService, маршрутизирующие на Service без подов: у cart нет подходящего пода
+	data, err := os.ReadFile(traffic)
+	if err != nil {
+		t.Fatalf("Failed to read testdata: %v", err)
+	}
+	withoutCart := filepath.Join(t.TempDir(), "mesh.yaml")
+	os.WriteFile(withoutCart, []byte(strings.Replace(string(data), "    app: cart\n    version: v1", "    app: legacy", 1)), 0o644)
+	noPods := `SELECT DISTINCT d.namespace, d.name, s.name AS service
+		FROM virtual_service_destinations d
+		JOIN services s ON s.host = d.canonical_host
+		WHERE NOT EXISTS (
+			SELECT 1 FROM workloads w
+			WHERE w.namespace = s.namespace AND NOT EXISTS (
+				SELECT 1 FROM selector_labels sl
+				WHERE sl.kind = 'Service' AND sl.namespace = s.namespace AND sl.name = s.name
+				  AND NOT EXISTS (
+					SELECT 1 FROM workload_labels wl
+					WHERE wl.namespace = w.namespace AND wl.name = w.name AND wl.key = sl.key AND wl.value = sl.value)))`
+	code, stdout, stderr = runTest(noPods, "query", "--format", "json", "-", withoutCart)
+	var result integrity.QueryResult
+	if code != exitOK || json.Unmarshal([]byte(stdout), &result) != nil || len(result.Rows) != 1 || result.Rows[0][1] != "cart" {
+		t.Errorf("Expected the cart VirtualService, got %d:\n%s%s", code, stdout, stderr)
+	}
+
+	if code, stdout, _ := runTest("", "query", "--schema", traffic); code != exitOK || !strings.Contains(stdout, "CREATE TABLE services (") {
+		t.Errorf("Expected the schema, got %d:\n%s", code, stdout)
+	}
+	if code, stdout, stderr := runTest("", "query", "--max-rows", "1", "SELECT name FROM workloads", traffic); code != exitOK ||
+		strings.Count(stdout, "\n") != 2 || !strings.Contains(stderr, "truncated to 1 rows") {
+		t.Errorf("Expected a truncated result, got %d:\n%s%s", code, stdout, stderr)
+	}
+
+	// Снимок вместо кластера
+	snapshot := filepath.Join(t.TempDir(), "snapshot.db")
+	if code, _, stderr := runTest("", "snapshot", "save", "-o", snapshot, traffic); code != exitOK {
+		t.Fatalf("Failed to save snapshot: %s", stderr)
+	}
+	if code, stdout, stderr := runTest("", "query", "--snapshot", snapshot, "SELECT count(*) AS pods FROM workloads"); code != exitOK || stdout != "pods\n2\n" {
+		t.Errorf("Expected the snapshot to be queried, got %d:\n%s%s", code, stdout, stderr)
+	}
+
+	for _, args := range [][]string{
+		{"query"},
+		{"query", "--format", "xml", "SELECT 1", traffic},
+		{"query", "DELETE FROM services", traffic},
+		{"query", "SELECT 1; DROP TABLE services", traffic},
+		{"query", "SELECT * FROM no_such_table", traffic},
+		{"query", "--timeout", "100ms", "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT count(*) FROM c", traffic},
+		{"query", "--snapshot", snapshot, "SELECT 1", traffic},
+	} {
+		if code, _, _ := runTest("", args...); code != exitFailure {
+			t.Errorf("%v: expected exit %d, got %d", args, exitFailure, code)
+		}
+	}
+}
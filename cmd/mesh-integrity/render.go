@@ -0,0 +1,91 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity/loader"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity/render"
+)
+
+// renderOptions select Helm charts and kustomizations rendered in-process as lint inputs
+type renderOptions struct {
+	charts         []string
+	kustomizations []string
+	helm           render.HelmOptions
+}
+
+func (r *renderOptions) register(flags *flag.FlagSet) {
+	appendTo := func(values *[]string) func(string) error {
+		return func(value string) error {
+			*values = append(*values, value)
+			return nil
+		}
+	}
+	flags.Func("helm", "Local chart directory or archive rendered like helm template; may be repeated", appendTo(&r.charts))
+	flags.Func("kustomize", "Kustomization directory built like kustomize build; may be repeated", appendTo(&r.kustomizations))
+	valuesUsage := "Values file of the charts, later files win; may be repeated"
+	flags.Func("f", valuesUsage, appendTo(&r.helm.ValueFiles))
+	flags.Func("values", valuesUsage, appendTo(&r.helm.ValueFiles))
+	flags.Func("set", "Value of the charts, key=value; may be repeated", appendTo(&r.helm.Values))
+	flags.StringVar(&r.helm.ReleaseName, "release-name", render.DefaultReleaseName, "Release name of the charts")
+	flags.StringVar(&r.helm.Namespace, "namespace", render.DefaultNamespace, "Release namespace of the charts")
+	flags.StringVar(&r.helm.KubeVersion, "kube-version", "", "Kubernetes version of .Capabilities of the charts")
+}
+
+func (r *renderOptions) active() bool {
+	return len(r.charts) > 0 || len(r.kustomizations) > 0
+}
+
+// validate rejects chart settings without a chart: they would be silently ignored
+func (r *renderOptions) validate() error {
+	if len(r.charts) == 0 && (len(r.helm.ValueFiles) > 0 || len(r.helm.Values) > 0) {
+		return errors.New("-f, --values and --set require --helm")
+	}
+	return nil
+}
+
+// load renders the charts and kustomizations and returns the objects and the number of
+// inputs rendered. Errors are printed like the errors of loadInputs.
+func (r *renderOptions) load(stderr io.Writer) ([]loader.Object, int, bool) {
+	var objects []loader.Object
+	ok := true
+	add := func(loaded []loader.Object, err error) {
+		objects = append(objects, loaded...)
+		var parseErrors loader.ParseErrors
+		if errors.As(err, &parseErrors) {
+			for _, parseError := range parseErrors {
+				fmt.Fprintf(stderr, "mesh-integrity: %v\n", parseError)
+			}
+			ok = false
+		} else if err != nil {
+			fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+			ok = false
+		}
+	}
+	for _, chart := range r.charts {
+		add(render.Helm(chart, r.helm))
+	}
+	for _, kustomization := range r.kustomizations {
+		add(render.Kustomize(kustomization))
+	}
+	return objects, len(r.charts) + len(r.kustomizations), ok
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity/cluster"
+)
+
+// runSnapshot saves the relational model of a cluster or of manifests to a file and
+// replays the integrity checks over a saved snapshot
+func runSnapshot(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	usage := func() {
+		fmt.Fprintln(stderr, "Usage: mesh-integrity snapshot save [flags] -o file [file | directory | -]...")
+		fmt.Fprintln(stderr, "       mesh-integrity snapshot check [flags] file")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "save writes every table of the relational model of the cluster, or of the manifests")
+		fmt.Fprintln(stderr, "when given, to a SQLite database or a JSON/YAML bundle. check imports a snapshot and")
+		fmt.Fprintln(stderr, "runs the integrity checks over it.")
+	}
+	if len(args) == 0 {
+		usage()
+		return exitFailure
+	}
+	switch args[0] {
+	case "save":
+		return runSnapshotSave(args[1:], stdin, stdout, stderr)
+	case "check":
+		return runSnapshotCheck(args[1:], stdout, stderr)
+	case "-h", "--help", "help":
+		usage()
+		return exitOK
+	}
+	fmt.Fprintf(stderr, "mesh-integrity: unknown snapshot command %q\n\n", args[0])
+	usage()
+	return exitFailure
+}
+
+func runSnapshotSave(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("snapshot save", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var output, format, kubeconfig, kubeContext string
+	flags.StringVar(&output, "o", "", "Snapshot file to write")
+	flags.StringVar(&format, "format", "", "Snapshot format: sqlite, json or yaml; derived from the file extension by default")
+	flags.StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig of the cluster")
+	flags.StringVar(&kubeContext, "context", "", "Kubeconfig context of the cluster")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitFailure
+	}
+	if output == "" {
+		fmt.Fprintln(stderr, "mesh-integrity: -o is required")
+		return exitFailure
+	}
+	snapshotFormat, err := integrity.ParseSnapshotFormat(format, output)
+	if err != nil {
+		fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+		return exitFailure
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), liveTimeout)
+	defer cancel()
+	var model *integrity.RelationalModel
+	if inputs := flags.Args(); len(inputs) > 0 {
+		objects, _, ok := loadInputs(inputs, stdin, stderr)
+		if !ok {
+			return exitFailure
+		}
+		if model, err = manifestModel(objects); err != nil {
+			fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+			return exitFailure
+		}
+	} else if model, err = cluster.Model(ctx, kubeconfig, kubeContext); err != nil {
+		fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+		return exitFailure
+	}
+
+	operator := integrity.NewSQLiteIntegrityOperator(nil)
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+		return exitFailure
+	}
+	defer db.Close()
+	if err := operator.SaveSnapshot(ctx, db, output, snapshotFormat); err != nil {
+		fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+		return exitFailure
+	}
+	fmt.Fprintf(stdout, "snapshot written to %s (%s)\n", output, snapshotFormat)
+	return exitOK
+}
+
+func runSnapshotCheck(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("snapshot check", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var quiet bool
+	var format string
+	flags.BoolVar(&quiet, "quiet", false, "Print only the summary line")
+	flags.StringVar(&format, "format", formatText, "Output format: "+strings.Join(formats, ", "))
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitFailure
+	}
+	if !slices.Contains(formats, format) {
+		fmt.Fprintf(stderr, "mesh-integrity: unknown format %q, expected one of %s\n", format, strings.Join(formats, ", "))
+		return exitFailure
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "mesh-integrity: expected a single snapshot file")
+		return exitFailure
+	}
+
+	ctx := context.Background()
+	operator := integrity.NewSQLiteIntegrityOperator(nil)
+	db, err := operator.LoadSnapshot(ctx, flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+		return exitFailure
+	}
+	defer db.Close()
+
+	report, err := operator.CheckIntegrity(db)
+	if err == nil {
+		report.RepairPlans, err = operator.ComputeRepairPlans(db, report)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+		return exitFailure
+	}
+	if err := writeReport(stdout, format, report, nil, 1, quiet); err != nil {
+		fmt.Fprintf(stderr, "mesh-integrity: %v\n", err)
+		return exitFailure
+	}
+	if countErrors(report.Violations) > 0 {
+		return exitViolations
+	}
+	return exitOK
+}
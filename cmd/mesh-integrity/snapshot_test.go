@@ -0,0 +1,45 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	invalid := "../../internal/integrity/testdata/invalid-mesh-resources.yaml"
+	dir := t.TempDir()
+
+	// Снимок в каждом формате воспроизводит нарушения манифестов
+	for _, name := range []string{"snapshot.db", "snapshot.json", "snapshot.yaml"} {
+		path := filepath.Join(dir, name)
+		if code, stdout, stderr := runTest("", "snapshot", "save", "-o", path, invalid); code != exitOK || !strings.Contains(stdout, "snapshot written to "+path) {
+			t.Fatalf("%s: expected the snapshot to be saved, got %d:\n%s%s", name, code, stdout, stderr)
+		}
+		if code, stdout, stderr := runTest("", "snapshot", "check", path); code != exitViolations || !strings.Contains(stdout, "error: ForeignKeyViolation VirtualService/default/broken-vs") {
+			t.Errorf("%s: expected exit %d with the violations of the manifests, got %d:\n%s%s", name, exitViolations, code, stdout, stderr)
+		}
+		// Снимок служит базой для --live-snapshot
+		if code, stdout, stderr := runTest("", "lint", "--live-snapshot", path, "--delete", "VirtualService/default/broken-vs"); code != exitOK {
+			t.Errorf("%s: expected --live-snapshot to accept the snapshot, got %d:\n%s%s", name, code, stdout, stderr)
+		}
+	}
+
+	if code, stdout, _ := runTest("", "snapshot", "check", "--format", "json", filepath.Join(dir, "snapshot.json")); code != exitViolations || !strings.Contains(stdout, `"version": "mesh-integrity/v1"`) {
+		t.Errorf("Expected a JSON report, got %d:\n%s", code, stdout)
+	}
+
+	for _, args := range [][]string{
+		{"snapshot"},
+		{"snapshot", "restore"},
+		{"snapshot", "save", invalid},
+		{"snapshot", "save", "--format", "xml", "-o", filepath.Join(dir, "snapshot.xml"), invalid},
+		{"snapshot", "check"},
+		{"snapshot", "check", filepath.Join(dir, "does-not-exist.db")},
+		{"snapshot", "check", invalid},
+	} {
+		if code, _, _ := runTest("", args...); code != exitFailure {
+			t.Errorf("%v: expected exit %d, got %d", args, exitFailure, code)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package httpapi serves the relational model of the cluster over HTTP. Handlers are
+// registered on the metrics server of the manager, which protects them with authn/authz.
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity/loader"
+)
+
+var impactlog = logf.Log.WithName("impact-api")
+
+// ImpactPath is the path of the impact analysis endpoint
+const ImpactPath = "/impact"
+
+// maxRequestBytes limits the size of a request body
+const maxRequestBytes = 1 << 20
+
+// ImpactRequest is the body of POST /impact: the operation and the object as it would be
+// applied, or its apiVersion, kind and metadata for a delete
+type ImpactRequest struct {
+	Operation integrity.MutationOperation `json:"operation"`
+	Object    runtime.RawExtension        `json:"object"`
+}
+
+// ImpactHandler answers "what breaks if this object changes" with an integrity.ImpactReport.
+// Objects are decoded with the manifest loader, so every version it supports is accepted.
+type ImpactHandler struct {
+	Client client.Client
+}
+
+// NewImpactHandler creates an ImpactHandler reading the cluster through the client
+func NewImpactHandler(c client.Client) *ImpactHandler {
+	return &ImpactHandler{Client: c}
+}
+
+// ServeHTTP implements http.Handler
+func (h *ImpactHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request ImpactRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	mutation, err := h.mutation(request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	operator := integrity.NewSQLiteIntegrityOperator(h.Client)
+	report, err := operator.AnalyzeClusterImpact(r.Context(), mutation)
+	if err != nil {
+		impactlog.Error(err, "impact analysis failed", "operation", mutation.Operation)
+		http.Error(w, fmt.Sprintf("impact analysis failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	impactlog.Info("Impact analyzed", "operation", report.Operation, "resource", report.Resource,
+		"introduced", len(report.Introduced), "resolved", len(report.Resolved))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		impactlog.Error(err, "failed to write impact report")
+	}
+}
+
+// mutation decodes the object of the request into a typed object of the model
+func (h *ImpactHandler) mutation(request ImpactRequest) (integrity.Mutation, error) {
+	switch request.Operation {
+	case integrity.MutationApply, integrity.MutationDelete:
+	default:
+		return integrity.Mutation{}, fmt.Errorf("unknown operation %q, expected %q or %q",
+			request.Operation, integrity.MutationApply, integrity.MutationDelete)
+	}
+	if len(request.Object.Raw) == 0 {
+		return integrity.Mutation{}, fmt.Errorf("object is required")
+	}
+
+	obj, err := loader.Decode(request.Object.Raw)
+	if err != nil {
+		return integrity.Mutation{}, fmt.Errorf("invalid object: %w", err)
+	}
+	if _, isNamespace := obj.(*corev1.Namespace); obj.GetName() == "" || (obj.GetNamespace() == "" && !isNamespace) {
+		return integrity.Mutation{}, fmt.Errorf("object metadata.name and metadata.namespace are required")
+	}
+	return integrity.Mutation{Operation: request.Operation, Object: obj}, nil
+}
@@ -0,0 +1,122 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	networking "istio.io/api/networking/v1beta1"
+	istio "istio.io/client-go/pkg/apis/networking/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+)
+
+func newTestHandler(t *testing.T, objects ...client.Object) *ImpactHandler {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to register core types: %v", err)
+	}
+	if err := integrity.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to register mesh types: %v", err)
+	}
+	return NewImpactHandler(fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build())
+}
+
+func serveImpact(handler http.Handler, method, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, ImpactPath, strings.NewReader(body)))
+	return recorder
+}
+
+func TestImpactHandler(t *testing.T) {
+	handler := newTestHandler(t,
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "payments", Name: "api", Annotations: map[string]string{"mesh.operator.istio.io/managed": "true"}},
+			Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 8080}}},
+		},
+		&istio.Gateway{ObjectMeta: metav1.ObjectMeta{Namespace: "istio-system", Name: "public"}},
+		&istio.VirtualService{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "payments", Name: "api"},
+			Spec: networking.VirtualService{
+				Hosts:    []string{"api.example.com"},
+				Gateways: []string{"istio-system/public"},
+				Http: []*networking.HTTPRoute{{
+					Route: []*networking.HTTPRouteDestination{{
+						Destination: &networking.Destination{
+							Host: "api.payments.svc.cluster.local",
+							Port: &networking.PortSelector{Number: 8080},
+						},
+					}},
+				}},
+			},
+		},
+	)
+
+	recorder := serveImpact(handler, http.MethodPost, `{
+		"operation": "delete",
+		"object": {"apiVersion": "v1", "kind": "Service", "metadata": {"namespace": "payments", "name": "api"}}
+	}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", recorder.Code, recorder.Body)
+	}
+	var report integrity.ImpactReport
+	if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to decode impact report: %v", err)
+	}
+	if report.Resource != "Service/payments/api" || report.Operation != integrity.MutationDelete {
+		t.Errorf("Unexpected report %s %s", report.Operation, report.Resource)
+	}
+	if len(report.Dependents) != 1 || report.Dependents[0].Resource() != "VirtualService/payments/api" || len(report.Introduced) == 0 {
+		t.Errorf("Expected VirtualService/payments/api to break, got dependents %v, introduced %v", report.Dependents, report.Introduced)
+	}
+
+	// Изменение портов Service ломает VirtualService, который направляет трафик на прежний порт
+	recorder = serveImpact(handler, http.MethodPost, `{
+		"operation": "apply",
+		"object": {"apiVersion": "v1", "kind": "Service", "metadata": {"namespace": "payments", "name": "api"},
+			"spec": {"ports": [{"name": "http", "port": 9090}]}}
+	}`)
+	report = integrity.ImpactReport{}
+	if recorder.Code != http.StatusOK || json.Unmarshal(recorder.Body.Bytes(), &report) != nil {
+		t.Fatalf("Expected 200, got %d: %s", recorder.Code, recorder.Body)
+	}
+	if report.Operation != integrity.MutationApply || len(report.Introduced) != 1 ||
+		report.Introduced[0].Resource != "VirtualService/payments/api" || !strings.Contains(report.Introduced[0].Message, "port 8080") {
+		t.Errorf("Expected changing the ports of Service/payments/api to break VirtualService/payments/api, got %v", report.Introduced)
+	}
+
+	// networking.istio.io/v1 декодируется в тот же тип, что и v1beta1
+	recorder = serveImpact(handler, http.MethodPost, `{
+		"operation": "delete",
+		"object": {"apiVersion": "networking.istio.io/v1", "kind": "Gateway", "metadata": {"namespace": "istio-system", "name": "public"}}
+	}`)
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "VirtualService/payments/api") {
+		t.Errorf("Expected deleting a networking.istio.io/v1 Gateway to break VirtualService/payments/api, got %d: %s", recorder.Code, recorder.Body)
+	}
+
+	for _, tt := range []struct {
+		method string
+		body   string
+		code   int
+	}{
+		{http.MethodGet, "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "{", http.StatusBadRequest},
+		{http.MethodPost, `{"operation": "patch", "object": {"apiVersion": "v1", "kind": "Service", "metadata": {"namespace": "payments", "name": "api"}}}`, http.StatusBadRequest},
+		{http.MethodPost, `{"operation": "apply", "object": {"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"namespace": "payments", "name": "api"}}}`, http.StatusBadRequest},
+		{http.MethodPost, `{"operation": "apply", "object": {"apiVersion": "v1", "kind": "Service", "metadata": {"name": "api"}}}`, http.StatusBadRequest},
+		{http.MethodPost, `{"operation": "apply", "object": {"apiVersion": "networking.istio.io/v2", "kind": "Gateway", "metadata": {"namespace": "payments", "name": "api"}}}`, http.StatusBadRequest},
+	} {
+		if recorder := serveImpact(handler, tt.method, tt.body); recorder.Code != tt.code {
+			t.Errorf("%s %s: expected %d, got %d: %s", tt.method, tt.body, tt.code, recorder.Code, recorder.Body)
+		}
+	}
+}
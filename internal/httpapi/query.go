@@ -0,0 +1,115 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+)
+
+var querylog = logf.Log.WithName("query-api")
+
+// QueryPath is the path of the read-only SQL query endpoint
+const QueryPath = "/query"
+
+// QueryHandler runs a single read-only SELECT over the relational model of the cluster.
+// The statement is the q parameter of a GET or the body of a POST; without a statement
+// the handler returns the schema from sqlite_master.
+type QueryHandler struct {
+	Client  client.Client
+	Options integrity.QueryOptions
+}
+
+// NewQueryHandler creates a QueryHandler with the default timeout, row and byte limits
+func NewQueryHandler(c client.Client) *QueryHandler {
+	return &QueryHandler{
+		Client: c,
+		Options: integrity.QueryOptions{
+			Timeout:  integrity.DefaultQueryTimeout,
+			MaxRows:  integrity.DefaultQueryMaxRows,
+			MaxBytes: integrity.DefaultQueryMaxBytes,
+		},
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *QueryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var statement string
+	switch r.Method {
+	case http.MethodGet:
+		statement = r.URL.Query().Get("q")
+	case http.MethodPost:
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, integrity.MaxQueryLength))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read statement: %v", err), http.StatusBadRequest)
+			return
+		}
+		statement = string(body)
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		http.Error(w, "only GET and POST are supported", http.StatusMethodNotAllowed)
+		return
+	}
+	if statement == "" {
+		statement = integrity.SchemaQuery
+	}
+	// Недопустимый оператор отклоняется до построения модели кластера
+	if _, err := integrity.ValidateQuery(statement); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	operator := integrity.NewSQLiteIntegrityOperator(h.Client)
+	model, err := operator.BuildRelationalModel(r.Context())
+	if err != nil {
+		querylog.Error(err, "failed to build relational model")
+		http.Error(w, fmt.Sprintf("failed to build relational model: %v", err), http.StatusInternalServerError)
+		return
+	}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	result, err := operator.Query(r.Context(), db, statement, h.Options)
+	if err != nil {
+		querylog.Info("query failed", "statement", statement, "error", err.Error())
+		status := http.StatusBadRequest
+		if errors.Is(err, context.DeadlineExceeded) {
+			status = http.StatusGatewayTimeout
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	querylog.Info("query", "statement", statement, "rows", len(result.Rows), "truncated", result.Truncated)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		querylog.Error(err, "failed to write query result")
+	}
+}
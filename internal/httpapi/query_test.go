@@ -0,0 +1,77 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	networking "istio.io/api/networking/v1beta1"
+	istio "istio.io/client-go/pkg/apis/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+)
+
+func TestQueryHandler(t *testing.T) {
+	handler := NewQueryHandler(newTestHandler(t, &istio.VirtualService{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "payments", Name: "api"},
+		Spec: networking.VirtualService{
+			Hosts:    []string{"api.example.com"},
+			Gateways: []string{"istio-system/public"},
+		},
+	}).Client)
+	serve := func(request *http.Request) (*httptest.ResponseRecorder, *integrity.QueryResult) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		var result integrity.QueryResult
+		if recorder.Code == http.StatusOK {
+			if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+				t.Fatalf("Failed to decode result: %v", err)
+			}
+		}
+		return recorder, &result
+	}
+
+	statement := "SELECT namespace, name, gateway_name FROM virtual_services"
+	recorder, result := serve(httptest.NewRequest(http.MethodGet, QueryPath+"?q="+url.QueryEscape(statement), nil))
+	if recorder.Code != http.StatusOK || len(result.Rows) != 1 || result.Rows[0][2] != "public" {
+		t.Fatalf("Expected the VirtualService row, got %d: %s", recorder.Code, recorder.Body)
+	}
+	recorder, result = serve(httptest.NewRequest(http.MethodPost, QueryPath, strings.NewReader(statement)))
+	if recorder.Code != http.StatusOK || len(result.Rows) != 1 {
+		t.Errorf("Expected the statement in the body to run, got %d: %s", recorder.Code, recorder.Body)
+	}
+
+	// Без оператора возвращается схема модели
+	recorder, result = serve(httptest.NewRequest(http.MethodGet, QueryPath, nil))
+	if recorder.Code != http.StatusOK || len(result.Columns) != 4 || result.Columns[3] != "sql" || len(result.Rows) == 0 {
+		t.Errorf("Expected the schema, got %d: %s", recorder.Code, recorder.Body)
+	}
+
+	handler.Options = integrity.QueryOptions{MaxRows: 1}
+	if _, result = serve(httptest.NewRequest(http.MethodGet, QueryPath, nil)); len(result.Rows) != 1 || !result.Truncated {
+		t.Errorf("Expected the row limit to apply, got %v", result)
+	}
+	handler.Options = integrity.QueryOptions{Timeout: 100 * time.Millisecond}
+	endless := "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT count(*) FROM c"
+	if recorder, _ = serve(httptest.NewRequest(http.MethodPost, QueryPath, strings.NewReader(endless))); recorder.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504 for a query over the timeout, got %d: %s", recorder.Code, recorder.Body)
+	}
+
+	for _, statement := range []string{
+		"DELETE FROM virtual_services",
+		"SELECT 1; DROP TABLE virtual_services",
+		"SELECT * FROM no_such_table",
+	} {
+		if recorder, _ := serve(httptest.NewRequest(http.MethodPost, QueryPath, strings.NewReader(statement))); recorder.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d: %s", statement, recorder.Code, recorder.Body)
+		}
+	}
+	if recorder, _ := serve(httptest.NewRequest(http.MethodDelete, QueryPath, nil)); recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for DELETE, got %d", recorder.Code)
+	}
+}
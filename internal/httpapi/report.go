@@ -0,0 +1,115 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+	integrityreport "github.com/mdarin/istio-integrity-operator/internal/integrity/report"
+)
+
+var reportlog = logf.Log.WithName("report-api")
+
+// ReportPath is the path of the integrity report endpoint
+const ReportPath = "/report"
+
+// ReportHandler serves the integrity report of the cluster: violations and repair plans.
+// The report is SARIF when requested with ?format=sarif or Accept: application/sarif+json.
+type ReportHandler struct {
+	Client client.Client
+}
+
+// NewReportHandler creates a ReportHandler reading the cluster through the client
+func NewReportHandler(c client.Client) *ReportHandler {
+	return &ReportHandler{Client: c}
+}
+
+// ServeHTTP implements http.Handler
+func (h *ReportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	sarif, err := wantsSARIF(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.report(r)
+	if err != nil {
+		reportlog.Error(err, "integrity check failed")
+		http.Error(w, fmt.Sprintf("integrity check failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if sarif {
+		w.Header().Set("Content-Type", integrityreport.SARIFMediaType)
+		// Ресурсы кластера не имеют файлов, SARIF содержит только логические расположения
+		err = integrityreport.WriteSARIF(w, report, nil)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(report)
+	}
+	if err != nil {
+		reportlog.Error(err, "failed to write integrity report")
+	}
+}
+
+func (h *ReportHandler) report(r *http.Request) (*integrity.IntegrityReport, error) {
+	operator := integrity.NewSQLiteIntegrityOperator(h.Client)
+	model, err := operator.BuildRelationalModel(r.Context())
+	if err != nil {
+		return nil, fmt.Errorf("failed to build relational model: %w", err)
+	}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	report, err := operator.CheckIntegrity(db)
+	if err != nil {
+		return nil, err
+	}
+	if report.RepairPlans, err = operator.ComputeRepairPlans(db, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// wantsSARIF выбирает формат: параметр format важнее заголовка Accept
+func wantsSARIF(r *http.Request) (bool, error) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "sarif":
+		return true, nil
+	case "json":
+		return false, nil
+	case "":
+		return strings.Contains(r.Header.Get("Accept"), integrityreport.SARIFMediaType), nil
+	default:
+		return false, fmt.Errorf("unknown format %q, expected json or sarif", format)
+	}
+}
@@ -0,0 +1,71 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	networking "istio.io/api/networking/v1beta1"
+	istio "istio.io/client-go/pkg/apis/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+	integrityreport "github.com/mdarin/istio-integrity-operator/internal/integrity/report"
+)
+
+func TestReportHandler(t *testing.T) {
+	handler := NewReportHandler(newTestHandler(t, &istio.VirtualService{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "payments", Name: "api"},
+		Spec: networking.VirtualService{
+			Hosts:    []string{"api.example.com"},
+			Gateways: []string{"istio-system/public"},
+		},
+	}).Client)
+
+	serve := func(target, accept string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		if accept != "" {
+			request.Header.Set("Accept", accept)
+		}
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := serve(ReportPath, "")
+	var report integrity.IntegrityReport
+	if recorder.Code != http.StatusOK || json.Unmarshal(recorder.Body.Bytes(), &report) != nil {
+		t.Fatalf("Expected a JSON report, got %d: %s", recorder.Code, recorder.Body)
+	}
+	if report.IsConsistent || len(report.Violations) == 0 || len(report.RepairPlans) == 0 {
+		t.Errorf("Expected violations and repair plans for the missing Gateway, got %+v", report)
+	}
+
+	for _, tt := range []struct {
+		target string
+		accept string
+	}{
+		{ReportPath + "?format=sarif", ""},
+		{ReportPath, integrityreport.SARIFMediaType},
+	} {
+		recorder := serve(tt.target, tt.accept)
+		var log integrityreport.SARIFLog
+		if recorder.Code != http.StatusOK || json.Unmarshal(recorder.Body.Bytes(), &log) != nil {
+			t.Fatalf("%s: expected a SARIF log, got %d: %s", tt.target, recorder.Code, recorder.Body)
+		}
+		if recorder.Header().Get("Content-Type") != integrityreport.SARIFMediaType || len(log.Runs[0].Results) != len(report.Violations) {
+			t.Errorf("%s: expected %d SARIF results, got %s with %d", tt.target, len(report.Violations),
+				recorder.Header().Get("Content-Type"), len(log.Runs[0].Results))
+		}
+	}
+
+	if recorder := serve(ReportPath+"?format=xml", ""); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown format, got %d", recorder.Code)
+	}
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, ReportPath, nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", recorder.Code)
+	}
+}
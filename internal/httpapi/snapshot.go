@@ -0,0 +1,119 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpapi
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+)
+
+var snapshotlog = logf.Log.WithName("snapshot-api")
+
+// SnapshotPath is the path of the snapshot export endpoint
+const SnapshotPath = "/snapshot"
+
+// snapshotContentTypes are the media types of the snapshot formats
+var snapshotContentTypes = map[integrity.SnapshotFormat]string{
+	integrity.SnapshotSQLite: "application/vnd.sqlite3",
+	integrity.SnapshotJSON:   "application/json",
+	integrity.SnapshotYAML:   "application/yaml",
+}
+
+// SnapshotHandler exports every table of the relational model of the cluster,
+// ?format= selects json (default), yaml or sqlite
+type SnapshotHandler struct {
+	Client client.Client
+}
+
+// NewSnapshotHandler creates a SnapshotHandler reading the cluster through the client
+func NewSnapshotHandler(c client.Client) *SnapshotHandler {
+	return &SnapshotHandler{Client: c}
+}
+
+// ServeHTTP implements http.Handler
+func (h *SnapshotHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		name = string(integrity.SnapshotJSON)
+	}
+	format, err := integrity.ParseSnapshotFormat(name, "")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	operator := integrity.NewSQLiteIntegrityOperator(h.Client)
+	model, err := operator.BuildRelationalModel(r.Context())
+	if err != nil {
+		snapshotlog.Error(err, "failed to build relational model")
+		http.Error(w, fmt.Sprintf("failed to build relational model: %v", err), http.StatusInternalServerError)
+		return
+	}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	if format != integrity.SnapshotSQLite {
+		w.Header().Set("Content-Type", snapshotContentTypes[format])
+		if err := operator.WriteSnapshot(r.Context(), w, db, format); err != nil {
+			snapshotlog.Error(err, "failed to write snapshot")
+		}
+		return
+	}
+
+	// SQLite пишет базу только в файл, поэтому снимок отдается из временного каталога
+	dir, err := os.MkdirTemp("", "mesh-snapshot-")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "snapshot.db")
+	if err := operator.SaveSnapshot(r.Context(), db, path, format); err != nil {
+		snapshotlog.Error(err, "failed to save snapshot")
+		http.Error(w, fmt.Sprintf("failed to save snapshot: %v", err), http.StatusInternalServerError)
+		return
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", snapshotContentTypes[format])
+	w.Header().Set("Content-Disposition", `attachment; filename="mesh-snapshot.db"`)
+	if _, err := io.Copy(w, file); err != nil {
+		snapshotlog.Error(err, "failed to write snapshot")
+	}
+}
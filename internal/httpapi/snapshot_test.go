@@ -0,0 +1,57 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	networking "istio.io/api/networking/v1beta1"
+	istio "istio.io/client-go/pkg/apis/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+)
+
+func TestSnapshotHandler(t *testing.T) {
+	handler := NewSnapshotHandler(newTestHandler(t, &istio.VirtualService{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "payments", Name: "api"},
+		Spec: networking.VirtualService{
+			Hosts:    []string{"api.example.com"},
+			Gateways: []string{"istio-system/public"},
+		},
+	}).Client)
+	operator := integrity.NewSQLiteIntegrityOperator(nil)
+	dir := t.TempDir()
+
+	// Снимок в любом формате импортируется и воспроизводит нарушения кластера
+	for _, format := range []string{"", "yaml", "sqlite"} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, SnapshotPath+"?format="+format, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("format %q: expected 200, got %d: %s", format, recorder.Code, recorder.Body)
+		}
+
+		path := filepath.Join(dir, "snapshot-"+format)
+		if err := os.WriteFile(path, recorder.Body.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		db, err := operator.LoadSnapshot(context.Background(), path)
+		if err != nil {
+			t.Fatalf("format %q: failed to load snapshot: %v", format, err)
+		}
+		report, err := operator.CheckIntegrity(db)
+		db.Close()
+		if err != nil || len(report.Violations) == 0 {
+			t.Errorf("format %q: expected the missing Gateway to be reported, got %v (%v)", format, report, err)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, SnapshotPath+"?format=xml", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown format, got %d", recorder.Code)
+	}
+}
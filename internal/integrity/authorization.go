@@ -0,0 +1,203 @@
+package integrity
+
+import (
+	"database/sql"
+	"fmt"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+)
+
+// knownNamespacesSQL объявляет CTE known_namespaces и known_service_accounts.
+// Namespace считается существующим, если он объявлен явно или в нем есть хотя бы один ресурс модели;
+// ServiceAccount default существует в каждом namespace.
+const knownNamespacesSQL = `
+	WITH known_namespaces(name) AS (
+		SELECT name FROM namespaces
+		UNION SELECT namespace FROM services
+		UNION SELECT namespace FROM workloads
+		UNION SELECT namespace FROM service_accounts
+		UNION SELECT namespace FROM virtual_services
+		UNION SELECT namespace FROM destination_rules
+		UNION SELECT namespace FROM gateways
+		UNION SELECT namespace FROM service_entries
+		UNION SELECT namespace FROM peer_authentications
+		UNION SELECT namespace FROM authorization_policies
+	),
+	known_service_accounts(namespace, name) AS (
+		SELECT namespace, name FROM service_accounts
+		UNION SELECT namespace, service_account FROM workloads
+		UNION SELECT name, 'default' FROM known_namespaces
+	)
+`
+
+// authorizationSource identifies a single rules[].from[].source field of a policy
+type authorizationSource struct {
+	namespace, name string
+	rule, from      int
+	field           string
+}
+
+// checkAuthorizationPolicyViolations проверяет ссылки AuthorizationPolicy на namespaces,
+// ServiceAccounts и workload, а также ALLOW политики, которые ничего не разрешают
+func (o *SQLiteIntegrityOperator) checkAuthorizationPolicyViolations(db *sql.DB) ([]meshv1alpha1.ConstraintViolation, error) {
+	var violations []meshv1alpha1.ConstraintViolation
+
+	// 1. source.namespaces и principals cluster.local/ns/<ns>/sa/<sa> -> Namespace, ServiceAccount.
+	// Значения с wildcard не проверяются, notNamespaces и notPrincipals сообщаются как Warning.
+	rows, err := db.Query(knownNamespacesSQL + `
+		SELECT s.namespace, s.name, s.rule_index, s.from_index, s.field, s.value,
+			CASE
+				WHEN s.field IN ('namespaces', 'notNamespaces') THEN
+					CASE WHEN instr(s.value, '*') = 0 AND s.value NOT IN (SELECT name FROM known_namespaces)
+						THEN 'Namespace/' || s.value ELSE '' END
+				WHEN s.principal_namespace = '' OR instr(s.principal_namespace, '*') > 0 THEN ''
+				WHEN s.principal_namespace NOT IN (SELECT name FROM known_namespaces)
+					THEN 'Namespace/' || s.principal_namespace
+				WHEN instr(s.principal_service_account, '*') = 0 AND NOT EXISTS (
+					SELECT 1 FROM known_service_accounts k
+					WHERE k.namespace = s.principal_namespace AND k.name = s.principal_service_account
+				) THEN 'ServiceAccount/' || s.principal_namespace || '/' || s.principal_service_account
+				ELSE ''
+			END AS missing
+		FROM authorization_policy_sources s
+		ORDER BY s.namespace, s.name, s.rule_index, s.from_index, s.field, s.value
+	`)
+	if err != nil {
+		return nil, err
+	}
+	values := map[authorizationSource]int{}
+	missingValues := map[authorizationSource]int{}
+	for rows.Next() {
+		var source authorizationSource
+		var value, missing string
+		if err := rows.Scan(&source.namespace, &source.name, &source.rule, &source.from, &source.field, &value, &missing); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		values[source]++
+		if missing == "" {
+			continue
+		}
+		missingValues[source]++
+		// Исключение несуществующего объекта сейчас ничего не меняет в политике, но
+		// начнет действовать, когда объект создадут
+		if source.field == "notNamespaces" || source.field == "notPrincipals" {
+			violations = append(violations, meshv1alpha1.ConstraintViolation{
+				Type:     "ForeignKeyViolation",
+				Resource: fmt.Sprintf("AuthorizationPolicy/%s/%s", source.namespace, source.name),
+				Message: fmt.Sprintf("rules[%d].from[%d].source.%s %q excludes non-existent %s, the exclusion has no effect",
+					source.rule, source.from, source.field, value, missing),
+				Severity: "Warning",
+			})
+			continue
+		}
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "ForeignKeyViolation",
+			Resource: fmt.Sprintf("AuthorizationPolicy/%s/%s", source.namespace, source.name),
+			Message: fmt.Sprintf("rules[%d].from[%d].source.%s %q references non-existent %s",
+				source.rule, source.from, source.field, value, missing),
+			Severity: "Error",
+		})
+	}
+	rows.Close()
+
+	// 2. selector не выбирает ни одного workload: политика ни к чему не применяется.
+	// Без workload в модели проверить selector нельзя.
+	rows, err = db.Query(`
+		SELECT ap.namespace, ap.name, ap.selector
+		FROM authorization_policies ap
+		WHERE ap.selector <> ''
+		  AND EXISTS (SELECT 1 FROM workloads)
+		  AND NOT EXISTS (
+			SELECT 1 FROM workloads w
+			WHERE (w.namespace = ap.namespace OR ap.namespace = ?)
+			  AND `+selectorMatchesSQL("AuthorizationPolicy", "ap.namespace", "ap.name", "w")+`
+		  )
+		ORDER BY ap.namespace, ap.name
+	`, istioRootNamespace)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var ns, name, selector string
+		if err := rows.Scan(&ns, &name, &selector); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "AuthorizationPolicyViolation",
+			Resource: fmt.Sprintf("AuthorizationPolicy/%s/%s", ns, name),
+			Message:  fmt.Sprintf("Selector %s matches no workload in namespace %s", selector, ns),
+			Severity: "Warning",
+		})
+	}
+	rows.Close()
+
+	// 3. ALLOW политика, все правила которой ссылаются только на несуществующие источники,
+	// не разрешает ни одного запроса: для выбранных workload это implicit deny-all.
+	// Политика без rules - намеренный allow-nothing и не сообщается.
+	rows, err = db.Query(`
+		SELECT r.namespace, r.name, r.rule_index, r.sources
+		FROM authorization_policies ap
+		JOIN authorization_policy_rules r ON r.namespace = ap.namespace AND r.name = ap.name
+		WHERE ap.action = 'ALLOW'
+		ORDER BY r.namespace, r.name, r.rule_index
+	`)
+	if err != nil {
+		return nil, err
+	}
+	type policyKey struct{ namespace, name string }
+	var policies []policyKey
+	matchesNothing := map[policyKey]bool{}
+	for rows.Next() {
+		var key policyKey
+		var rule, sources int
+		if err := rows.Scan(&key.namespace, &key.name, &rule, &sources); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if _, ok := matchesNothing[key]; !ok {
+			policies = append(policies, key)
+			matchesNothing[key] = true
+		}
+		if !ruleMatchesNothing(key.namespace, key.name, rule, sources, values, missingValues) {
+			matchesNothing[key] = false
+		}
+	}
+	rows.Close()
+
+	for _, key := range policies {
+		if !matchesNothing[key] {
+			continue
+		}
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "AuthorizationPolicyViolation",
+			Resource: fmt.Sprintf("AuthorizationPolicy/%s/%s", key.namespace, key.name),
+			Message:  "ALLOW policy matches no request: every rule references only non-existent sources, requests to the selected workloads are implicitly denied",
+			Severity: "Warning",
+		})
+	}
+
+	return violations, nil
+}
+
+// ruleMatchesNothing reports whether every from[] of a rule has a positive field
+// (principals or namespaces) whose values all reference non-existent objects
+func ruleMatchesNothing(namespace, name string, rule, sources int, values, missingValues map[authorizationSource]int) bool {
+	if sources == 0 {
+		return false
+	}
+	for from := 0; from < sources; from++ {
+		dead := false
+		for _, field := range []string{"principals", "namespaces"} {
+			source := authorizationSource{namespace: namespace, name: name, rule: rule, from: from, field: field}
+			if values[source] > 0 && missingValues[source] == values[source] {
+				dead = true
+			}
+		}
+		if !dead {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+// Package cluster reads the relational model of a cluster selected by a kubeconfig context,
+// for the command line tools. The manager uses its own client and cache instead.
+package cluster
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+)
+
+// NewClient creates a client for the kubeconfig context with the kinds of the model registered.
+// An empty kubeconfig uses the default loading rules, an empty context the current context.
+func NewClient(kubeconfig, kubeContext string) (client.Client, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules,
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := integrity.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	return c, nil
+}
+
+// Model builds the relational model of the cluster with BuildRelationalModel
+func Model(ctx context.Context, kubeconfig, kubeContext string) (*integrity.RelationalModel, error) {
+	c, err := NewClient(kubeconfig, kubeContext)
+	if err != nil {
+		return nil, err
+	}
+	return integrity.NewSQLiteIntegrityOperator(c).BuildRelationalModel(ctx)
+}
@@ -0,0 +1,900 @@
+package integrity
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+	networking "istio.io/api/networking/v1beta1"
+	istio "istio.io/client-go/pkg/apis/networking/v1beta1"
+	security "istio.io/client-go/pkg/apis/security/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+// exportToAnnotation is the Service annotation Istio reads instead of spec.exportTo
+const exportToAnnotation = "networking.istio.io/exportTo"
+
+// serviceToRecord преобразует Kubernetes Service в ServiceRecord
+func serviceToRecord(service *corev1.Service) ServiceRecord {
+	if service == nil {
+		return ServiceRecord{}
+	}
+
+	// Генерируем стандартный Kubernetes FQDN
+	host := fmt.Sprintf("%s.%s.svc.cluster.local", service.Name, service.Namespace)
+
+	record := ServiceRecord{
+		Namespace: service.Namespace,
+		Name:      service.Name,
+		Host:      host,
+		Protocol:  "TCP",
+		ExportTo:  serviceExportTo(service),
+		Selector:  joinLabels(service.Spec.Selector),
+	}
+
+	if len(service.Spec.Ports) > 0 {
+		record.Port = service.Spec.Ports[0].Port
+		if protocol := service.Spec.Ports[0].Protocol; protocol != "" {
+			record.Protocol = string(protocol)
+		}
+	}
+
+	return record
+}
+
+// serviceToPorts возвращает все порты Service
+func serviceToPorts(service *corev1.Service) []ServicePortRecord {
+	if service == nil {
+		return nil
+	}
+
+	records := make([]ServicePortRecord, 0, len(service.Spec.Ports))
+	for _, port := range service.Spec.Ports {
+		record := ServicePortRecord{
+			Namespace: service.Namespace,
+			Name:      service.Name,
+			PortName:  port.Name,
+			Port:      port.Port,
+			Protocol:  "TCP",
+		}
+		if port.Protocol != "" {
+			record.Protocol = string(port.Protocol)
+		}
+		// targetPort по умолчанию равен port; именованный targetPort без pod spec не разрешить
+		switch {
+		case port.TargetPort.Type == intstr.Int && port.TargetPort.IntVal != 0:
+			record.TargetPort = port.TargetPort.IntVal
+		case port.TargetPort.Type == intstr.Int:
+			record.TargetPort = port.Port
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// podToWorkload преобразует Pod в WorkloadRecord
+func podToWorkload(pod *corev1.Pod) WorkloadRecord {
+	if pod == nil {
+		return WorkloadRecord{}
+	}
+
+	serviceAccount := pod.Spec.ServiceAccountName
+	if serviceAccount == "" {
+		serviceAccount = "default"
+	}
+
+	return WorkloadRecord{
+		Namespace:      pod.Namespace,
+		Name:           pod.Name,
+		ServiceAccount: serviceAccount,
+		Labels:         joinLabels(pod.Labels),
+	}
+}
+
+// serviceExportTo returns the normalized exportTo list of a Service annotation
+func serviceExportTo(service *corev1.Service) string {
+	return joinExportTo(strings.Split(service.Annotations[exportToAnnotation], ","))
+}
+
+// virtualServiceToRecord преобразует Istio VirtualService в VirtualServiceRecord
+func virtualServiceToRecord(vs *istio.VirtualService) VirtualServiceRecord {
+	if vs == nil {
+		return VirtualServiceRecord{}
+	}
+
+	record := VirtualServiceRecord{
+		Namespace: vs.Namespace,
+		Name:      vs.Name,
+		Host:      "", // Берем первый host из спецификации
+		ExportTo:  joinExportTo(vs.Spec.ExportTo),
+	}
+
+	// Получаем hosts
+	if len(vs.Spec.Hosts) > 0 {
+		record.Host = vs.Spec.Hosts[0]
+	}
+
+	// Получаем gateway reference
+	if len(vs.Spec.Gateways) > 0 {
+		gatewayParts := strings.Split(vs.Spec.Gateways[0], "/")
+		if len(gatewayParts) == 2 {
+			record.GatewayNamespace = gatewayParts[0]
+			record.GatewayName = gatewayParts[1]
+		} else if len(gatewayParts) == 1 {
+			record.GatewayNamespace = vs.Namespace // gateway в том же namespace
+			record.GatewayName = gatewayParts[0]
+		}
+	}
+
+	// Получаем service reference из destination
+	if len(vs.Spec.Http) > 0 && len(vs.Spec.Http[0].Route) > 0 {
+		destination := vs.Spec.Http[0].Route[0].Destination
+		if destination != nil {
+			hostParts := strings.Split(destination.Host, ".")
+			if len(hostParts) >= 2 {
+				record.ServiceName = hostParts[0]
+				record.ServiceNamespace = hostParts[1]
+			}
+		}
+	}
+
+	return record
+}
+
+// virtualServiceToDestinations возвращает все destination из spec.http[].route[]
+func virtualServiceToDestinations(vs *istio.VirtualService) []VirtualServiceDestinationRecord {
+	if vs == nil {
+		return nil
+	}
+
+	var records []VirtualServiceDestinationRecord
+	for httpIndex, http := range vs.Spec.Http {
+		for routeIndex, route := range http.GetRoute() {
+			destination := route.GetDestination()
+			if destination == nil {
+				continue
+			}
+			records = append(records, VirtualServiceDestinationRecord{
+				Namespace:  vs.Namespace,
+				Name:       vs.Name,
+				HTTPIndex:  httpIndex,
+				RouteIndex: routeIndex,
+				Host:       destination.GetHost(),
+				Subset:     destination.GetSubset(),
+				Port:       int32(destination.GetPort().GetNumber()),
+				Weight:     route.GetWeight(),
+			})
+		}
+	}
+	return records
+}
+
+// virtualServiceToDelegates возвращает ребра http[].delegate
+func virtualServiceToDelegates(vs *istio.VirtualService) []VirtualServiceDelegateRecord {
+	if vs == nil {
+		return nil
+	}
+
+	var records []VirtualServiceDelegateRecord
+	for httpIndex, http := range vs.Spec.Http {
+		delegate := http.GetDelegate()
+		if delegate == nil {
+			continue
+		}
+		record := VirtualServiceDelegateRecord{
+			Namespace:         vs.Namespace,
+			Name:              vs.Name,
+			HTTPIndex:         httpIndex,
+			DelegateNamespace: delegate.GetNamespace(),
+			DelegateName:      delegate.GetName(),
+		}
+		if record.DelegateNamespace == "" {
+			record.DelegateNamespace = vs.Namespace
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// virtualServiceToHosts возвращает все spec.hosts VirtualService
+func virtualServiceToHosts(vs *istio.VirtualService) []VirtualServiceHostRecord {
+	if vs == nil {
+		return nil
+	}
+
+	records := make([]VirtualServiceHostRecord, 0, len(vs.Spec.Hosts))
+	for _, host := range vs.Spec.Hosts {
+		records = append(records, VirtualServiceHostRecord{Namespace: vs.Namespace, Name: vs.Name, Host: host})
+	}
+	return records
+}
+
+// virtualServiceToGateways возвращает spec.gateways; без gateways VirtualService применяется к mesh
+func virtualServiceToGateways(vs *istio.VirtualService) []VirtualServiceGatewayRecord {
+	if vs == nil {
+		return nil
+	}
+
+	gateways := vs.Spec.Gateways
+	if len(gateways) == 0 {
+		gateways = []string{"mesh"}
+	}
+
+	records := make([]VirtualServiceGatewayRecord, 0, len(gateways))
+	for _, gateway := range gateways {
+		record := VirtualServiceGatewayRecord{Namespace: vs.Namespace, Name: vs.Name, GatewayName: gateway}
+		if gatewayNs, gatewayName, ok := strings.Cut(gateway, "/"); ok {
+			record.GatewayNamespace, record.GatewayName = gatewayNs, gatewayName
+		} else if gateway != "mesh" {
+			record.GatewayNamespace = vs.Namespace
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// virtualServiceToHTTPMatches разворачивает spec.http[].match[] в строки routes, matches и headers
+func virtualServiceToHTTPMatches(vs *istio.VirtualService) ([]HTTPRouteRecord, []HTTPMatchRecord, []HTTPMatchHeaderRecord) {
+	if vs == nil {
+		return nil, nil, nil
+	}
+
+	var routes []HTTPRouteRecord
+	var matches []HTTPMatchRecord
+	var headers []HTTPMatchHeaderRecord
+	for httpIndex, http := range vs.Spec.Http {
+		routes = append(routes, HTTPRouteRecord{
+			Namespace: vs.Namespace,
+			Name:      vs.Name,
+			HTTPIndex: httpIndex,
+			RouteName: http.GetName(),
+			Matches:   len(http.GetMatch()),
+		})
+
+		for matchIndex, match := range http.GetMatch() {
+			record := HTTPMatchRecord{
+				Namespace:     vs.Namespace,
+				Name:          vs.Name,
+				HTTPIndex:     httpIndex,
+				MatchIndex:    matchIndex,
+				IgnoreURICase: match.GetIgnoreUriCase(),
+				HasOtherConditions: match.GetScheme() != nil || match.GetAuthority() != nil || match.GetPort() != 0 ||
+					len(match.GetSourceLabels()) > 0 || len(match.GetGateways()) > 0 || len(match.GetQueryParams()) > 0 ||
+					len(match.GetWithoutHeaders()) > 0 || match.GetSourceNamespace() != "",
+			}
+			record.URIType, record.URI = stringMatch(match.GetUri())
+			record.MethodType, record.Method = stringMatch(match.GetMethod())
+			matches = append(matches, record)
+
+			for header, value := range match.GetHeaders() {
+				headerRecord := HTTPMatchHeaderRecord{
+					Namespace:  vs.Namespace,
+					Name:       vs.Name,
+					HTTPIndex:  httpIndex,
+					MatchIndex: matchIndex,
+					Header:     strings.ToLower(header),
+				}
+				headerRecord.MatchType, headerRecord.Value = stringMatch(value)
+				headers = append(headers, headerRecord)
+			}
+		}
+	}
+	sort.Slice(headers, func(i, j int) bool {
+		if headers[i].HTTPIndex != headers[j].HTTPIndex {
+			return headers[i].HTTPIndex < headers[j].HTTPIndex
+		}
+		if headers[i].MatchIndex != headers[j].MatchIndex {
+			return headers[i].MatchIndex < headers[j].MatchIndex
+		}
+		return headers[i].Header < headers[j].Header
+	})
+	return routes, matches, headers
+}
+
+// stringMatch returns the type (exact, prefix, regex) and value of an Istio StringMatch
+func stringMatch(match *networking.StringMatch) (string, string) {
+	switch {
+	case match == nil:
+		return "", ""
+	case match.GetExact() != "":
+		return "exact", match.GetExact()
+	case match.GetPrefix() != "":
+		return "prefix", match.GetPrefix()
+	case match.GetRegex() != "":
+		return "regex", match.GetRegex()
+	}
+	// Пустой StringMatch (например, headers: {x-canary: {}}) проверяет только наличие
+	return "present", ""
+}
+
+// destinationRuleToRecord преобразует Istio DestinationRule в DestinationRuleRecord
+func destinationRuleToRecord(dr *istio.DestinationRule) DestinationRuleRecord {
+	if dr == nil {
+		return DestinationRuleRecord{}
+	}
+
+	record := DestinationRuleRecord{
+		Namespace:        dr.Namespace,
+		Name:             dr.Name,
+		ServiceNamespace: "",
+		ServiceName:      "",
+		Host:             dr.Spec.Host,
+		TrafficPolicy:    dr.Spec.TrafficPolicy.String(),
+		ExportTo:         joinExportTo(dr.Spec.ExportTo),
+		WorkloadSelector: joinLabels(dr.Spec.GetWorkloadSelector().GetMatchLabels()),
+	}
+
+	if !dr.CreationTimestamp.IsZero() {
+		record.CreatedAt = dr.CreationTimestamp.UTC().Format(time.RFC3339)
+	}
+
+	// Преобразуем subsets в строку
+	if len(dr.Spec.Subsets) > 0 {
+		var subsetNames []string
+		for _, subset := range dr.Spec.Subsets {
+			subsetNames = append(subsetNames, subset.Name)
+		}
+		record.Subsets = strings.Join(subsetNames, ",")
+	}
+
+	// Получаем service reference из host
+	hostParts := strings.Split(dr.Spec.Host, ".")
+	if len(hostParts) >= 2 {
+		record.ServiceNamespace = hostParts[1]
+		record.ServiceName = hostParts[0]
+	}
+
+	return record
+}
+
+// destinationRuleToSubsets возвращает spec.subsets DestinationRule с их labels
+func destinationRuleToSubsets(dr *istio.DestinationRule) []DestinationRuleSubsetRecord {
+	if dr == nil {
+		return nil
+	}
+
+	records := make([]DestinationRuleSubsetRecord, 0, len(dr.Spec.Subsets))
+	for _, subset := range dr.Spec.Subsets {
+		records = append(records, DestinationRuleSubsetRecord{
+			Namespace: dr.Namespace,
+			Name:      dr.Name,
+			Subset:    subset.GetName(),
+			Labels:    joinLabels(subset.GetLabels()),
+		})
+	}
+	return records
+}
+
+// destinationRuleToTrafficPolicies разворачивает trafficPolicy правила, его subsets
+// и portLevelSettings в плоские записи
+func destinationRuleToTrafficPolicies(dr *istio.DestinationRule) []TrafficPolicyRecord {
+	if dr == nil {
+		return nil
+	}
+
+	records := appendTrafficPolicy(nil, dr.Namespace, dr.Name, "", dr.Spec.TrafficPolicy)
+	for _, subset := range dr.Spec.Subsets {
+		records = appendTrafficPolicy(records, dr.Namespace, dr.Name, subset.GetName(), subset.GetTrafficPolicy())
+	}
+	return records
+}
+
+func appendTrafficPolicy(records []TrafficPolicyRecord, namespace, name, subset string, policy *networking.TrafficPolicy) []TrafficPolicyRecord {
+	if policy == nil {
+		return records
+	}
+
+	record := TrafficPolicyRecord{Namespace: namespace, Name: name, Subset: subset}
+	fillTrafficPolicy(&record, policy.GetLoadBalancer(), policy.GetConnectionPool(), policy.GetOutlierDetection(), policy.GetTls())
+	records = append(records, record)
+
+	for _, portPolicy := range policy.GetPortLevelSettings() {
+		portRecord := TrafficPolicyRecord{
+			Namespace: namespace,
+			Name:      name,
+			Subset:    subset,
+			Port:      int32(portPolicy.GetPort().GetNumber()),
+		}
+		fillTrafficPolicy(&portRecord, portPolicy.GetLoadBalancer(), portPolicy.GetConnectionPool(), portPolicy.GetOutlierDetection(), portPolicy.GetTls())
+		records = append(records, portRecord)
+	}
+	return records
+}
+
+func fillTrafficPolicy(
+	record *TrafficPolicyRecord,
+	lb *networking.LoadBalancerSettings,
+	pool *networking.ConnectionPoolSettings,
+	outlier *networking.OutlierDetection,
+	tls *networking.ClientTLSSettings,
+) {
+	// loadBalancer только с localityLbSetting или warmup не задает simple: GetSimple вернул бы UNSPECIFIED
+	switch policy := lb.GetLbPolicy().(type) {
+	case *networking.LoadBalancerSettings_ConsistentHash:
+		record.ConsistentHash = consistentHashKey(policy.ConsistentHash)
+	case *networking.LoadBalancerSettings_Simple:
+		record.LoadBalancer = policy.Simple.String()
+	}
+
+	record.MaxConnections = pool.GetTcp().GetMaxConnections()
+	record.HTTP1MaxPendingRequests = pool.GetHttp().GetHttp1MaxPendingRequests()
+	record.HTTP2MaxRequests = pool.GetHttp().GetHttp2MaxRequests()
+	record.MaxRequestsPerConnection = pool.GetHttp().GetMaxRequestsPerConnection()
+
+	if outlier != nil {
+		record.Consecutive5xxErrors = int32(outlier.GetConsecutive_5XxErrors().GetValue())
+		if interval := outlier.GetInterval(); interval != nil {
+			record.Interval = interval.AsDuration().String()
+		}
+		if ejection := outlier.GetBaseEjectionTime(); ejection != nil {
+			record.BaseEjectionTime = ejection.AsDuration().String()
+		}
+		record.MaxEjectionPercent = outlier.GetMaxEjectionPercent()
+	}
+
+	if tls != nil {
+		record.TLSMode = tls.GetMode().String()
+	}
+}
+
+// consistentHashKey описывает ключ consistentHash: httpHeaderName:x-user-id, httpCookie:session, useSourceIp
+func consistentHashKey(hash *networking.LoadBalancerSettings_ConsistentHashLB) string {
+	switch {
+	case hash.GetHttpHeaderName() != "":
+		return "httpHeaderName:" + hash.GetHttpHeaderName()
+	case hash.GetHttpCookie() != nil:
+		return "httpCookie:" + hash.GetHttpCookie().GetName()
+	case hash.GetUseSourceIp():
+		return "useSourceIp"
+	case hash.GetHttpQueryParameterName() != "":
+		return "httpQueryParameterName:" + hash.GetHttpQueryParameterName()
+	}
+	return "consistentHash"
+}
+
+// serviceEntryToRecords преобразует Istio ServiceEntry в ServiceEntryRecord (по одной записи на host)
+func serviceEntryToRecords(se *istio.ServiceEntry) []ServiceEntryRecord {
+	if se == nil {
+		return nil
+	}
+
+	records := make([]ServiceEntryRecord, 0, len(se.Spec.Hosts))
+	for _, host := range se.Spec.Hosts {
+		records = append(records, ServiceEntryRecord{
+			Namespace: se.Namespace,
+			Name:      se.Name,
+			Host:      host,
+			Location:  se.Spec.Location.String(),
+			ExportTo:  joinExportTo(se.Spec.ExportTo),
+		})
+	}
+	return records
+}
+
+// peerAuthenticationToRecords преобразует PeerAuthentication в запись политики и записи portLevelMtls
+func peerAuthenticationToRecords(pa *security.PeerAuthentication) (PeerAuthenticationRecord, []PeerAuthenticationPortRecord) {
+	if pa == nil {
+		return PeerAuthenticationRecord{}, nil
+	}
+
+	record := PeerAuthenticationRecord{
+		Namespace: pa.Namespace,
+		Name:      pa.Name,
+		Selector:  joinLabels(pa.Spec.GetSelector().GetMatchLabels()),
+		Mode:      pa.Spec.GetMtls().GetMode().String(),
+	}
+	if !pa.CreationTimestamp.IsZero() {
+		record.CreatedAt = pa.CreationTimestamp.UTC().Format(time.RFC3339)
+	}
+
+	ports := make([]PeerAuthenticationPortRecord, 0, len(pa.Spec.PortLevelMtls))
+	for port, mtls := range pa.Spec.PortLevelMtls {
+		ports = append(ports, PeerAuthenticationPortRecord{
+			Namespace: pa.Namespace,
+			Name:      pa.Name,
+			Port:      int32(port),
+			Mode:      mtls.GetMode().String(),
+		})
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].Port < ports[j].Port })
+
+	return record, ports
+}
+
+// authorizationPolicyToRecords разворачивает AuthorizationPolicy в строки rules, sources и operations
+func authorizationPolicyToRecords(ap *security.AuthorizationPolicy) (
+	AuthorizationPolicyRecord,
+	[]AuthorizationPolicyRuleRecord,
+	[]AuthorizationPolicySourceRecord,
+	[]AuthorizationPolicyOperationRecord,
+) {
+	if ap == nil {
+		return AuthorizationPolicyRecord{}, nil, nil, nil
+	}
+
+	record := AuthorizationPolicyRecord{
+		Namespace: ap.Namespace,
+		Name:      ap.Name,
+		Action:    ap.Spec.GetAction().String(),
+		Selector:  joinLabels(ap.Spec.GetSelector().GetMatchLabels()),
+		Rules:     len(ap.Spec.GetRules()),
+	}
+
+	var rules []AuthorizationPolicyRuleRecord
+	var sources []AuthorizationPolicySourceRecord
+	var operations []AuthorizationPolicyOperationRecord
+	for ruleIndex, rule := range ap.Spec.GetRules() {
+		rules = append(rules, AuthorizationPolicyRuleRecord{
+			Namespace:  ap.Namespace,
+			Name:       ap.Name,
+			RuleIndex:  ruleIndex,
+			Sources:    len(rule.GetFrom()),
+			Operations: len(rule.GetTo()),
+		})
+
+		for fromIndex, from := range rule.GetFrom() {
+			source := from.GetSource()
+			fields := []struct {
+				name      string
+				values    []string
+				principal bool
+			}{
+				{"principals", source.GetPrincipals(), true},
+				{"notPrincipals", source.GetNotPrincipals(), true},
+				{"namespaces", source.GetNamespaces(), false},
+				{"notNamespaces", source.GetNotNamespaces(), false},
+			}
+			for _, field := range fields {
+				for _, value := range field.values {
+					sourceRecord := AuthorizationPolicySourceRecord{
+						Namespace: ap.Namespace,
+						Name:      ap.Name,
+						RuleIndex: ruleIndex,
+						FromIndex: fromIndex,
+						Field:     field.name,
+						Value:     value,
+					}
+					if field.principal {
+						sourceRecord.PrincipalNamespace, sourceRecord.PrincipalServiceAccount = splitPrincipal(value)
+					}
+					sources = append(sources, sourceRecord)
+				}
+			}
+		}
+
+		for toIndex, to := range rule.GetTo() {
+			operation := to.GetOperation()
+			operations = append(operations, AuthorizationPolicyOperationRecord{
+				Namespace: ap.Namespace,
+				Name:      ap.Name,
+				RuleIndex: ruleIndex,
+				ToIndex:   toIndex,
+				Hosts:     strings.Join(operation.GetHosts(), ","),
+				Ports:     strings.Join(operation.GetPorts(), ","),
+				Methods:   strings.Join(operation.GetMethods(), ","),
+				Paths:     strings.Join(operation.GetPaths(), ","),
+			})
+		}
+	}
+
+	return record, rules, sources, operations
+}
+
+// splitPrincipal extracts namespace and service account from <trust-domain>/ns/<ns>/sa/<sa>
+func splitPrincipal(principal string) (string, string) {
+	parts := strings.Split(principal, "/")
+	if len(parts) != 5 || parts[1] != "ns" || parts[3] != "sa" {
+		return "", ""
+	}
+	return parts[2], parts[4]
+}
+
+// sidecarToRecords разворачивает Sidecar и его egress hosts в ns/dnsName форме
+func sidecarToRecords(sc *istio.Sidecar) (SidecarRecord, []SidecarEgressHostRecord) {
+	if sc == nil {
+		return SidecarRecord{}, nil
+	}
+
+	record := SidecarRecord{
+		Namespace:        sc.Namespace,
+		Name:             sc.Name,
+		WorkloadSelector: joinLabels(sc.Spec.GetWorkloadSelector().GetLabels()),
+		EgressListeners:  len(sc.Spec.GetEgress()),
+	}
+	if !sc.CreationTimestamp.IsZero() {
+		record.CreatedAt = sc.CreationTimestamp.UTC().Format(time.RFC3339)
+	}
+
+	var hosts []SidecarEgressHostRecord
+	for listenerIndex, listener := range sc.Spec.GetEgress() {
+		for _, host := range listener.GetHosts() {
+			// Istio требует форму namespace/dnsName
+			hostNamespace, dnsName, ok := strings.Cut(host, "/")
+			if !ok {
+				continue
+			}
+			hosts = append(hosts, SidecarEgressHostRecord{
+				Namespace:     sc.Namespace,
+				Name:          sc.Name,
+				ListenerIndex: listenerIndex,
+				HostNamespace: hostNamespace,
+				Host:          strings.ToLower(dnsName),
+			})
+		}
+	}
+	return record, hosts
+}
+
+// meshServiceToRecord преобразует MeshService в MeshServiceRecord
+func meshServiceToRecord(ms *meshv1alpha1.MeshService) MeshServiceRecord {
+	if ms == nil {
+		return MeshServiceRecord{}
+	}
+
+	record := MeshServiceRecord{
+		Namespace:        ms.Namespace,
+		Name:             ms.Name,
+		ServiceNamespace: ms.Spec.Namespace,
+		ServiceName:      ms.Spec.ServiceName,
+		Hosts:            strings.Join(ms.Spec.Hosts, ","),
+		GatewayNamespace: ms.Spec.Gateway.Namespace,
+		GatewayName:      ms.Spec.Gateway.Name,
+	}
+	// Service по умолчанию в namespace самого MeshService
+	if record.ServiceNamespace == "" {
+		record.ServiceNamespace = ms.Namespace
+	}
+	return record
+}
+
+// meshServiceToSubsets возвращает spec.subsets MeshService с labels и weight
+func meshServiceToSubsets(ms *meshv1alpha1.MeshService) []MeshServiceSubsetRecord {
+	if ms == nil {
+		return nil
+	}
+
+	records := make([]MeshServiceSubsetRecord, 0, len(ms.Spec.Subsets))
+	for _, subset := range ms.Spec.Subsets {
+		records = append(records, MeshServiceSubsetRecord{
+			Namespace: ms.Namespace,
+			Name:      ms.Name,
+			Subset:    subset.Name,
+			Labels:    joinLabels(subset.Labels),
+			Weight:    subset.Weight,
+		})
+	}
+	return records
+}
+
+// gatewayAPIGroup is the API group of Gateway API resources
+const gatewayAPIGroup = gatewayv1.GroupName
+
+// gatewayAPIGatewayToRecords преобразует Gateway API Gateway, его listeners и tls.certificateRefs
+func gatewayAPIGatewayToRecords(gw *gatewayv1.Gateway) (GatewayAPIGatewayRecord, []GatewayAPIListenerRecord, []GatewayAPICertificateRefRecord) {
+	if gw == nil {
+		return GatewayAPIGatewayRecord{}, nil, nil
+	}
+
+	record := GatewayAPIGatewayRecord{
+		Namespace:    gw.Namespace,
+		Name:         gw.Name,
+		GatewayClass: string(gw.Spec.GatewayClassName),
+	}
+
+	listeners := make([]GatewayAPIListenerRecord, 0, len(gw.Spec.Listeners))
+	var certificates []GatewayAPICertificateRefRecord
+	for _, listener := range gw.Spec.Listeners {
+		listenerRecord := GatewayAPIListenerRecord{
+			Namespace:         gw.Namespace,
+			Name:              gw.Name,
+			ListenerName:      string(listener.Name),
+			Port:              int32(listener.Port),
+			Protocol:          string(listener.Protocol),
+			AllowedNamespaces: string(gatewayv1.NamespacesFromSame),
+		}
+		if listener.Hostname != nil {
+			listenerRecord.Hostname = string(*listener.Hostname)
+		}
+		if allowed := listener.AllowedRoutes; allowed != nil {
+			if allowed.Namespaces != nil && allowed.Namespaces.From != nil {
+				listenerRecord.AllowedNamespaces = string(*allowed.Namespaces.From)
+			}
+			var kinds []string
+			for _, kind := range allowed.Kinds {
+				kinds = append(kinds, string(kind.Kind))
+			}
+			listenerRecord.AllowedKinds = strings.Join(kinds, ",")
+		}
+		listeners = append(listeners, listenerRecord)
+
+		if listener.TLS == nil {
+			continue
+		}
+		for refIndex, ref := range listener.TLS.CertificateRefs {
+			certificate := GatewayAPICertificateRefRecord{
+				Namespace:    gw.Namespace,
+				Name:         gw.Name,
+				ListenerName: string(listener.Name),
+				RefIndex:     refIndex,
+				RefKind:      "Secret",
+				RefNamespace: gw.Namespace,
+				RefName:      string(ref.Name),
+			}
+			if ref.Group != nil {
+				certificate.RefGroup = string(*ref.Group)
+			}
+			if ref.Kind != nil {
+				certificate.RefKind = string(*ref.Kind)
+			}
+			if ref.Namespace != nil {
+				certificate.RefNamespace = string(*ref.Namespace)
+			}
+			certificates = append(certificates, certificate)
+		}
+	}
+	return record, listeners, certificates
+}
+
+// httpRouteToRecords преобразует HTTPRoute в записи route, parentRefs и backendRefs
+func httpRouteToRecords(route *gatewayv1.HTTPRoute) (GatewayAPIRouteRecord, []GatewayAPIParentRefRecord, []GatewayAPIBackendRefRecord) {
+	if route == nil {
+		return GatewayAPIRouteRecord{}, nil, nil
+	}
+
+	const kind = "HTTPRoute"
+	var backends []GatewayAPIBackendRefRecord
+	for ruleIndex, rule := range route.Spec.Rules {
+		for refIndex, ref := range rule.BackendRefs {
+			backends = append(backends, backendRefToRecord(kind, route.Namespace, route.Name, ruleIndex, refIndex, ref.BackendRef))
+		}
+	}
+	return gatewayAPIRouteToRecord(kind, route.Namespace, route.Name, route.Spec.Hostnames),
+		parentRefsToRecords(kind, route.Namespace, route.Name, route.Spec.ParentRefs),
+		backends
+}
+
+// grpcRouteToRecords преобразует GRPCRoute в записи route, parentRefs и backendRefs
+func grpcRouteToRecords(route *gatewayv1.GRPCRoute) (GatewayAPIRouteRecord, []GatewayAPIParentRefRecord, []GatewayAPIBackendRefRecord) {
+	if route == nil {
+		return GatewayAPIRouteRecord{}, nil, nil
+	}
+
+	const kind = "GRPCRoute"
+	var backends []GatewayAPIBackendRefRecord
+	for ruleIndex, rule := range route.Spec.Rules {
+		for refIndex, ref := range rule.BackendRefs {
+			backends = append(backends, backendRefToRecord(kind, route.Namespace, route.Name, ruleIndex, refIndex, ref.BackendRef))
+		}
+	}
+	return gatewayAPIRouteToRecord(kind, route.Namespace, route.Name, route.Spec.Hostnames),
+		parentRefsToRecords(kind, route.Namespace, route.Name, route.Spec.ParentRefs),
+		backends
+}
+
+func gatewayAPIRouteToRecord(kind, namespace, name string, hostnames []gatewayv1.Hostname) GatewayAPIRouteRecord {
+	names := make([]string, 0, len(hostnames))
+	for _, hostname := range hostnames {
+		names = append(names, string(hostname))
+	}
+	return GatewayAPIRouteRecord{Kind: kind, Namespace: namespace, Name: name, Hostnames: strings.Join(names, ",")}
+}
+
+// parentRefsToRecords применяет значения по умолчанию Gateway API: group
+// gateway.networking.k8s.io, kind Gateway, namespace маршрута
+func parentRefsToRecords(kind, namespace, name string, refs []gatewayv1.ParentReference) []GatewayAPIParentRefRecord {
+	records := make([]GatewayAPIParentRefRecord, 0, len(refs))
+	for refIndex, ref := range refs {
+		record := GatewayAPIParentRefRecord{
+			Kind:            kind,
+			Namespace:       namespace,
+			Name:            name,
+			RefIndex:        refIndex,
+			ParentGroup:     gatewayAPIGroup,
+			ParentKind:      "Gateway",
+			ParentNamespace: namespace,
+			ParentName:      string(ref.Name),
+		}
+		if ref.Group != nil {
+			record.ParentGroup = string(*ref.Group)
+		}
+		if ref.Kind != nil {
+			record.ParentKind = string(*ref.Kind)
+		}
+		if ref.Namespace != nil {
+			record.ParentNamespace = string(*ref.Namespace)
+		}
+		if ref.SectionName != nil {
+			record.SectionName = string(*ref.SectionName)
+		}
+		if ref.Port != nil {
+			record.Port = int32(*ref.Port)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// backendRefToRecord применяет значения по умолчанию: core group, kind Service,
+// namespace маршрута, weight 1
+func backendRefToRecord(kind, namespace, name string, ruleIndex, refIndex int, ref gatewayv1.BackendRef) GatewayAPIBackendRefRecord {
+	record := GatewayAPIBackendRefRecord{
+		Kind:             kind,
+		Namespace:        namespace,
+		Name:             name,
+		RuleIndex:        ruleIndex,
+		RefIndex:         refIndex,
+		BackendKind:      "Service",
+		BackendNamespace: namespace,
+		BackendName:      string(ref.Name),
+		Weight:           1,
+	}
+	if ref.Group != nil {
+		record.BackendGroup = string(*ref.Group)
+	}
+	if ref.Kind != nil {
+		record.BackendKind = string(*ref.Kind)
+	}
+	if ref.Namespace != nil {
+		record.BackendNamespace = string(*ref.Namespace)
+	}
+	if ref.Port != nil {
+		record.Port = int32(*ref.Port)
+	}
+	if ref.Weight != nil {
+		record.Weight = *ref.Weight
+	}
+	return record
+}
+
+// referenceGrantToRecords разворачивает ReferenceGrant в пары from x to
+func referenceGrantToRecords(grant *gatewayv1beta1.ReferenceGrant) []ReferenceGrantRecord {
+	if grant == nil {
+		return nil
+	}
+
+	var records []ReferenceGrantRecord
+	for _, from := range grant.Spec.From {
+		for _, to := range grant.Spec.To {
+			record := ReferenceGrantRecord{
+				Namespace:     grant.Namespace,
+				Name:          grant.Name,
+				FromGroup:     string(from.Group),
+				FromKind:      string(from.Kind),
+				FromNamespace: string(from.Namespace),
+				ToGroup:       string(to.Group),
+				ToKind:        string(to.Kind),
+			}
+			if to.Name != nil {
+				record.ToName = string(*to.Name)
+			}
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// joinLabels serializes a label map into the sorted k1=v1,k2=v2 form stored in the model
+func joinLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// joinExportTo trims and joins exportTo entries into the comma separated form stored in the model
+func joinExportTo(exportTo []string) string {
+	var entries []string
+	for _, entry := range exportTo {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return strings.Join(entries, ",")
+}
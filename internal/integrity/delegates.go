@@ -0,0 +1,163 @@
+package integrity
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+)
+
+// maxDelegateDepth is the delegation depth Istio supports: a root VirtualService delegates
+// to children, children can not delegate further
+const maxDelegateDepth = 1
+
+// delegateRecursionLimit bounds the recursive CTE on graphs with long chains
+const delegateRecursionLimit = 32
+
+// checkDelegateViolations проверяет http[].delegate: дочерний VirtualService существует,
+// не задает hosts и gateways, а цепочки не образуют циклов и не глубже maxDelegateDepth
+func (o *SQLiteIntegrityOperator) checkDelegateViolations(db *sql.DB) ([]meshv1alpha1.ConstraintViolation, error) {
+	var violations []meshv1alpha1.ConstraintViolation
+
+	// 1. delegate -> VirtualService
+	rows, err := db.Query(`
+		SELECT d.namespace, d.name, d.http_index, d.delegate_namespace, d.delegate_name,
+			vs.namespace IS NOT NULL, COALESCE(vs.host, ''), COALESCE(vs.gateway_name, '')
+		FROM virtual_service_delegates d
+		LEFT JOIN virtual_services vs ON vs.namespace = d.delegate_namespace AND vs.name = d.delegate_name
+		ORDER BY d.namespace, d.name, d.http_index
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var ns, name, childNs, childName, childHost, childGateway string
+		var httpIndex int
+		var exists bool
+		if err := rows.Scan(&ns, &name, &httpIndex, &childNs, &childName, &exists, &childHost, &childGateway); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		resource := fmt.Sprintf("VirtualService/%s/%s", ns, name)
+		switch {
+		case !exists:
+			violations = append(violations, meshv1alpha1.ConstraintViolation{
+				Type:     "ForeignKeyViolation",
+				Resource: resource,
+				Message:  fmt.Sprintf("http[%d].delegate references non-existent VirtualService/%s/%s", httpIndex, childNs, childName),
+				Severity: "Error",
+			})
+		case childHost != "" || childGateway != "":
+			var defined []string
+			if childHost != "" {
+				defined = append(defined, "hosts")
+			}
+			if childGateway != "" {
+				defined = append(defined, "gateways")
+			}
+			violations = append(violations, meshv1alpha1.ConstraintViolation{
+				Type:     "DelegateViolation",
+				Resource: resource,
+				Message: fmt.Sprintf("http[%d].delegate VirtualService/%s/%s must not define %s; Istio ignores it as a delegate",
+					httpIndex, childNs, childName, strings.Join(defined, " and ")),
+				Severity: "Error",
+			})
+		}
+	}
+	rows.Close()
+
+	// 2. Обход цепочек delegate рекурсивным CTE: path хранит пройденные VirtualService
+	// в виде ,ns/name,ns/name, и обход останавливается на первом повторе
+	rows, err = db.Query(`
+		WITH RECURSIVE chain(root_namespace, root_name, namespace, name, depth, path, cycle) AS (
+			SELECT d.namespace, d.name, d.delegate_namespace, d.delegate_name, 1,
+				',' || d.namespace || '/' || d.name || ',' || d.delegate_namespace || '/' || d.delegate_name || ',',
+				d.namespace = d.delegate_namespace AND d.name = d.delegate_name
+			FROM virtual_service_delegates d
+			UNION ALL
+			SELECT c.root_namespace, c.root_name, d.delegate_namespace, d.delegate_name, c.depth + 1,
+				c.path || d.delegate_namespace || '/' || d.delegate_name || ',',
+				instr(c.path, ',' || d.delegate_namespace || '/' || d.delegate_name || ',') > 0
+			FROM chain c
+			JOIN virtual_service_delegates d ON d.namespace = c.namespace AND d.name = c.name
+			WHERE NOT c.cycle AND c.depth < ?
+		)
+		SELECT c.root_namespace, c.root_name, c.depth, c.path, c.cycle,
+			EXISTS (
+				SELECT 1 FROM virtual_service_delegates p
+				WHERE p.delegate_namespace = c.root_namespace AND p.delegate_name = c.root_name
+			) AS delegated
+		FROM chain c
+		WHERE c.cycle OR c.depth > ?
+		ORDER BY c.cycle DESC, c.root_namespace, c.root_name, c.depth DESC, c.path
+	`, delegateRecursionLimit, maxDelegateDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reportedCycles := map[string]bool{}
+	reportedRoots := map[string]bool{}
+	for rows.Next() {
+		var rootNs, rootName, path string
+		var depth int
+		var cycle, delegated bool
+		if err := rows.Scan(&rootNs, &rootName, &depth, &path, &cycle, &delegated); err != nil {
+			return nil, err
+		}
+		nodes := strings.Split(strings.Trim(path, ","), ",")
+
+		if cycle {
+			// Один и тот же цикл находится из каждой его вершины и из вершин перед ним
+			loop := cycleNodes(nodes)
+			key := cycleKey(loop)
+			if reportedCycles[key] {
+				continue
+			}
+			reportedCycles[key] = true
+			violations = append(violations, meshv1alpha1.ConstraintViolation{
+				Type:     "DelegateViolation",
+				Resource: "VirtualService/" + loop[0],
+				Message:  fmt.Sprintf("Delegate cycle: %s", strings.Join(loop, " -> ")),
+				Severity: "Error",
+			})
+			continue
+		}
+
+		// Слишком глубокие цепочки сообщаются от настоящего корня, самым длинным путем
+		root := rootNs + "/" + rootName
+		if delegated || reportedRoots[root] {
+			continue
+		}
+		reportedRoots[root] = true
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "DelegateViolation",
+			Resource: "VirtualService/" + root,
+			Message: fmt.Sprintf("Delegate chain depth %d exceeds supported depth %d: %s",
+				depth, maxDelegateDepth, strings.Join(nodes, " -> ")),
+			Severity: "Error",
+		})
+	}
+
+	return violations, rows.Err()
+}
+
+// cycleNodes returns the closed loop at the end of a path whose last node repeats an earlier one
+func cycleNodes(nodes []string) []string {
+	last := nodes[len(nodes)-1]
+	for i, node := range nodes {
+		if node == last {
+			return nodes[i:]
+		}
+	}
+	return nodes
+}
+
+// cycleKey identifies a loop regardless of the node it was entered from
+func cycleKey(loop []string) string {
+	members := append([]string(nil), loop[:len(loop)-1]...)
+	sort.Strings(members)
+	return strings.Join(members, ",")
+}
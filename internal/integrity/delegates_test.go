@@ -0,0 +1,87 @@
+// Тесты для цепочек delegate VirtualService
+package integrity
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckDelegateViolations(t *testing.T) {
+	model, err := parseYAMLResources("testdata/delegate-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse YAML file: %v", err)
+	}
+
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	var delegates int
+	db.QueryRow("SELECT COUNT(*) FROM virtual_service_delegates").Scan(&delegates)
+	if delegates != 7 {
+		t.Errorf("Expected 7 delegate edges, got %d", delegates)
+	}
+
+	violations, err := operator.checkDelegateViolations(db)
+	if err != nil {
+		t.Fatalf("Failed to check delegate violations: %v", err)
+	}
+
+	expected := []struct {
+		violationType string
+		resource      string
+		fragment      string
+	}{
+		{"ForeignKeyViolation", "VirtualService/shop/root", "http[1].delegate references non-existent VirtualService/checkout/cart-routes"},
+		{"DelegateViolation", "VirtualService/shop/root", "http[2].delegate VirtualService/shop/legacy-routes must not define hosts"},
+		{"DelegateViolation", "VirtualService/shop/root", "depth 2 exceeds supported depth 1: shop/root -> shop/nested-routes -> shop/deep-routes"},
+		{"DelegateViolation", "VirtualService/team/ping", "Delegate cycle: team/ping -> team/pong -> team/ping"},
+	}
+
+	for _, violation := range violations {
+		t.Logf("⚠️ Violation: %s %s - %s", violation.Type, violation.Resource, violation.Message)
+	}
+	for _, want := range expected {
+		found := false
+		for _, violation := range violations {
+			if violation.Type == want.violationType && violation.Resource == want.resource && strings.Contains(violation.Message, want.fragment) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Expected %s for %s containing %q", want.violationType, want.resource, want.fragment)
+		}
+	}
+	if len(violations) != len(expected) {
+		t.Errorf("Expected %d delegate violations, got %d", len(expected), len(violations))
+	}
+}
+
+func TestCheckForeignKeyViolations_DelegateRoot(t *testing.T) {
+	model, err := parseYAMLResources("testdata/delegate-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse YAML file: %v", err)
+	}
+
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	// Корневой VirtualService без route и delegate без gateways не ссылаются на пустые Gateway/Service
+	violations, err := operator.checkForeignKeyViolations(db)
+	if err != nil {
+		t.Fatalf("Failed to check foreign key violations: %v", err)
+	}
+	for _, violation := range violations {
+		if strings.Contains(violation.Message, "//") || strings.HasSuffix(violation.Message, "/") {
+			t.Errorf("Unexpected violation for an empty reference: %s %s", violation.Resource, violation.Message)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package integrity
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+	istio "istio.io/client-go/pkg/apis/networking/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// gatewayAPIGatewayKind distinguishes a Gateway API Gateway from an Istio Gateway
+const gatewayAPIGatewayKind = "Gateway." + gatewayAPIGroup
+
+// Dependent is a resource that references the object and breaks when the object is deleted
+type Dependent struct {
+	Kind       string   `json:"kind"`
+	Namespace  string   `json:"namespace"`
+	Name       string   `json:"name"`
+	References []string `json:"references"` // поля, через которые идет ссылка
+}
+
+// Resource returns the dependent in Kind/namespace/name form
+func (d Dependent) Resource() string {
+	return fmt.Sprintf("%s/%s/%s", d.Kind, d.Namespace, d.Name)
+}
+
+// kindTables maps the kinds of top-level objects to the tables that store them
+var kindTables = map[string]string{
+	"Service":         "services",
+	"Gateway":         "gateways",
+	"VirtualService":  "virtual_services",
+	"DestinationRule": "destination_rules",
+	"MeshService":     "mesh_services",
+}
+
+// DependencyKind returns the kind FindDependents expects for the object, or "" when
+// nothing in the model can reference it
+func DependencyKind(obj client.Object) string {
+	switch obj.(type) {
+	case *corev1.Service:
+		return "Service"
+	case *istio.Gateway:
+		return "Gateway"
+	case *istio.VirtualService:
+		return "VirtualService"
+	case *istio.DestinationRule:
+		return "DestinationRule"
+	case *meshv1alpha1.MeshService:
+		return "MeshService"
+	case *gatewayv1.Gateway:
+		return gatewayAPIGatewayKind
+	}
+	return ""
+}
+
+// FindDependents отвечает на вопрос "что сломается": возвращает ресурсы, которые ссылаются
+// на объект kind namespace/name. Ссылки берутся из FOREIGN KEY ... ON DELETE CASCADE схемы
+// (строки, которые каскадно удалились бы вместе с объектом) и из логических ссылок по host
+// и спискам, которые схема не может выразить внешним ключом.
+func (o *SQLiteIntegrityOperator) FindDependents(db *sql.DB, kind, namespace, name string) ([]Dependent, error) {
+	dependents := map[string]*Dependent{}
+	add := func(kind, ns, name, reference string) {
+		key := kind + "/" + ns + "/" + name
+		dependent, ok := dependents[key]
+		if !ok {
+			dependent = &Dependent{Kind: kind, Namespace: ns, Name: name}
+			dependents[key] = dependent
+		}
+		if !containsString(dependent.References, reference) {
+			dependent.References = append(dependent.References, reference)
+		}
+	}
+
+	// 1. Объявленные внешние ключи верхнеуровневых таблиц на таблицу объекта
+	if table, ok := kindTables[kind]; ok {
+		if err := cascadeDependents(db, table, namespace, name, add); err != nil {
+			return nil, err
+		}
+	}
+
+	// 2. Логические ссылки
+	query, args := logicalDependentsSQL(kind, namespace, name)
+	if query != "" {
+		rows, err := db.Query(query, args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var depKind, depNs, depName, reference string
+			if err := rows.Scan(&depKind, &depNs, &depName, &reference); err != nil {
+				return nil, err
+			}
+			// Ссылка объекта на самого себя не мешает удалению
+			if depKind == kind && depNs == namespace && depName == name {
+				continue
+			}
+			add(depKind, depNs, depName, reference)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]Dependent, 0, len(dependents))
+	for _, dependent := range dependents {
+		sort.Strings(dependent.References)
+		result = append(result, *dependent)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Resource() < result[j].Resource() })
+	return result, nil
+}
+
+// cascadeDependents reads FOREIGN KEY declarations of the other top-level tables that point
+// at parent and reports the rows referencing namespace/name
+func cascadeDependents(db *sql.DB, parent, namespace, name string, add func(kind, ns, name, reference string)) error {
+	for kind, table := range kindTables {
+		if table == parent {
+			continue
+		}
+		rows, err := db.Query(`SELECT id, "from", "to" FROM pragma_foreign_key_list(?) WHERE "table" = ? ORDER BY id, seq`, table, parent)
+		if err != nil {
+			return err
+		}
+		keys := map[int][][2]string{}
+		var ids []int
+		for rows.Next() {
+			var id int
+			var from, to string
+			if err := rows.Scan(&id, &from, &to); err != nil {
+				rows.Close()
+				return err
+			}
+			if _, ok := keys[id]; !ok {
+				ids = append(ids, id)
+			}
+			keys[id] = append(keys[id], [2]string{from, to})
+		}
+		rows.Close()
+
+		for _, id := range ids {
+			var conditions, columns []string
+			var args []any
+			for _, pair := range keys[id] {
+				value := name
+				if pair[1] == "namespace" {
+					value = namespace
+				}
+				conditions = append(conditions, pair[0]+" = ?")
+				columns = append(columns, pair[0])
+				args = append(args, value)
+			}
+			dependents, err := db.Query(fmt.Sprintf("SELECT namespace, name FROM %s WHERE %s", table, strings.Join(conditions, " AND ")), args...)
+			if err != nil {
+				return err
+			}
+			for dependents.Next() {
+				var ns, depName string
+				if err := dependents.Scan(&ns, &depName); err != nil {
+					dependents.Close()
+					return err
+				}
+				add(kind, ns, depName, fmt.Sprintf("foreign key (%s)", strings.Join(columns, ", ")))
+			}
+			dependents.Close()
+		}
+	}
+	return nil
+}
+
+// logicalDependentsSQL returns a query of (kind, namespace, name, reference) rows
+// referencing the object through hosts and reference lists
+func logicalDependentsSQL(kind, namespace, name string) (string, []any) {
+	switch kind {
+	case "Service":
+		host := fmt.Sprintf("%s.%s.%s", name, namespace, clusterDomainSuffix)
+		return `
+			SELECT 'VirtualService', namespace, name, 'http[' || http_index || '].route[' || route_index || '].destination.host'
+			FROM virtual_service_destinations WHERE canonical_host = ?
+			UNION
+			SELECT 'DestinationRule', namespace, name, 'spec.host'
+			FROM destination_rules WHERE canonical_host = ?
+			UNION
+			SELECT 'MeshService', namespace, name, 'spec.serviceName'
+			FROM mesh_services WHERE service_namespace = ? AND service_name = ?
+			UNION
+			SELECT kind, namespace, name, 'rules[' || rule_index || '].backendRefs[' || ref_index || ']'
+			FROM gateway_api_backend_refs
+			WHERE backend_group = '' AND backend_kind = 'Service' AND backend_namespace = ? AND backend_name = ?
+		`, []any{host, host, namespace, name, namespace, name}
+	case "Gateway":
+		return `
+			SELECT 'VirtualService', namespace, name, 'spec.gateways'
+			FROM virtual_service_gateways WHERE gateway_namespace = ? AND gateway_name = ?
+			UNION
+			SELECT 'MeshService', namespace, name, 'spec.gateway'
+			FROM mesh_services WHERE gateway_namespace = ? AND gateway_name = ?
+		`, []any{namespace, name, namespace, name}
+	case "VirtualService":
+		return `
+			SELECT 'VirtualService', namespace, name, 'http[' || http_index || '].delegate'
+			FROM virtual_service_delegates WHERE delegate_namespace = ? AND delegate_name = ?
+		`, []any{namespace, name}
+	case gatewayAPIGatewayKind:
+		return `
+			SELECT kind, namespace, name, 'parentRefs[' || ref_index || ']'
+			FROM gateway_api_parent_refs
+			WHERE parent_group = ? AND parent_kind = 'Gateway' AND parent_namespace = ? AND parent_name = ?
+		`, []any{gatewayAPIGroup, namespace, name}
+	}
+	return "", nil
+}
@@ -0,0 +1,67 @@
+// Тесты для поиска зависимых ресурсов перед удалением
+package integrity
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindDependents(t *testing.T) {
+	model, err := parseYAMLResources("testdata/dependent-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse YAML file: %v", err)
+	}
+
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		kind      string
+		namespace string
+		name      string
+		expected  []string // Kind/namespace/name: фрагмент ссылки
+	}{
+		{"Service", "shop", "reviews", []string{
+			"DestinationRule/shop/reviews: spec.host",
+			"HTTPRoute/shop/reviews: rules[0].backendRefs[0]",
+			"MeshService/shop/reviews: spec.serviceName",
+			"VirtualService/shop/reviews: http[0].route[0].destination.host",
+			"VirtualService/shop/reviews-routes: http[0].route[0].destination.host",
+		}},
+		{"Gateway", "istio-system", "public", []string{
+			"MeshService/shop/reviews: spec.gateway",
+			"VirtualService/shop/reviews: spec.gateways",
+		}},
+		{"VirtualService", "shop", "reviews-routes", []string{
+			"VirtualService/shop/root: http[0].delegate",
+		}},
+		{"Gateway.gateway.networking.k8s.io", "shop", "edge", []string{
+			"HTTPRoute/shop/reviews: parentRefs[0]",
+		}},
+		{"DestinationRule", "shop", "reviews", nil},
+	}
+
+	for _, tt := range tests {
+		dependents, err := operator.FindDependents(db, tt.kind, tt.namespace, tt.name)
+		if err != nil {
+			t.Fatalf("Failed to find dependents of %s/%s/%s: %v", tt.kind, tt.namespace, tt.name, err)
+		}
+		for _, dependent := range dependents {
+			t.Logf("🔗 %s/%s/%s <- %s %v", tt.kind, tt.namespace, tt.name, dependent.Resource(), dependent.References)
+		}
+		if len(dependents) != len(tt.expected) {
+			t.Errorf("Expected %d dependents of %s/%s/%s, got %d", len(tt.expected), tt.kind, tt.namespace, tt.name, len(dependents))
+			continue
+		}
+		for i, want := range tt.expected {
+			resource, reference, _ := strings.Cut(want, ": ")
+			if dependents[i].Resource() != resource || !containsString(dependents[i].References, reference) {
+				t.Errorf("Expected dependent %s via %s, got %s %v", resource, reference, dependents[i].Resource(), dependents[i].References)
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package integrity
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+)
+
+// clusterDomainSuffix is the DNS suffix Istio appends to short Kubernetes service names
+const clusterDomainSuffix = "svc.cluster.local"
+
+// canonicalHost expands a host as Istio does for the namespace the config lives in:
+// "reviews" -> "reviews.<ns>.svc.cluster.local", "reviews.prod" -> "reviews.prod.svc.cluster.local".
+// Wildcards and external FQDNs are returned lowercased but otherwise unchanged.
+func canonicalHost(host, namespace string) string {
+	host = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+	if host == "" || strings.Contains(host, "*") {
+		return host
+	}
+
+	parts := strings.Split(host, ".")
+	switch {
+	case len(parts) == 1:
+		return fmt.Sprintf("%s.%s.%s", host, namespace, clusterDomainSuffix)
+	case len(parts) == 2:
+		return fmt.Sprintf("%s.%s", host, clusterDomainSuffix)
+	case len(parts) == 3 && parts[2] == "svc":
+		return host + ".cluster.local"
+	}
+	return host
+}
+
+// destinationRuleConflict groups DestinationRules that Istio merges for the same host
+type destinationRuleConflict struct {
+	host      string
+	namespace string
+	selector  string
+	rules     map[string]bool
+}
+
+// checkDestinationRuleHostConflicts находит несколько DestinationRule для одного host.
+//
+// Istio ищет DestinationRule сначала в namespace клиента, затем в namespace сервиса и
+// в root namespace, поэтому конфликтуют только правила из одного namespace с
+// пересекающимися exportTo. Правила с разными workloadSelector применяются к разным
+// workload и не конфликтуют.
+func (o *SQLiteIntegrityOperator) checkDestinationRuleHostConflicts(db *sql.DB) ([]meshv1alpha1.ConstraintViolation, error) {
+	var violations []meshv1alpha1.ConstraintViolation
+
+	rows, err := db.Query(`
+		SELECT d1.canonical_host, d1.namespace, d1.workload_selector, d1.name, d2.name
+		FROM destination_rules d1
+		JOIN destination_rules d2
+		  ON d1.canonical_host = d2.canonical_host
+		 AND d1.namespace = d2.namespace
+		 AND d1.workload_selector = d2.workload_selector
+		 AND d1.name < d2.name
+		WHERE d1.canonical_host <> ''
+		  AND EXISTS (
+			SELECT 1 FROM exports e1
+			JOIN exports e2
+			  ON e1.export_namespace = e2.export_namespace
+			  OR e1.export_namespace = '*'
+			  OR e2.export_namespace = '*'
+			WHERE e1.kind = 'DestinationRule' AND e1.namespace = d1.namespace AND e1.name = d1.name
+			  AND e2.kind = 'DestinationRule' AND e2.namespace = d2.namespace AND e2.name = d2.name
+		  )
+		ORDER BY d1.canonical_host, d1.namespace, d1.workload_selector
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []*destinationRuleConflict
+	byKey := map[string]*destinationRuleConflict{}
+	for rows.Next() {
+		var host, ns, selector, first, second string
+		if err := rows.Scan(&host, &ns, &selector, &first, &second); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		key := host + "|" + ns + "|" + selector
+		conflict, ok := byKey[key]
+		if !ok {
+			conflict = &destinationRuleConflict{host: host, namespace: ns, selector: selector, rules: map[string]bool{}}
+			byKey[key] = conflict
+			conflicts = append(conflicts, conflict)
+		}
+		conflict.rules[first] = true
+		conflict.rules[second] = true
+	}
+	rows.Close()
+
+	for _, conflict := range conflicts {
+		winner, err := o.pickDestinationRule(db, conflict)
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, 0, len(conflict.rules))
+		for name := range conflict.rules {
+			names = append(names, fmt.Sprintf("%s/%s", conflict.namespace, name))
+		}
+		sort.Strings(names)
+
+		resource := fmt.Sprintf("DestinationRule/* (host: %s, namespace: %s)", conflict.host, conflict.namespace)
+		if conflict.selector != "" {
+			resource = fmt.Sprintf("DestinationRule/* (host: %s, namespace: %s, workloadSelector: %s)", conflict.host, conflict.namespace, conflict.selector)
+		}
+
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "UniqueConstraintViolation",
+			Resource: resource,
+			Message: fmt.Sprintf("Multiple DestinationRules for host %s: %s; Istio merges them and applies %s/%s on conflicting fields",
+				conflict.host, strings.Join(names, ", "), conflict.namespace, winner),
+			Severity: "Error",
+		})
+	}
+
+	return violations, nil
+}
+
+// pickDestinationRule returns the rule Istio prefers: the oldest one, then by name
+func (o *SQLiteIntegrityOperator) pickDestinationRule(db *sql.DB, conflict *destinationRuleConflict) (string, error) {
+	rows, err := db.Query(`
+		SELECT name FROM destination_rules
+		WHERE canonical_host = ? AND namespace = ? AND workload_selector = ?
+		ORDER BY created_at = '', created_at, name
+	`, conflict.host, conflict.namespace, conflict.selector)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return "", err
+		}
+		if conflict.rules[name] {
+			return name, nil
+		}
+	}
+	return "", rows.Err()
+}
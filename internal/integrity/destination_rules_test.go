@@ -0,0 +1,85 @@
+// Тесты для дубликатов DestinationRule
+package integrity
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCanonicalHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "reviews", want: "reviews.prod.svc.cluster.local"},
+		{host: "reviews.prod", want: "reviews.prod.svc.cluster.local"},
+		{host: "reviews.prod.svc", want: "reviews.prod.svc.cluster.local"},
+		{host: "Reviews.Prod.svc.cluster.local.", want: "reviews.prod.svc.cluster.local"},
+		{host: "*.example.com", want: "*.example.com"},
+		{host: "api.example.com", want: "api.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := canonicalHost(tt.host, "prod"); got != tt.want {
+				t.Errorf("canonicalHost(%q) = %q, want %q", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckDestinationRuleHostConflicts(t *testing.T) {
+	operator := &SQLiteIntegrityOperator{}
+
+	model := &RelationalModel{
+		Services: []ServiceRecord{
+			{Namespace: "prod", Name: "reviews", Host: "reviews.prod.svc.cluster.local", Port: 9080, Protocol: "TCP"},
+			{Namespace: "prod", Name: "ratings", Host: "ratings.prod.svc.cluster.local", Port: 9080, Protocol: "TCP"},
+		},
+		DestinationRules: []DestinationRuleRecord{
+			// ❌ две команды описали один и тот же host разными формами записи
+			{Namespace: "prod", Name: "team-b-reviews", Host: "reviews", ServiceNamespace: "prod", ServiceName: "reviews",
+				CreatedAt: "2025-03-01T00:00:00Z"},
+			{Namespace: "prod", Name: "team-a-reviews", Host: "reviews.prod.svc.cluster.local", ServiceNamespace: "prod", ServiceName: "reviews",
+				CreatedAt: "2025-01-01T00:00:00Z"},
+			// ✅ workloadSelector - исключение, правило применяется только к своим workload
+			{Namespace: "prod", Name: "reviews-canary", Host: "reviews.prod.svc.cluster.local", ServiceNamespace: "prod", ServiceName: "reviews",
+				WorkloadSelector: "app=frontend,version=canary"},
+			// ✅ не пересекающиеся exportTo
+			{Namespace: "prod", Name: "ratings-local", Host: "ratings", ServiceNamespace: "prod", ServiceName: "ratings", ExportTo: "."},
+			{Namespace: "prod", Name: "ratings-shop", Host: "ratings", ServiceNamespace: "prod", ServiceName: "ratings", ExportTo: "shop"},
+			// ✅ правило в namespace клиента имеет приоритет и не конфликтует
+			{Namespace: "shop", Name: "reviews", Host: "reviews.prod.svc.cluster.local", ServiceNamespace: "prod", ServiceName: "reviews"},
+		},
+	}
+
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	violations, err := operator.checkDestinationRuleHostConflicts(db)
+	if err != nil {
+		t.Fatalf("Failed to check DestinationRule conflicts: %v", err)
+	}
+
+	if len(violations) != 1 {
+		t.Fatalf("Expected 1 DestinationRule conflict, got %d: %v", len(violations), violations)
+	}
+
+	violation := violations[0]
+	t.Logf("⚠️ Violation: %s %s - %s", violation.Type, violation.Resource, violation.Message)
+	if violation.Type != "UniqueConstraintViolation" {
+		t.Errorf("Expected violation type 'UniqueConstraintViolation', got '%s'", violation.Type)
+	}
+	if violation.Resource != "DestinationRule/* (host: reviews.prod.svc.cluster.local, namespace: prod)" {
+		t.Errorf("Unexpected violation resource: %s", violation.Resource)
+	}
+	if !strings.Contains(violation.Message, "prod/team-a-reviews, prod/team-b-reviews") {
+		t.Errorf("Expected message to list conflicting rules, got %q", violation.Message)
+	}
+	if !strings.Contains(violation.Message, "applies prod/team-a-reviews") {
+		t.Errorf("Expected the oldest rule to be picked, got %q", violation.Message)
+	}
+}
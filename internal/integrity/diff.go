@@ -0,0 +1,193 @@
+package integrity
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ResourceChangeType is the kind of change of a resource between two models
+type ResourceChangeType string
+
+const (
+	ResourceAdded    ResourceChangeType = "added"
+	ResourceRemoved  ResourceChangeType = "removed"
+	ResourceModified ResourceChangeType = "modified"
+)
+
+// ResourceChange is a resource that differs between two models
+type ResourceChange struct {
+	// Resource is Kind/namespace/name like ConstraintViolation.Resource
+	Resource string             `json:"resource"`
+	Change   ResourceChangeType `json:"change"`
+}
+
+// ModelDiff compares the violations and the resources of two models
+type ModelDiff struct {
+	// Report holds only the introduced violations and the repair plans for them
+	Report *IntegrityReport `json:"report"`
+	// Resolved are violations of the old model the new model no longer has
+	Resolved []meshv1alpha1.ConstraintViolation `json:"resolved"`
+	// Unchanged are violations of both models
+	Unchanged []meshv1alpha1.ConstraintViolation `json:"unchanged"`
+	// Resources are the added, removed and modified resources in resource order
+	Resources []ResourceChange `json:"resources"`
+}
+
+// DiffModels проверяет обе базы и сравнивает нарушения так же, как AnalyzeOverlay.
+// Ресурс считается измененным, если отличаются строки, которые loadData записал для него
+// в любую из таблиц: изменения, не попадающие в модель, на целостность не влияют.
+func (o *SQLiteIntegrityOperator) DiffModels(ctx context.Context, oldDB, newDB *sql.DB) (*ModelDiff, error) {
+	before, err := o.CheckIntegrity(oldDB)
+	if err != nil {
+		return nil, err
+	}
+	after, err := o.CheckIntegrity(newDB)
+	if err != nil {
+		return nil, err
+	}
+
+	introduced := DiffViolations(before.Violations, after.Violations)
+	report := &IntegrityReport{IsConsistent: len(introduced) == 0, Violations: introduced}
+	if report.RepairPlans, err = o.ComputeRepairPlans(newDB, report); err != nil {
+		return nil, err
+	}
+	diff := &ModelDiff{
+		Report:    report,
+		Resolved:  DiffViolations(after.Violations, before.Violations),
+		Unchanged: DiffViolations(introduced, after.Violations),
+	}
+
+	oldResources, err := modelResources(ctx, oldDB)
+	if err != nil {
+		return nil, err
+	}
+	newResources, err := modelResources(ctx, newDB)
+	if err != nil {
+		return nil, err
+	}
+	for resource, digest := range newResources {
+		if oldDigest, ok := oldResources[resource]; !ok {
+			diff.Resources = append(diff.Resources, ResourceChange{Resource: resource, Change: ResourceAdded})
+		} else if oldDigest != digest {
+			diff.Resources = append(diff.Resources, ResourceChange{Resource: resource, Change: ResourceModified})
+		}
+	}
+	for resource := range oldResources {
+		if _, ok := newResources[resource]; !ok {
+			diff.Resources = append(diff.Resources, ResourceChange{Resource: resource, Change: ResourceRemoved})
+		}
+	}
+	sort.Slice(diff.Resources, func(i, j int) bool {
+		return diff.Resources[i].Resource < diff.Resources[j].Resource
+	})
+	return diff, nil
+}
+
+// modelResources returns the digest of the records of every resource of the database
+// keyed by Kind/namespace/name
+func modelResources(ctx context.Context, db *sql.DB) (map[string]string, error) {
+	resources := map[string]string{}
+	for _, source := range modelLists() {
+		tables := objectRecordTables(listItem(source.list))
+		if len(tables) == 0 {
+			continue
+		}
+
+		names, err := recordNames(ctx, db, tables[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", source.kind, err)
+		}
+		for _, name := range names {
+			digest, err := recordsDigest(ctx, db, tables, name[0], name[1])
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s/%s/%s: %w", source.kind, name[0], name[1], err)
+			}
+			resources[fmt.Sprintf("%s/%s/%s", source.kind, name[0], name[1])] = digest
+		}
+	}
+	return resources, nil
+}
+
+// listItem returns an empty object of the item type of the list, Istio lists hold pointers
+func listItem(list client.ObjectList) client.Object {
+	items := reflect.ValueOf(list).Elem().FieldByName("Items").Type().Elem()
+	if items.Kind() == reflect.Pointer {
+		items = items.Elem()
+	}
+	return reflect.New(items).Interface().(client.Object)
+}
+
+// recordNames lists namespace and name of the objects stored in the main table of the kind
+func recordNames(ctx context.Context, db *sql.DB, table recordTable) ([][2]string, error) {
+	query := fmt.Sprintf("SELECT DISTINCT namespace, name FROM %s", table.table)
+	var args []any
+	if table.table == "namespaces" {
+		query = "SELECT '', name FROM namespaces"
+	} else if table.kind != "" {
+		query += " WHERE kind = ?"
+		args = append(args, table.kind)
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names [][2]string
+	for rows.Next() {
+		var name [2]string
+		if err := rows.Scan(&name[0], &name[1]); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// recordsDigest hashes the records of the object in all of its tables. Строки сортируются:
+// порядок вставки зависит от порядка объектов во входных данных, а не от их содержимого.
+func recordsDigest(ctx context.Context, db *sql.DB, tables []recordTable, namespace, name string) (string, error) {
+	hash := sha256.New()
+	for _, table := range tables {
+		where, args := table.where(namespace, name)
+		rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s WHERE %s", table.table, where), args...)
+		if err != nil {
+			return "", err
+		}
+		columns, err := rows.Columns()
+		if err != nil {
+			rows.Close()
+			return "", err
+		}
+		var lines []string
+		for rows.Next() {
+			values := make([]any, len(columns))
+			pointers := make([]any, len(columns))
+			for i := range values {
+				pointers[i] = &values[i]
+			}
+			if err := rows.Scan(pointers...); err != nil {
+				rows.Close()
+				return "", err
+			}
+			lines = append(lines, fmt.Sprintf("%#v", values))
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return "", err
+		}
+		sort.Strings(lines)
+		fmt.Fprintf(hash, "%s\n%s\n", table.table, strings.Join(lines, "\n"))
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
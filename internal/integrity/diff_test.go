@@ -0,0 +1,96 @@
+// Тесты сравнения двух моделей
+package integrity
+
+import (
+	"context"
+	"testing"
+
+	networking "istio.io/api/networking/v1beta1"
+	istio "istio.io/client-go/pkg/apis/networking/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDiffModels(t *testing.T) {
+	operator := &SQLiteIntegrityOperator{}
+	oldModel, err := parseYAMLResources("testdata/invalid-mesh-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse YAML file: %v", err)
+	}
+	// Новая модель: broken-vs удален, web изменен, добавлен new-vs со ссылкой на несуществующий Gateway
+	newModel, err := parseYAMLResources("testdata/invalid-mesh-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse YAML file: %v", err)
+	}
+	newModel.RemoveObject(&istio.VirtualService{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "broken-vs"}})
+	web := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 9090}}},
+	}
+	newModel.RemoveObject(web)
+	if err := newModel.AddManifestObject(web); err != nil {
+		t.Fatal(err)
+	}
+	if err := newModel.AddManifestObject(&istio.VirtualService{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "new-vs"},
+		Spec: networking.VirtualService{
+			Hosts:    []string{"new.example.com"},
+			Gateways: []string{"istio-system/missing-gateway"},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	oldDB, err := operator.CreateInMemoryDB(oldModel)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer oldDB.Close()
+	newDB, err := operator.CreateInMemoryDB(newModel)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer newDB.Close()
+
+	diff, err := operator.DiffModels(context.Background(), oldDB, newDB)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	for _, violation := range diff.Report.Violations {
+		if violation.Resource != "VirtualService/default/new-vs" {
+			t.Errorf("Unexpected introduced violation %s: %s", violation.Resource, violation.Message)
+		}
+	}
+	for _, violation := range diff.Resolved {
+		if violation.Resource != "VirtualService/default/broken-vs" {
+			t.Errorf("Unexpected resolved violation %s: %s", violation.Resource, violation.Message)
+		}
+	}
+	if len(diff.Report.Violations) == 0 || len(diff.Resolved) == 0 || len(diff.Unchanged) == 0 || diff.Report.IsConsistent {
+		t.Errorf("Expected introduced, resolved and unchanged violations, got %d, %d, %d",
+			len(diff.Report.Violations), len(diff.Resolved), len(diff.Unchanged))
+	}
+
+	expected := []ResourceChange{
+		{Resource: "Service/default/web", Change: ResourceModified},
+		{Resource: "VirtualService/default/broken-vs", Change: ResourceRemoved},
+		{Resource: "VirtualService/default/new-vs", Change: ResourceAdded},
+	}
+	if len(diff.Resources) != len(expected) {
+		t.Fatalf("Expected resource changes %v, got %v", expected, diff.Resources)
+	}
+	for i := range expected {
+		if diff.Resources[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], diff.Resources[i])
+		}
+	}
+
+	// Модель без изменений
+	same, err := operator.DiffModels(context.Background(), oldDB, oldDB)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(same.Report.Violations) != 0 || len(same.Resolved) != 0 || len(same.Resources) != 0 {
+		t.Errorf("Expected no changes, got %+v", same)
+	}
+}
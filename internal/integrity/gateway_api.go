@@ -0,0 +1,205 @@
+package integrity
+
+import (
+	"database/sql"
+	"fmt"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+)
+
+// listenerAllowsRouteSQL is the predicate for a listener l accepting route p by namespace and kind.
+// allowedRoutes.namespaces.from: Selector needs namespace labels the model does not have and is accepted.
+const listenerAllowsRouteSQL = `
+	(l.allowed_namespaces IN ('All', 'Selector') OR l.namespace = p.namespace)
+	AND (l.allowed_kinds = '' OR instr(',' || l.allowed_kinds || ',', ',' || p.kind || ',') > 0)
+`
+
+// listenerMatchesRefSQL is the predicate for a listener l of gateway g selected by parentRef p
+const listenerMatchesRefSQL = `
+	l.namespace = g.namespace AND l.name = g.name
+	AND (p.section_name = '' OR l.listener_name = p.section_name)
+	AND (p.port = 0 OR l.port = p.port)
+`
+
+// checkGatewayAPIViolations проверяет ссылки Gateway API маршрутов (HTTPRoute, GRPCRoute)
+// так же, как для Istio VirtualService: parentRef -> Gateway listener, backendRef -> Service/port,
+// cross-namespace backendRef и certificateRef -> ReferenceGrant
+func (o *SQLiteIntegrityOperator) checkGatewayAPIViolations(db *sql.DB) ([]meshv1alpha1.ConstraintViolation, error) {
+	var violations []meshv1alpha1.ConstraintViolation
+
+	// 1. parentRef -> Gateway
+	rows, err := db.Query(`
+		SELECT p.kind, p.namespace, p.name, p.ref_index, p.parent_namespace, p.parent_name
+		FROM gateway_api_parent_refs p
+		LEFT JOIN gateway_api_gateways g ON g.namespace = p.parent_namespace AND g.name = p.parent_name
+		WHERE p.parent_group = ? AND p.parent_kind = 'Gateway' AND g.namespace IS NULL
+		ORDER BY p.kind, p.namespace, p.name, p.ref_index
+	`, gatewayAPIGroup)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var kind, ns, name, gwNs, gwName string
+		var refIndex int
+		if err := rows.Scan(&kind, &ns, &name, &refIndex, &gwNs, &gwName); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "ForeignKeyViolation",
+			Resource: fmt.Sprintf("%s/%s/%s", kind, ns, name),
+			Message:  fmt.Sprintf("parentRefs[%d] references non-existent %s/%s/%s", refIndex, gatewayAPIGatewayKind, gwNs, gwName),
+			Severity: "Error",
+		})
+	}
+	rows.Close()
+
+	// 2. parentRef sectionName/port -> listener
+	rows, err = db.Query(`
+		SELECT p.kind, p.namespace, p.name, p.ref_index, g.namespace, g.name, p.section_name, p.port
+		FROM gateway_api_parent_refs p
+		JOIN gateway_api_gateways g ON g.namespace = p.parent_namespace AND g.name = p.parent_name
+		WHERE p.parent_group = ? AND p.parent_kind = 'Gateway'
+		  AND (p.section_name <> '' OR p.port <> 0)
+		  AND NOT EXISTS (SELECT 1 FROM gateway_api_listeners l WHERE `+listenerMatchesRefSQL+`)
+		ORDER BY p.kind, p.namespace, p.name, p.ref_index
+	`, gatewayAPIGroup)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var kind, ns, name, gwNs, gwName, sectionName string
+		var refIndex int
+		var port int32
+		if err := rows.Scan(&kind, &ns, &name, &refIndex, &gwNs, &gwName, &sectionName, &port); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "ForeignKeyViolation",
+			Resource: fmt.Sprintf("%s/%s/%s", kind, ns, name),
+			Message: fmt.Sprintf("parentRefs[%d] references non-existent listener%s of %s/%s/%s",
+				refIndex, listenerScope(sectionName, port), gatewayAPIGatewayKind, gwNs, gwName),
+			Severity: "Error",
+		})
+	}
+	rows.Close()
+
+	// 3. Listener существует, но allowedRoutes не принимает маршрут из этого namespace или этого kind
+	rows, err = db.Query(`
+		SELECT p.kind, p.namespace, p.name, p.ref_index, g.namespace, g.name, p.section_name, p.port
+		FROM gateway_api_parent_refs p
+		JOIN gateway_api_gateways g ON g.namespace = p.parent_namespace AND g.name = p.parent_name
+		WHERE p.parent_group = ? AND p.parent_kind = 'Gateway'
+		  AND EXISTS (SELECT 1 FROM gateway_api_listeners l WHERE `+listenerMatchesRefSQL+`)
+		  AND NOT EXISTS (
+			SELECT 1 FROM gateway_api_listeners l
+			WHERE `+listenerMatchesRefSQL+` AND `+listenerAllowsRouteSQL+`
+		  )
+		ORDER BY p.kind, p.namespace, p.name, p.ref_index
+	`, gatewayAPIGroup)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var kind, ns, name, gwNs, gwName, sectionName string
+		var refIndex int
+		var port int32
+		if err := rows.Scan(&kind, &ns, &name, &refIndex, &gwNs, &gwName, &sectionName, &port); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "VisibilityViolation",
+			Resource: fmt.Sprintf("%s/%s/%s", kind, ns, name),
+			Message: fmt.Sprintf("parentRefs[%d]: no listener%s of %s/%s/%s allows %s from namespace %s",
+				refIndex, listenerScope(sectionName, port), gatewayAPIGatewayKind, gwNs, gwName, kind, ns),
+			Severity: "Error",
+		})
+	}
+	rows.Close()
+
+	// 4. backendRef -> Service
+	rows, err = db.Query(`
+		SELECT b.kind, b.namespace, b.name, b.rule_index, b.ref_index, b.backend_namespace, b.backend_name
+		FROM gateway_api_backend_refs b
+		LEFT JOIN services s ON s.namespace = b.backend_namespace AND s.name = b.backend_name
+		WHERE b.backend_group = '' AND b.backend_kind = 'Service' AND s.namespace IS NULL
+		ORDER BY b.kind, b.namespace, b.name, b.rule_index, b.ref_index
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var kind, ns, name, svcNs, svcName string
+		var ruleIndex, refIndex int
+		if err := rows.Scan(&kind, &ns, &name, &ruleIndex, &refIndex, &svcNs, &svcName); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "ForeignKeyViolation",
+			Resource: fmt.Sprintf("%s/%s/%s", kind, ns, name),
+			Message:  fmt.Sprintf("rules[%d].backendRefs[%d] references non-existent Service/%s/%s", ruleIndex, refIndex, svcNs, svcName),
+			Severity: "Error",
+		})
+	}
+	rows.Close()
+
+	// 5. backendRef port -> порт Service (если порты сервиса известны)
+	rows, err = db.Query(`
+		SELECT b.kind, b.namespace, b.name, b.rule_index, b.ref_index, b.backend_namespace, b.backend_name, b.port
+		FROM gateway_api_backend_refs b
+		WHERE b.backend_group = '' AND b.backend_kind = 'Service' AND b.port <> 0
+		  AND EXISTS (
+			SELECT 1 FROM service_ports sp
+			WHERE sp.namespace = b.backend_namespace AND sp.name = b.backend_name
+		  )
+		  AND NOT EXISTS (
+			SELECT 1 FROM service_ports sp
+			WHERE sp.namespace = b.backend_namespace AND sp.name = b.backend_name AND sp.port = b.port
+		  )
+		ORDER BY b.kind, b.namespace, b.name, b.rule_index, b.ref_index
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var kind, ns, name, svcNs, svcName string
+		var ruleIndex, refIndex int
+		var port int32
+		if err := rows.Scan(&kind, &ns, &name, &ruleIndex, &refIndex, &svcNs, &svcName, &port); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "ForeignKeyViolation",
+			Resource: fmt.Sprintf("%s/%s/%s", kind, ns, name),
+			Message:  fmt.Sprintf("rules[%d].backendRefs[%d] references port %d not exposed by Service/%s/%s", ruleIndex, refIndex, port, svcNs, svcName),
+			Severity: "Error",
+		})
+	}
+	rows.Close()
+
+	// 6. Cross-namespace backendRefs и certificateRefs разрешаются только ReferenceGrant
+	grantViolations, err := o.checkReferenceGrantViolations(db)
+	if err != nil {
+		return nil, err
+	}
+	violations = append(violations, grantViolations...)
+
+	return violations, nil
+}
+
+// listenerScope describes how a parentRef selects listeners
+func listenerScope(sectionName string, port int32) string {
+	switch {
+	case sectionName != "" && port != 0:
+		return fmt.Sprintf(" %s (port %d)", sectionName, port)
+	case sectionName != "":
+		return " " + sectionName
+	case port != 0:
+		return fmt.Sprintf(" on port %d", port)
+	}
+	return ""
+}
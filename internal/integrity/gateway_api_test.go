@@ -0,0 +1,102 @@
+// Тесты для ссылочной целостности Kubernetes Gateway API
+package integrity
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckGatewayAPIViolations(t *testing.T) {
+	model, err := parseYAMLResources("testdata/gateway-api-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse YAML file: %v", err)
+	}
+
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	var listeners, routes int
+	db.QueryRow("SELECT COUNT(*) FROM gateway_api_listeners").Scan(&listeners)
+	db.QueryRow("SELECT COUNT(*) FROM gateway_api_routes WHERE kind = 'GRPCRoute'").Scan(&routes)
+	if listeners != 4 || routes != 1 {
+		t.Errorf("Expected 4 listeners and 1 GRPCRoute, got %d and %d", listeners, routes)
+	}
+
+	violations, err := operator.checkGatewayAPIViolations(db)
+	if err != nil {
+		t.Fatalf("Failed to check Gateway API violations: %v", err)
+	}
+
+	expected := []struct {
+		violationType string
+		resource      string
+		fragment      string
+	}{
+		{"VisibilityViolation", "HTTPRoute/shop/secure", "no listener https of Gateway.gateway.networking.k8s.io/infra/public allows HTTPRoute from namespace shop"},
+		{"ForeignKeyViolation", "HTTPRoute/shop/missing-listener", "non-existent listener admin of Gateway.gateway.networking.k8s.io/infra/public"},
+		{"ForeignKeyViolation", "HTTPRoute/shop/orphan", "non-existent Gateway.gateway.networking.k8s.io/infra/internal"},
+		{"ForeignKeyViolation", "HTTPRoute/shop/orphan", "non-existent Service/shop/cart"},
+		{"ReferenceGrantViolation", "HTTPRoute/shop/billing", "Service/payments/billing is not permitted"},
+		{"ForeignKeyViolation", "HTTPRoute/shop/billing", "port 8081 not exposed by Service/shop/web"},
+		{"VisibilityViolation", "HTTPRoute/shop/grpc-only", "no listener grpc of Gateway.gateway.networking.k8s.io/infra/public allows HTTPRoute"},
+		{"ReferenceGrantViolation", "Gateway.gateway.networking.k8s.io/infra/public", "listeners[https].tls.certificateRefs[0] to Secret/certs/wildcard-cert"},
+	}
+
+	for _, violation := range violations {
+		t.Logf("⚠️ Violation: %s %s - %s", violation.Type, violation.Resource, violation.Message)
+	}
+	for _, want := range expected {
+		found := false
+		for _, violation := range violations {
+			if violation.Type == want.violationType && violation.Resource == want.resource && strings.Contains(violation.Message, want.fragment) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Expected %s for %s containing %q", want.violationType, want.resource, want.fragment)
+		}
+	}
+	if len(violations) != len(expected) {
+		t.Errorf("Expected %d Gateway API violations, got %d", len(expected), len(violations))
+	}
+}
+
+func TestGatewayAPIAndIstioInOneReport(t *testing.T) {
+	model, err := parseYAMLResources("testdata/gateway-api-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse YAML file: %v", err)
+	}
+
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	report, err := operator.CheckIntegrity(db)
+	if err != nil {
+		t.Fatalf("Failed to check integrity: %v", err)
+	}
+
+	var istioViolation, gatewayAPIViolation bool
+	for _, violation := range report.Violations {
+		switch {
+		case violation.Resource == "VirtualService/shop/legacy" && strings.Contains(violation.Message, "Gateway/istio-system/legacy-gateway"):
+			istioViolation = true
+		case strings.HasPrefix(violation.Resource, "HTTPRoute/"):
+			gatewayAPIViolation = true
+		}
+	}
+	if !istioViolation || !gatewayAPIViolation {
+		t.Errorf("Expected Istio and Gateway API violations in one report, got istio=%v gatewayAPI=%v", istioViolation, gatewayAPIViolation)
+	}
+	if report.IsConsistent {
+		t.Error("Expected report to be inconsistent")
+	}
+}
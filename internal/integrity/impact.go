@@ -0,0 +1,303 @@
+package integrity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+	istio "istio.io/client-go/pkg/apis/networking/v1beta1"
+	security "istio.io/client-go/pkg/apis/security/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+// MutationOperation is the kind of change a hypothetical Mutation makes
+type MutationOperation string
+
+const (
+	// MutationApply creates the object or replaces its current version
+	MutationApply MutationOperation = "apply"
+	// MutationDelete deletes the object
+	MutationDelete MutationOperation = "delete"
+)
+
+// Mutation is a hypothetical change of one object analyzed by AnalyzeImpact
+type Mutation struct {
+	Operation MutationOperation
+	Object    client.Object
+}
+
+// ImpactReport describes what a Mutation breaks and fixes
+type ImpactReport struct {
+	Operation MutationOperation `json:"operation"`
+	Resource  string            `json:"resource"`
+	// Dependents reference the deleted object
+	Dependents []Dependent `json:"dependents,omitempty"`
+	// Introduced are violations the mutation adds, Resolved are violations it removes
+	Introduced []meshv1alpha1.ConstraintViolation `json:"introduced"`
+	Resolved   []meshv1alpha1.ConstraintViolation `json:"resolved"`
+}
+
+// AnalyzeImpact применяет мутацию к копии базы и сравнивает нарушения до и после.
+// Исходная база не изменяется, поэтому одну базу можно использовать для нескольких мутаций.
+func (o *SQLiteIntegrityOperator) AnalyzeImpact(ctx context.Context, db *sql.DB, mutation Mutation) (*ImpactReport, error) {
+	obj := mutation.Object
+	if obj == nil || !IsModelObject(obj) {
+		return nil, fmt.Errorf("unsupported object type %T", obj)
+	}
+	report := &ImpactReport{
+		Operation:  mutation.Operation,
+		Resource:   ObjectResource(obj),
+		Introduced: []meshv1alpha1.ConstraintViolation{},
+		Resolved:   []meshv1alpha1.ConstraintViolation{},
+	}
+
+	before, err := o.CheckIntegrity(db)
+	if err != nil {
+		return nil, err
+	}
+
+	switch mutation.Operation {
+	case MutationApply:
+	case MutationDelete:
+		if kind := DependencyKind(obj); kind != "" {
+			if report.Dependents, err = o.FindDependents(db, kind, obj.GetNamespace(), obj.GetName()); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown mutation operation %q, expected %q or %q", mutation.Operation, MutationApply, MutationDelete)
+	}
+
+	copied, err := o.CopyDB(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer copied.Close()
+
+	if err := o.applyMutation(ctx, copied, mutation); err != nil {
+		return nil, fmt.Errorf("failed to apply %s of %s: %w", mutation.Operation, report.Resource, err)
+	}
+	after, err := o.CheckIntegrity(copied)
+	if err != nil {
+		return nil, err
+	}
+
+	report.Introduced = append(report.Introduced, DiffViolations(before.Violations, after.Violations)...)
+	report.Resolved = append(report.Resolved, DiffViolations(after.Violations, before.Violations)...)
+	return report, nil
+}
+
+// AnalyzeClusterImpact builds the relational model of the cluster and analyzes the mutation against it
+func (o *SQLiteIntegrityOperator) AnalyzeClusterImpact(ctx context.Context, mutation Mutation) (*ImpactReport, error) {
+	model, err := o.BuildRelationalModel(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build relational model: %w", err)
+	}
+	db, err := o.CreateInMemoryDB(model)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return o.AnalyzeImpact(ctx, db, mutation)
+}
+
+// CopyDB copies the database into a new in-memory database with the SQLite backup API
+func (o *SQLiteIntegrityOperator) CopyDB(ctx context.Context, db *sql.DB) (*sql.DB, error) {
+	copied, err := sql.Open("sqlite3", fmt.Sprintf("file:integrity-%d?mode=memory&cache=shared", databaseSeq.Add(1)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err := backupDB(ctx, copied, db); err != nil {
+		copied.Close()
+		return nil, fmt.Errorf("failed to copy database: %w", err)
+	}
+	if _, err := copied.Exec("PRAGMA foreign_keys = ON;"); err != nil {
+		copied.Close()
+		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+	}
+	return copied, nil
+}
+
+func backupDB(ctx context.Context, dst, src *sql.DB) error {
+	dstConn, err := dst.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return dstConn.Raw(func(dstDriver any) error {
+		return srcConn.Raw(func(srcDriver any) error {
+			dstSQLite, ok := dstDriver.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected driver connection %T", dstDriver)
+			}
+			srcSQLite, ok := srcDriver.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected driver connection %T", srcDriver)
+			}
+			backup, err := dstSQLite.Backup("main", srcSQLite, "main")
+			if err != nil {
+				return err
+			}
+			// -1 копирует все страницы за один шаг
+			if _, err := backup.Step(-1); err != nil {
+				backup.Close()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+}
+
+// applyMutation удаляет строки объекта и, для apply, загружает записи его новой версии.
+// Внешние ключи выключены, чтобы ON DELETE CASCADE не удалил зависимые строки:
+// их висячие ссылки и есть результат анализа.
+func (o *SQLiteIntegrityOperator) applyMutation(ctx context.Context, db *sql.DB, mutation Mutation) error {
+	obj := mutation.Object
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return fmt.Errorf("failed to disable foreign keys: %w", err)
+	}
+	for _, table := range objectRecordTables(obj) {
+		query, args := table.deleteSQL(obj.GetNamespace(), obj.GetName())
+		if _, err := conn.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = ON"); err != nil {
+		return fmt.Errorf("failed to enable foreign keys: %w", err)
+	}
+	conn.Close()
+
+	if mutation.Operation != MutationApply {
+		return nil
+	}
+	// Новая версия приходит как манифест: Service без аннотации mesh.operator.istio.io/managed
+	// не должен выглядеть удаленным для ссылающихся на него ресурсов
+	part := &RelationalModel{}
+	if err := part.AddManifestObject(obj); err != nil {
+		return err
+	}
+	return o.loadData(db, part)
+}
+
+// recordTable is a table holding records of one object kind
+type recordTable struct {
+	table   string
+	kind    string // значение столбца kind, если таблица общая для нескольких kind
+	subsets bool   // записи subsets с name вида <name>/<subset>
+}
+
+func (t recordTable) deleteSQL(namespace, name string) (string, []any) {
+	where, args := t.where(namespace, name)
+	return fmt.Sprintf("DELETE FROM %s WHERE %s", t.table, where), args
+}
+
+// where returns the condition selecting the records of the object namespace/name
+func (t recordTable) where(namespace, name string) (string, []any) {
+	if t.table == "namespaces" {
+		return "name = ?", []any{name}
+	}
+	conditions := []string{"namespace = ?"}
+	args := []any{namespace}
+	if t.subsets {
+		conditions = append(conditions, "substr(name, 1, ?) = ?")
+		args = append(args, len(name)+1, name+"/")
+	} else {
+		conditions = append(conditions, "name = ?")
+		args = append(args, name)
+	}
+	if t.kind != "" {
+		conditions = append(conditions, "kind = ?")
+		args = append(args, t.kind)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// objectRecordTables lists the tables loadData fills from the records of the object,
+// the database counterpart of RemoveObject. TestObjectRecordTablesCoverSchema fails for a
+// table of createSchema or a kind of a shared table missing here.
+func objectRecordTables(obj client.Object) []recordTable {
+	tables := func(names ...string) []recordTable {
+		result := make([]recordTable, 0, len(names))
+		for _, name := range names {
+			result = append(result, recordTable{table: name})
+		}
+		return result
+	}
+
+	switch obj.(type) {
+	case *corev1.Service:
+		return append(tables("services", "service_ports"),
+			recordTable{table: "selector_labels", kind: "Service"}, recordTable{table: "exports", kind: "Service"})
+	case *corev1.Pod:
+		return tables("workloads", "workload_labels")
+	case *corev1.Namespace:
+		return tables("namespaces")
+	case *corev1.ServiceAccount:
+		return tables("service_accounts")
+	case *istio.VirtualService:
+		return append(tables("virtual_services", "virtual_service_destinations", "virtual_service_delegates",
+			"virtual_service_hosts", "virtual_service_gateways", "http_routes", "http_matches", "http_match_headers"),
+			recordTable{table: "exports", kind: "VirtualService"})
+	case *istio.DestinationRule:
+		return append(tables("destination_rules", "traffic_policies", "destination_rule_subsets"),
+			recordTable{table: "selector_labels", kind: "DestinationRuleSubset", subsets: true},
+			recordTable{table: "exports", kind: "DestinationRule"})
+	case *istio.Gateway:
+		return tables("gateways")
+	case *istio.ServiceEntry:
+		return append(tables("service_entries"), recordTable{table: "exports", kind: "ServiceEntry"})
+	case *istio.Sidecar:
+		return append(tables("sidecars", "sidecar_egress_hosts"), recordTable{table: "selector_labels", kind: "Sidecar"})
+	case *security.PeerAuthentication:
+		return append(tables("peer_authentications", "peer_authentication_ports"),
+			recordTable{table: "selector_labels", kind: "PeerAuthentication"})
+	case *security.AuthorizationPolicy:
+		return append(tables("authorization_policies", "authorization_policy_rules",
+			"authorization_policy_sources", "authorization_policy_operations"),
+			recordTable{table: "selector_labels", kind: "AuthorizationPolicy"})
+	case *meshv1alpha1.MeshService:
+		return append(tables("mesh_services", "mesh_service_subsets"),
+			recordTable{table: "selector_labels", kind: "MeshServiceSubset", subsets: true})
+	case *gatewayv1.Gateway:
+		return tables("gateway_api_gateways", "gateway_api_listeners", "gateway_api_certificate_refs")
+	case *gatewayv1.HTTPRoute:
+		return []recordTable{{table: "gateway_api_routes", kind: "HTTPRoute"},
+			{table: "gateway_api_parent_refs", kind: "HTTPRoute"}, {table: "gateway_api_backend_refs", kind: "HTTPRoute"}}
+	case *gatewayv1.GRPCRoute:
+		return []recordTable{{table: "gateway_api_routes", kind: "GRPCRoute"},
+			{table: "gateway_api_parent_refs", kind: "GRPCRoute"}, {table: "gateway_api_backend_refs", kind: "GRPCRoute"}}
+	case *gatewayv1beta1.ReferenceGrant:
+		return tables("reference_grants")
+	}
+	return nil
+}
+
+// ObjectResource formats the object as Kind/namespace/name like ConstraintViolation.Resource,
+// a Gateway API Gateway as Gateway.gateway.networking.k8s.io/namespace/name
+func ObjectResource(obj client.Object) string {
+	kind := DependencyKind(obj)
+	if kind == "" {
+		kind = strings.TrimPrefix(fmt.Sprintf("%T", obj), "*")
+		kind = kind[strings.LastIndex(kind, ".")+1:]
+	}
+	return fmt.Sprintf("%s/%s/%s", kind, obj.GetNamespace(), obj.GetName())
+}
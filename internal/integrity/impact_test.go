@@ -0,0 +1,308 @@
+// Тесты для анализа последствий изменений на копии базы
+package integrity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+	networking "istio.io/api/networking/v1beta1"
+	istio "istio.io/client-go/pkg/apis/networking/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func impactObjects() []client.Object {
+	return []client.Object{
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "payments", Name: "api", Annotations: map[string]string{meshManagedAnnotation: "true"}},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": "api"},
+				Ports:    []corev1.ServicePort{{Name: "http", Port: 8080}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "payments", Name: "api-v1", Labels: map[string]string{"app": "api", "version": "v1"}},
+		},
+		&istio.Gateway{ObjectMeta: metav1.ObjectMeta{Namespace: "istio-system", Name: "public"}},
+		&istio.VirtualService{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "payments", Name: "api"},
+			Spec: networking.VirtualService{
+				Hosts:    []string{"api.example.com"},
+				Gateways: []string{"istio-system/public"},
+				Http: []*networking.HTTPRoute{{
+					Route: []*networking.HTTPRouteDestination{{
+						Destination: &networking.Destination{Host: "api.payments.svc.cluster.local", Subset: "v1"},
+					}},
+				}},
+			},
+		},
+		&istio.DestinationRule{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "payments", Name: "api"},
+			Spec: networking.DestinationRule{
+				Host:    "api.payments.svc.cluster.local",
+				Subsets: []*networking.Subset{{Name: "v1", Labels: map[string]string{"version": "v1"}}},
+			},
+		},
+		&meshv1alpha1.MeshService{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "payments", Name: "api"},
+			Spec: meshv1alpha1.MeshServiceSpec{
+				ServiceName: "api",
+				Gateway:     meshv1alpha1.GatewayReference{Name: "public", Namespace: "istio-system"},
+				Subsets:     []meshv1alpha1.Subset{{Name: "v1", Labels: map[string]string{"version": "v1"}}},
+			},
+		},
+	}
+}
+
+func impactModel(t *testing.T, objects []client.Object) *RelationalModel {
+	t.Helper()
+	model := &RelationalModel{}
+	for _, obj := range objects {
+		if err := model.AddObject(obj); err != nil {
+			t.Fatalf("Failed to add %T to the model: %v", obj, err)
+		}
+	}
+	return model
+}
+
+func TestAnalyzeImpact(t *testing.T) {
+	ctx := context.Background()
+	operator := &SQLiteIntegrityOperator{}
+	objects := impactObjects()
+	db, err := operator.CreateInMemoryDB(impactModel(t, objects))
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	// Удаление Service ломает VirtualService, DestinationRule и MeshService
+	report, err := operator.AnalyzeImpact(ctx, db, Mutation{Operation: MutationDelete, Object: objects[0]})
+	if err != nil {
+		t.Fatalf("Failed to analyze impact: %v", err)
+	}
+	for _, violation := range report.Introduced {
+		t.Logf("⚠️ Introduced: %s %s - %s", violation.Type, violation.Resource, violation.Message)
+	}
+	if report.Resource != "Service/payments/api" {
+		t.Errorf("Expected resource Service/payments/api, got %s", report.Resource)
+	}
+	var dependents []string
+	for _, dependent := range report.Dependents {
+		dependents = append(dependents, dependent.Resource())
+	}
+	if strings.Join(dependents, ",") != "DestinationRule/payments/api,MeshService/payments/api,VirtualService/payments/api" {
+		t.Errorf("Unexpected dependents %v", dependents)
+	}
+	if !hasViolation(report.Introduced, "ForeignKeyViolation", "VirtualService/payments/api") {
+		t.Errorf("Expected ForeignKeyViolation of VirtualService/payments/api to be introduced")
+	}
+	if len(report.Resolved) != 0 {
+		t.Errorf("Expected no resolved violations, got %v", report.Resolved)
+	}
+
+	// Исходная база не изменилась
+	var services int
+	db.QueryRow("SELECT COUNT(*) FROM services").Scan(&services)
+	if services != 1 {
+		t.Errorf("Expected the original database to keep 1 service, got %d", services)
+	}
+
+	// Изменение labels pod убирает его из subset v1
+	pod := objects[1].(*corev1.Pod).DeepCopy()
+	pod.Labels["version"] = "v2"
+	report, err = operator.AnalyzeImpact(ctx, db, Mutation{Operation: MutationApply, Object: pod})
+	if err != nil {
+		t.Fatalf("Failed to analyze impact: %v", err)
+	}
+	if !hasViolation(report.Introduced, "WeightViolation", "VirtualService/payments/api") {
+		t.Errorf("Expected WeightViolation for the empty subset, got %v", report.Introduced)
+	}
+
+	// Обратное изменение исправляет нарушение
+	changed, err := operator.CreateInMemoryDB(impactModel(t, append(append([]client.Object{}, objects[0], pod), objects[2:]...)))
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer changed.Close()
+	report, err = operator.AnalyzeImpact(ctx, changed, Mutation{Operation: MutationApply, Object: objects[1]})
+	if err != nil {
+		t.Fatalf("Failed to analyze impact: %v", err)
+	}
+	if len(report.Introduced) != 0 || !hasViolation(report.Resolved, "WeightViolation", "VirtualService/payments/api") {
+		t.Errorf("Expected the WeightViolation to be resolved, got introduced %v, resolved %v", report.Introduced, report.Resolved)
+	}
+
+	if _, err := operator.AnalyzeImpact(ctx, db, Mutation{Operation: "patch", Object: pod}); err == nil {
+		t.Error("Expected unknown operation to be rejected")
+	}
+}
+
+// TestApplyMutationMatchesModel сравнивает мутацию копии базы с базой, построенной
+// из модели после AddObject/RemoveObject
+func TestApplyMutationMatchesModel(t *testing.T) {
+	ctx := context.Background()
+	operator := &SQLiteIntegrityOperator{}
+	objects := impactObjects()
+	db, err := operator.CreateInMemoryDB(impactModel(t, objects))
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	for _, obj := range objects {
+		for _, operation := range []MutationOperation{MutationDelete, MutationApply} {
+			copied, err := operator.CopyDB(ctx, db)
+			if err != nil {
+				t.Fatalf("Failed to copy database: %v", err)
+			}
+			if err := operator.applyMutation(ctx, copied, Mutation{Operation: operation, Object: obj}); err != nil {
+				t.Fatalf("Failed to %s %T: %v", operation, obj, err)
+			}
+
+			model := impactModel(t, objects)
+			if operation == MutationDelete {
+				model.RemoveObject(obj)
+			}
+			expected, err := operator.CreateInMemoryDB(model)
+			if err != nil {
+				t.Fatalf("Failed to create in-memory database: %v", err)
+			}
+
+			if got, want := tableCounts(t, copied), tableCounts(t, expected); got != want {
+				t.Errorf("%s %s: table rows %s, expected %s", operation, ObjectResource(obj), got, want)
+			}
+			got, _ := operator.CheckIntegrity(copied)
+			want, _ := operator.CheckIntegrity(expected)
+			if len(DiffViolations(got.Violations, want.Violations)) != 0 || len(DiffViolations(want.Violations, got.Violations)) != 0 {
+				t.Errorf("%s %s: violations %v, expected %v", operation, ObjectResource(obj), got.Violations, want.Violations)
+			}
+			copied.Close()
+			expected.Close()
+		}
+	}
+}
+
+// TestObjectRecordTablesCoverSchema проверяет, что objectRecordTables знает каждую таблицу
+// схемы и каждый kind общих таблиц: иначе мутации и diff оставляли бы устаревшие строки
+func TestObjectRecordTablesCoverSchema(t *testing.T) {
+	ctx := context.Background()
+	covered := map[string]map[string]bool{}
+	for _, source := range modelLists() {
+		tables := objectRecordTables(listItem(source.list))
+		if len(tables) == 0 {
+			t.Errorf("%s: no record tables", source.kind)
+		}
+		for _, table := range tables {
+			if covered[table.table] == nil {
+				covered[table.table] = map[string]bool{}
+			}
+			covered[table.table][table.kind] = true
+		}
+	}
+
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(&RelationalModel{})
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	tables, err := snapshotTables(ctx, db)
+	db.Close()
+	if err != nil {
+		t.Fatalf("Failed to list tables: %v", err)
+	}
+	for _, table := range tables {
+		if _, ok := covered[table]; !ok {
+			t.Errorf("Table %s is not covered by objectRecordTables", table)
+		}
+	}
+
+	// Модели testdata заполняют общие таблицы записями всех kind
+	files, err := filepath.Glob("testdata/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		model, err := parseYAMLResources(file)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", file, err)
+		}
+		db, err := operator.CreateInMemoryDB(model)
+		if err != nil {
+			t.Fatalf("%s: failed to create in-memory database: %v", file, err)
+		}
+		for table, kinds := range covered {
+			if kinds[""] {
+				continue
+			}
+			for _, kind := range tableKinds(t, db, table) {
+				if !kinds[kind] {
+					t.Errorf("%s: records of kind %s in %s are not covered by objectRecordTables", file, kind, table)
+				}
+			}
+		}
+		db.Close()
+	}
+}
+
+func tableKinds(t *testing.T, db *sql.DB, table string) []string {
+	t.Helper()
+	rows, err := db.Query(fmt.Sprintf("SELECT DISTINCT kind FROM %s", table))
+	if err != nil {
+		t.Fatalf("Failed to read kinds of %s: %v", table, err)
+	}
+	defer rows.Close()
+	var kinds []string
+	for rows.Next() {
+		var kind string
+		if err := rows.Scan(&kind); err != nil {
+			t.Fatal(err)
+		}
+		kinds = append(kinds, kind)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return kinds
+}
+
+func tableCounts(t *testing.T, db *sql.DB) string {
+	t.Helper()
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table'")
+	if err != nil {
+		t.Fatalf("Failed to list tables: %v", err)
+	}
+	var tables []string
+	for rows.Next() {
+		var table string
+		rows.Scan(&table)
+		tables = append(tables, table)
+	}
+	rows.Close()
+	sort.Strings(tables)
+
+	var counts []string
+	for _, table := range tables {
+		var count int
+		db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count)
+		if count > 0 {
+			counts = append(counts, fmt.Sprintf("%s=%d", table, count))
+		}
+	}
+	return strings.Join(counts, " ")
+}
+
+func hasViolation(violations []meshv1alpha1.ConstraintViolation, violationType, resource string) bool {
+	for _, violation := range violations {
+		if violation.Type == violationType && violation.Resource == resource {
+			return true
+		}
+	}
+	return false
+}
@@ -3,6 +3,7 @@ package integrity
 
 import (
 	"database/sql"
+	"strings"
 	"testing"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -80,6 +81,47 @@ func TestCheckForeignKeyViolations(t *testing.T) {
 	}
 }
 
+func TestCheckDestinationPortViolations(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	operator := &SQLiteIntegrityOperator{}
+	if err := operator.createSchema(db); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+
+	// Порт 8443 не объявлен Service, порт без destination.port и Service без портов не проверяются
+	testData := `
+		INSERT INTO services (namespace, name, host, port, protocol) VALUES
+		('default', 'web', 'web.default.svc.cluster.local', 8080, 'TCP'),
+		('default', 'legacy', 'legacy.default.svc.cluster.local', 0, 'TCP');
+		INSERT INTO service_ports (namespace, name, port_name, port) VALUES
+		('default', 'web', 'http', 8080);
+		INSERT INTO virtual_services (namespace, name, gateway_namespace, gateway_name, host, service_namespace, service_name) VALUES
+		('default', 'web', '', '', 'web.example.com', 'default', 'web');
+		INSERT INTO virtual_service_destinations (namespace, name, http_index, route_index, host, canonical_host, port) VALUES
+		('default', 'web', 0, 0, 'web', 'web.default.svc.cluster.local', 8080),
+		('default', 'web', 1, 0, 'web', 'web.default.svc.cluster.local', 8443),
+		('default', 'web', 2, 0, 'web', 'web.default.svc.cluster.local', 0),
+		('default', 'web', 3, 0, 'legacy', 'legacy.default.svc.cluster.local', 9090);
+	`
+	if _, err := db.Exec(testData); err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+
+	violations, err := operator.checkForeignKeyViolations(db)
+	if err != nil {
+		t.Fatalf("Failed to check foreign key violations: %v", err)
+	}
+	expected := "http[1].route[0] references port 8443 not exposed by Service/default/web"
+	if len(violations) != 1 || violations[0].Resource != "VirtualService/default/web" || violations[0].Message != expected {
+		t.Errorf("Expected %q, got %v", expected, violations)
+	}
+}
+
 func TestCheckUniqueConstraintViolations(t *testing.T) {
 	db, err := sql.Open("sqlite3", "file:testdb?mode=memory&cache=shared")
 	if err != nil {
@@ -134,6 +176,48 @@ func TestCheckUniqueConstraintViolations(t *testing.T) {
 	// }
 }
 
+// Конфликт host:port проверяется по всем портам Service, а не только по первому
+func TestCheckUniqueConstraintViolationsAllPorts(t *testing.T) {
+	model := &RelationalModel{
+		Services: []ServiceRecord{
+			{Namespace: "default", Name: "web1", Host: "same.host.svc.cluster.local", Port: 80, Protocol: "TCP"},
+			{Namespace: "default", Name: "web2", Host: "same.host.svc.cluster.local", Port: 9090, Protocol: "TCP"},
+			{Namespace: "default", Name: "legacy", Host: "same.host.svc.cluster.local", Port: 9090, Protocol: "TCP"},
+		},
+		ServicePorts: []ServicePortRecord{
+			{Namespace: "default", Name: "web1", PortName: "http", Port: 80, Protocol: "TCP"},
+			{Namespace: "default", Name: "web1", PortName: "metrics", Port: 8080, Protocol: "TCP"},
+			{Namespace: "default", Name: "web2", PortName: "grpc", Port: 9090, Protocol: "TCP"},
+			{Namespace: "default", Name: "web2", PortName: "metrics", Port: 8080, Protocol: "TCP"},
+		},
+	}
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	violations, err := operator.checkUniqueConstraintViolations(db)
+	if err != nil {
+		t.Fatalf("Failed to check unique constraint violations: %v", err)
+	}
+	var ports []string
+	for _, violation := range violations {
+		if strings.HasPrefix(violation.Message, "Duplicate host:port") {
+			ports = append(ports, violation.Message)
+		}
+	}
+	// 8080 - второй порт web1 и web2, 9090 - порт web2 и services.port legacy без service_ports
+	expected := []string{
+		"Duplicate host:port combination: same.host.svc.cluster.local:8080 (2 services)",
+		"Duplicate host:port combination: same.host.svc.cluster.local:9090 (2 services)",
+	}
+	if strings.Join(ports, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected %q, got %q", expected, ports)
+	}
+}
+
 func TestCheckIntegrity_ConsistentModel(t *testing.T) {
 	db, err := sql.Open("sqlite3", "file:testdb?mode=memory&cache=shared")
 	if err != nil {
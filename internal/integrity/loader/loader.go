@@ -0,0 +1,526 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package loader reads YAML and JSON manifests into the typed objects the relational
+// model is built from. Every supported version of a kind is decoded into the version
+// the integrity package works with, each object remembers the file and line it came from.
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+	istio "istio.io/client-go/pkg/apis/networking/v1beta1"
+	security "istio.io/client-go/pkg/apis/security/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+	"sigs.k8s.io/yaml"
+)
+
+// StdinName is the path that reads manifests from stdin and the file name of its objects
+const StdinName = "-"
+
+// Source is the location of an object in the manifests
+type Source struct {
+	File string `json:"file"`
+	Line int    `json:"line"` // строка начала документа или элемента List, с 1
+	// EndLine is the last line of the document, 0 when the object shares it with others
+	EndLine int `json:"endLine,omitempty"`
+}
+
+func (s Source) String() string {
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
+}
+
+// Object is a decoded object with its location
+type Object struct {
+	client.Object
+	Source Source
+}
+
+// ParseError is a manifest that could not be decoded
+type ParseError struct {
+	Source Source
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Source, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseErrors collects the ParseError of every manifest that could not be decoded
+type ParseErrors []*ParseError
+
+func (e ParseErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// kind describes a supported kind: the versions accepted in manifests and the typed
+// object they are decoded into
+type kind struct {
+	versions []string
+	newObj   func() client.Object
+}
+
+// Istio и Gateway API публикуют одну схему во всех версиях, поэтому любая поддерживаемая
+// версия декодируется в тип, с которым работает integrity
+var (
+	istioNetworkingVersions = []string{"v1alpha3", "v1beta1", "v1"}
+	istioSecurityVersions   = []string{"v1beta1", "v1"}
+
+	kinds = map[schema.GroupKind]kind{
+		{Group: "", Kind: "Service"}:        {[]string{"v1"}, func() client.Object { return &corev1.Service{} }},
+		{Group: "", Kind: "Pod"}:            {[]string{"v1"}, func() client.Object { return &corev1.Pod{} }},
+		{Group: "", Kind: "Namespace"}:      {[]string{"v1"}, func() client.Object { return &corev1.Namespace{} }},
+		{Group: "", Kind: "ServiceAccount"}: {[]string{"v1"}, func() client.Object { return &corev1.ServiceAccount{} }},
+
+		{Group: istio.GroupName, Kind: "VirtualService"}:  {istioNetworkingVersions, func() client.Object { return &istio.VirtualService{} }},
+		{Group: istio.GroupName, Kind: "DestinationRule"}: {istioNetworkingVersions, func() client.Object { return &istio.DestinationRule{} }},
+		{Group: istio.GroupName, Kind: "Gateway"}:         {istioNetworkingVersions, func() client.Object { return &istio.Gateway{} }},
+		{Group: istio.GroupName, Kind: "ServiceEntry"}:    {istioNetworkingVersions, func() client.Object { return &istio.ServiceEntry{} }},
+		{Group: istio.GroupName, Kind: "Sidecar"}:         {istioNetworkingVersions, func() client.Object { return &istio.Sidecar{} }},
+
+		{Group: security.GroupName, Kind: "PeerAuthentication"}:  {istioSecurityVersions, func() client.Object { return &security.PeerAuthentication{} }},
+		{Group: security.GroupName, Kind: "AuthorizationPolicy"}: {istioSecurityVersions, func() client.Object { return &security.AuthorizationPolicy{} }},
+
+		{Group: meshv1alpha1.GroupVersion.Group, Kind: "MeshService"}: {[]string{"v1alpha1"}, func() client.Object { return &meshv1alpha1.MeshService{} }},
+
+		{Group: gatewayv1.GroupName, Kind: "Gateway"}:        {[]string{"v1", "v1beta1"}, func() client.Object { return &gatewayv1.Gateway{} }},
+		{Group: gatewayv1.GroupName, Kind: "HTTPRoute"}:      {[]string{"v1", "v1beta1"}, func() client.Object { return &gatewayv1.HTTPRoute{} }},
+		{Group: gatewayv1.GroupName, Kind: "GRPCRoute"}:      {[]string{"v1", "v1alpha2"}, func() client.Object { return &gatewayv1.GRPCRoute{} }},
+		{Group: gatewayv1.GroupName, Kind: "ReferenceGrant"}: {[]string{"v1beta1", "v1alpha2"}, func() client.Object { return &gatewayv1beta1.ReferenceGrant{} }},
+	}
+)
+
+// groupPreference разрешает kind без группы: Gateway без группы - это Gateway Istio
+var groupPreference = []string{"", istio.GroupName, security.GroupName, meshv1alpha1.GroupVersion.Group, gatewayv1.GroupName}
+
+// NewObject creates an empty object for a resource formatted as Kind/namespace/name or
+// Namespace/name. The kind may be qualified with its group, e.g. Gateway.gateway.networking.k8s.io,
+// and is matched case-insensitively.
+func NewObject(resource string) (client.Object, error) {
+	parts := strings.Split(resource, "/")
+	kindName, group, qualified := strings.Cut(parts[0], ".")
+
+	// Kind сравнивается без учета регистра, как в kubectl: service/default/api
+	var gk schema.GroupKind
+	for _, candidate := range groupPreference {
+		if qualified && candidate != group {
+			continue
+		}
+		for known := range kinds {
+			if known.Group == candidate && strings.EqualFold(known.Kind, kindName) {
+				gk = known
+				break
+			}
+		}
+		if gk.Kind != "" {
+			break
+		}
+	}
+	if gk.Kind == "" {
+		return nil, fmt.Errorf("%s: kind %s is not part of the relational model", resource, parts[0])
+	}
+
+	obj := kinds[gk].newObj()
+	switch {
+	case gk == schema.GroupKind{Kind: "Namespace"} && len(parts) == 2:
+		obj.SetName(parts[1])
+	case len(parts) == 3 && gk != schema.GroupKind{Kind: "Namespace"}:
+		obj.SetNamespace(parts[1])
+		obj.SetName(parts[2])
+	default:
+		return nil, fmt.Errorf("%s: expected Kind/namespace/name or Namespace/name", resource)
+	}
+	if obj.GetName() == "" || (obj.GetNamespace() == "" && len(parts) == 3) {
+		return nil, fmt.Errorf("%s: namespace and name must not be empty", resource)
+	}
+	return obj, nil
+}
+
+// Supported reports whether objects of the group and kind are part of the relational model
+func Supported(gk schema.GroupKind) bool {
+	_, ok := kinds[gk]
+	return ok
+}
+
+// LoadPaths loads files, directories (recursively, *.yaml, *.yml and *.json) and stdin
+// for StdinName. Objects of all manifests that could be decoded are returned together
+// with ParseErrors for the rest; failures to read a path are returned as is.
+func LoadPaths(paths []string, stdin io.Reader) ([]Object, error) {
+	var objects []Object
+	var parseErrors ParseErrors
+	load := func(name string, data []byte) {
+		loaded, err := Load(name, data)
+		objects = append(objects, loaded...)
+		var errs ParseErrors
+		if errors.As(err, &errs) {
+			parseErrors = append(parseErrors, errs...)
+		}
+	}
+
+	for _, path := range paths {
+		if path == StdinName {
+			data, err := io.ReadAll(stdin)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read stdin: %w", err)
+			}
+			load(StdinName, data)
+			continue
+		}
+
+		files, err := ManifestFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			load(file, data)
+		}
+	}
+
+	if len(parseErrors) > 0 {
+		return objects, parseErrors
+	}
+	return objects, nil
+}
+
+// LoadFile loads the manifests of one file
+func LoadFile(path string) ([]Object, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Load(path, data)
+}
+
+// ManifestFiles returns the path itself or the manifests of a directory in lexical order
+func ManifestFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && IsManifestFile(file) {
+			files = append(files, file)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// IsManifestFile reports whether a file in a directory is read as a manifest
+func IsManifestFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// Load decodes multi-document YAML or a stream of JSON objects. Documents of kinds that
+// are not part of the model are skipped; documents that cannot be decoded are reported as
+// ParseErrors, the remaining objects are still returned.
+func Load(name string, data []byte) ([]Object, error) {
+	var objects []Object
+	var parseErrors ParseErrors
+
+	docs, splitError := splitDocuments(name, data)
+	for _, doc := range docs {
+		loaded, err := decodeDocument(name, doc)
+		objects = append(objects, loaded...)
+		var parseError *ParseError
+		if errors.As(err, &parseError) {
+			parseErrors = append(parseErrors, parseError)
+		}
+	}
+
+	if splitError != nil {
+		parseErrors = append(parseErrors, splitError)
+	}
+
+	if len(parseErrors) > 0 {
+		return objects, parseErrors
+	}
+	return objects, nil
+}
+
+// Decode decodes a single YAML or JSON object of a supported kind
+func Decode(data []byte) (client.Object, error) {
+	raw, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	var typeMeta metav1.TypeMeta
+	if err := json.Unmarshal(raw, &typeMeta); err != nil {
+		return nil, fmt.Errorf("expected a Kubernetes object: %w", err)
+	}
+	gv, err := schema.ParseGroupVersion(typeMeta.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := decodeObject(gv.WithKind(typeMeta.Kind), raw)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, fmt.Errorf("object kind %s is not part of the relational model", gv.WithKind(typeMeta.Kind).GroupKind())
+	}
+	return obj, nil
+}
+
+// document is one YAML document or JSON value of a manifest
+type document struct {
+	data  []byte
+	start int  // строка, с которой начинаются data
+	line  int  // первая строка с содержимым
+	end   int  // последняя строка с содержимым
+	json  bool // JSON значение, номера строк ошибок не применимы
+	items bool // элемент массива JSON, строки документа общие с другими элементами
+}
+
+// splitDocuments разбивает манифест на документы utilyaml.YAMLReader: разделитель - это
+// "---" в начале строки, внутри block scalar такие строки имеют отступ. Reader отбрасывает
+// разделители и не сообщает номера строк, поэтому они отсчитываются по исходным строкам.
+// Поток JSON значений разбирается json.Decoder.
+func splitDocuments(name string, data []byte) ([]document, *ParseError) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		if docs, ok := splitJSON(data); ok {
+			return docs, nil
+		}
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	next := 1 // первая строка, которую reader еще не вернул
+	var docs []document
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		content, err := reader.Read()
+		if err == io.EOF {
+			return docs, nil
+		}
+		if err != nil {
+			// Reader останавливается на разделителе с содержимым после "---"
+			line := next
+			for line <= len(lines) && !invalidSeparator(lines[line-1]) {
+				line++
+			}
+			return docs, &ParseError{Source: Source{File: name, Line: line}, Err: err}
+		}
+
+		for next <= len(lines) && bytes.HasPrefix(lines[next-1], []byte(documentSeparator)) {
+			next++
+		}
+		doc := document{data: content, start: next}
+		for i, line := range bytes.Split(bytes.TrimSuffix(content, []byte("\n")), []byte("\n")) {
+			// "..." завершает документ и содержимым не считается
+			if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 && trimmed[0] != '#' && string(trimmed) != "..." {
+				if doc.line == 0 {
+					doc.line = next + i
+				}
+				doc.end = next + i
+			}
+		}
+		next += bytes.Count(content, []byte("\n"))
+		if doc.line > 0 {
+			docs = append(docs, doc)
+		}
+	}
+}
+
+// documentSeparator начинает строку-разделитель документов YAML, как в utilyaml.YAMLReader
+const documentSeparator = "---"
+
+// invalidSeparator reports whether YAMLReader rejects the line as a separator followed by
+// something other than a comment
+func invalidSeparator(line []byte) bool {
+	if !bytes.HasPrefix(line, []byte(documentSeparator)) {
+		return false
+	}
+	rest := bytes.TrimSpace(line[len(documentSeparator):])
+	return len(rest) > 0 && rest[0] != '#'
+}
+
+// splitJSON splits a stream of JSON values, ok is false when the data is not JSON
+func splitJSON(data []byte) ([]document, bool) {
+	var docs []document
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		offset := decoder.InputOffset()
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err == io.EOF {
+			return docs, true
+		} else if err != nil {
+			return nil, false
+		}
+		// InputOffset указывает на конец предыдущего значения, пропускаем пробелы до начала
+		start := offset + int64(len(data[offset:])-len(bytes.TrimLeft(data[offset:], " \t\r\n")))
+		line := 1 + bytes.Count(data[:start], []byte("\n"))
+		// Массив JSON объектов разбирается как набор документов
+		var items []json.RawMessage
+		if bytes.HasPrefix(bytes.TrimSpace(value), []byte("[")) && json.Unmarshal(value, &items) == nil {
+			for _, item := range items {
+				docs = append(docs, document{data: item, start: line, line: line, json: true, items: true})
+			}
+			continue
+		}
+		end := line + bytes.Count(value, []byte("\n"))
+		docs = append(docs, document{data: value, start: line, line: line, end: end, json: true})
+	}
+}
+
+func decodeDocument(name string, doc document) ([]Object, error) {
+	source := Source{File: name, Line: doc.line}
+	fail := func(err error) error {
+		return &ParseError{Source: source, Err: err}
+	}
+
+	data := doc.data
+	if !doc.json {
+		// Пустые строки перед документом сохраняют номера строк манифеста в ошибках YAML
+		data = append(bytes.Repeat([]byte("\n"), doc.start-1), doc.data...)
+	}
+	raw, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fail(err)
+	}
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil, nil
+	}
+
+	var typeMeta metav1.TypeMeta
+	if err := json.Unmarshal(raw, &typeMeta); err != nil {
+		return nil, fail(fmt.Errorf("expected a Kubernetes object: %w", err))
+	}
+	if typeMeta.APIVersion == "" || typeMeta.Kind == "" {
+		return nil, fail(errors.New("apiVersion and kind are required"))
+	}
+	gv, err := schema.ParseGroupVersion(typeMeta.APIVersion)
+	if err != nil {
+		return nil, fail(err)
+	}
+
+	// v1/List и <Kind>List из kubectl get -o yaml
+	if gv.Group == "" && strings.HasSuffix(typeMeta.Kind, "List") {
+		return decodeList(name, doc, raw)
+	}
+
+	obj, err := decodeObject(gv.WithKind(typeMeta.Kind), raw)
+	if err != nil {
+		return nil, fail(err)
+	}
+	if obj == nil {
+		return nil, nil
+	}
+	if !doc.items {
+		source.EndLine = doc.end
+	}
+	return []Object{{Object: obj, Source: source}}, nil
+}
+
+func decodeList(name string, doc document, raw []byte) ([]Object, error) {
+	source := Source{File: name, Line: doc.line}
+	var list struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, &ParseError{Source: source, Err: err}
+	}
+
+	var objects []Object
+	for i, item := range list.Items {
+		var typeMeta metav1.TypeMeta
+		if err := json.Unmarshal(item, &typeMeta); err != nil {
+			return objects, &ParseError{Source: source, Err: fmt.Errorf("items[%d]: %w", i, err)}
+		}
+		gv, err := schema.ParseGroupVersion(typeMeta.APIVersion)
+		if err != nil {
+			return objects, &ParseError{Source: source, Err: fmt.Errorf("items[%d]: %w", i, err)}
+		}
+		obj, err := decodeObject(gv.WithKind(typeMeta.Kind), item)
+		if err != nil {
+			return objects, &ParseError{Source: source, Err: fmt.Errorf("items[%d]: %w", i, err)}
+		}
+		if obj != nil {
+			objects = append(objects, Object{Object: obj, Source: source})
+		}
+	}
+	return objects, nil
+}
+
+// decodeObject decodes JSON of a supported kind into its typed object, nil for other kinds
+func decodeObject(gvk schema.GroupVersionKind, raw []byte) (client.Object, error) {
+	supported, ok := kinds[gvk.GroupKind()]
+	if !ok {
+		return nil, nil
+	}
+	if !slices.Contains(supported.versions, gvk.Version) {
+		return nil, fmt.Errorf("unsupported version %s of %s, expected one of %s",
+			gvk.GroupVersion(), gvk.Kind, strings.Join(supported.versions, ", "))
+	}
+
+	obj := supported.newObj()
+	if err := json.Unmarshal(raw, obj); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", gvk.Kind, err)
+	}
+	if obj.GetName() == "" {
+		return nil, fmt.Errorf("%s metadata.name is required", gvk.Kind)
+	}
+	return obj, nil
+}
+
+// ClientObjects drops the sources of the objects
+func ClientObjects(objects []Object) []client.Object {
+	result := make([]client.Object, 0, len(objects))
+	for _, obj := range objects {
+		result = append(result, obj.Object)
+	}
+	return result
+}
@@ -0,0 +1,279 @@
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	istio "istio.io/client-go/pkg/apis/networking/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestLoadYAML(t *testing.T) {
+	manifest := `# comment only documents are skipped
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+  namespace: payments
+  annotations:
+    description: |
+      text with a separator
+      ---
+      inside a block scalar
+    note: a---b
+spec:
+  ports:
+  - name: http
+    port: 8080
+---
+apiVersion: networking.istio.io/v1
+kind: Gateway
+metadata:
+  name: public
+  namespace: istio-system
+--- # separator with a comment
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: api
+  namespace: payments
+spec:
+  hosts: ["api.example.com"]
+  gateways: ["istio-system/public"]
+...
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: skipped
+  namespace: payments
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: gateway.networking.k8s.io/v1beta1
+  kind: HTTPRoute
+  metadata:
+    name: api
+    namespace: payments
+- apiVersion: v1
+  kind: Pod
+  metadata:
+    name: api-0
+    namespace: payments
+`
+	objects, err := Load("manifest.yaml", []byte(manifest))
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+
+	expected := []struct {
+		name  string
+		line  int
+		check func(any) bool
+	}{
+		{"api", 3, func(obj any) bool {
+			svc, ok := obj.(*corev1.Service)
+			return ok && strings.Contains(svc.Annotations["description"], "---") && svc.Annotations["note"] == "a---b"
+		}},
+		{"public", 19, func(obj any) bool { _, ok := obj.(*istio.Gateway); return ok }},
+		{"api", 25, func(obj any) bool {
+			vs, ok := obj.(*istio.VirtualService)
+			return ok && len(vs.Spec.Gateways) == 1
+		}},
+		{"api", 41, func(obj any) bool { _, ok := obj.(*gatewayv1.HTTPRoute); return ok }},
+		{"api-0", 41, func(obj any) bool { _, ok := obj.(*corev1.Pod); return ok }},
+	}
+	if len(objects) != len(expected) {
+		t.Fatalf("Expected %d objects, got %d", len(expected), len(objects))
+	}
+	for i, exp := range expected {
+		obj := objects[i]
+		if obj.GetName() != exp.name || obj.Source.Line != exp.line || !exp.check(obj.Object) {
+			t.Errorf("Object %d: expected %s at line %d, got %T %s at %s", i, exp.name, exp.line, obj.Object, obj.GetName(), obj.Source)
+		}
+		t.Logf("✅ %s: %T %s/%s", obj.Source, obj.Object, obj.GetNamespace(), obj.GetName())
+	}
+	// Конец документа известен для отдельных документов, но не для элементов List
+	for i, end := range []int{17, 23, 32, 0, 0} {
+		if objects[i].Source.EndLine != end {
+			t.Errorf("Object %d: expected end line %d, got %d", i, end, objects[i].Source.EndLine)
+		}
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	manifest := `{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "payments"}}
+{
+  "apiVersion": "security.istio.io/v1",
+  "kind": "PeerAuthentication",
+  "metadata": {"name": "default", "namespace": "payments"}
+}
+[{"apiVersion": "mesh.istio.operator/v1alpha1", "kind": "MeshService", "metadata": {"name": "api", "namespace": "payments"}}]
+`
+	objects, err := Load("manifest.json", []byte(manifest))
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	lines := []int{1, 2, 7}
+	if len(objects) != len(lines) {
+		t.Fatalf("Expected %d objects, got %d", len(lines), len(objects))
+	}
+	for i, line := range lines {
+		if objects[i].Source.Line != line {
+			t.Errorf("Object %s: expected line %d, got %s", objects[i].GetName(), line, objects[i].Source)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	manifest := `apiVersion: v1
+kind: Service
+metadata:
+  name: ok
+  namespace: payments
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: broken
+  namespace: [payments
+---
+apiVersion: networking.istio.io/v2
+kind: VirtualService
+metadata:
+  name: api
+---
+metadata:
+  name: no-kind
+---
+apiVersion: v1
+kind: Service
+spec:
+  ports: "not a list"
+`
+	objects, err := Load("broken.yaml", []byte(manifest))
+	if len(objects) != 1 || objects[0].GetName() != "ok" {
+		t.Errorf("Expected the valid Service to be loaded, got %d objects", len(objects))
+	}
+
+	var errs ParseErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected ParseErrors, got %v", err)
+	}
+	expected := []struct {
+		line     int
+		fragment string
+	}{
+		// ParseError указывает на документ, строка ошибки YAML отсчитывается от начала файла
+		{7, "line 11: did not find expected"},
+		{13, "unsupported version networking.istio.io/v2"},
+		{18, "apiVersion and kind are required"},
+		{21, "invalid Service"},
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d:\n%v", len(expected), len(errs), err)
+	}
+	for i, exp := range expected {
+		if errs[i].Source.File != "broken.yaml" || errs[i].Source.Line != exp.line || !strings.Contains(errs[i].Error(), exp.fragment) {
+			t.Errorf("Error %d: expected broken.yaml:%d %q, got %v", i, exp.line, exp.fragment, errs[i])
+		}
+		t.Logf("⚠️ %v", errs[i])
+	}
+
+	// После "---" допустим только комментарий
+	_, err = Load("separator.yaml", []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: shop\n--- kind: Service\n"))
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Source.Line != 5 || !strings.Contains(err.Error(), "invalid Yaml document separator") {
+		t.Errorf("Expected an invalid separator at separator.yaml:5, got %v", err)
+	}
+}
+
+func TestLoadPaths(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"service.yaml":       "apiVersion: v1\nkind: Service\nmetadata:\n  name: api\n  namespace: payments\n",
+		"nested/gateway.yml": "apiVersion: networking.istio.io/v1beta1\nkind: Gateway\nmetadata:\n  name: public\n  namespace: istio-system\n",
+		"nested/ns.json":     `{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "payments"}}`,
+		"README.md":          "not a manifest",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stdin := strings.NewReader("apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: api\n  namespace: payments\n")
+	objects, err := LoadPaths([]string{dir, StdinName}, stdin)
+	if err != nil {
+		t.Fatalf("Failed to load paths: %v", err)
+	}
+	if len(objects) != 4 {
+		t.Fatalf("Expected 4 objects, got %d", len(objects))
+	}
+	if last := objects[len(objects)-1]; last.Source.File != StdinName {
+		t.Errorf("Expected the last object to come from stdin, got %s", last.Source)
+	}
+
+	if _, err := LoadPaths([]string{filepath.Join(dir, "missing.yaml")}, nil); err == nil {
+		t.Error("Expected an error for a missing path")
+	}
+}
+
+// Фикстуры integrity загружаются без ошибок
+func TestLoadFixtures(t *testing.T) {
+	files, err := ManifestFiles("../testdata")
+	if err != nil {
+		t.Fatalf("Failed to list fixtures: %v", err)
+	}
+	for _, file := range files {
+		if _, err := LoadFile(file); err != nil {
+			t.Errorf("%v", err)
+		}
+	}
+}
+
+func TestNewObject(t *testing.T) {
+	tests := []struct {
+		resource string
+		expected string // тип объекта, пусто для ошибки
+	}{
+		{"VirtualService/payments/api", "*v1beta1.VirtualService"},
+		{"Gateway/istio-system/public", "*v1beta1.Gateway"},
+		{"Gateway.gateway.networking.k8s.io/infra/public", "*v1.Gateway"},
+		{"Service/payments/api", "*v1.Service"},
+		{"virtualservice/payments/api", "*v1beta1.VirtualService"},
+		{"httproute.gateway.networking.k8s.io/shop/web", "*v1.HTTPRoute"},
+		{"Namespace/payments", "*v1.Namespace"},
+		{"Namespace/payments/api", ""},
+		{"Service/api", ""},
+		{"Service//api", ""},
+		{"ConfigMap/payments/api", ""},
+		{"Gateway.example.com/infra/public", ""},
+	}
+	for _, tt := range tests {
+		obj, err := NewObject(tt.resource)
+		if tt.expected == "" {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %T", tt.resource, obj)
+			}
+			continue
+		}
+		if err != nil || fmt.Sprintf("%T", obj) != tt.expected {
+			t.Errorf("%s: expected %s, got %T (%v)", tt.resource, tt.expected, obj, err)
+			continue
+		}
+		parts := strings.Split(tt.resource, "/")
+		if obj.GetName() != parts[len(parts)-1] || (len(parts) == 3 && obj.GetNamespace() != parts[1]) {
+			t.Errorf("%s: unexpected %s/%s", tt.resource, obj.GetNamespace(), obj.GetName())
+		}
+	}
+}
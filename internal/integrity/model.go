@@ -0,0 +1,213 @@
+package integrity
+
+import (
+	"fmt"
+	"reflect"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+	istio "istio.io/client-go/pkg/apis/networking/v1beta1"
+	security "istio.io/client-go/pkg/apis/security/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+// meshManagedAnnotation marks the Services the operator tracks in the relational model
+const meshManagedAnnotation = "mesh.operator.istio.io/managed"
+
+// AddToScheme registers every API the relational model is built from besides the core
+// Kubernetes types: Istio networking and security, Gateway API and MeshService
+func AddToScheme(scheme *runtime.Scheme) error {
+	for _, add := range []func(*runtime.Scheme) error{
+		istio.AddToScheme,
+		security.AddToScheme,
+		gatewayv1.Install,
+		gatewayv1beta1.Install,
+		meshv1alpha1.AddToScheme,
+	} {
+		if err := add(scheme); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddObject converts a Kubernetes object into records of the model, replacing the records
+// of a previous version of the same object. Services without the mesh annotation are
+// not part of the model, adding one removes the previous version only.
+func (m *RelationalModel) AddObject(obj client.Object) error {
+	return m.addObject(obj, false)
+}
+
+// AddManifestObject adds an object read from manifests. Unlike AddObject it keeps every
+// Service: manifests describe the desired state, not a cluster with unrelated services.
+func (m *RelationalModel) AddManifestObject(obj client.Object) error {
+	return m.addObject(obj, true)
+}
+
+func (m *RelationalModel) addObject(obj client.Object, allServices bool) error {
+	if !IsModelObject(obj) {
+		return fmt.Errorf("unsupported object type %T", obj)
+	}
+	m.RemoveObject(obj)
+
+	switch o := obj.(type) {
+	case *corev1.Service:
+		if !allServices && !isMeshManagedService(o) {
+			return nil
+		}
+		m.Services = append(m.Services, serviceToRecord(o))
+		m.ServicePorts = append(m.ServicePorts, serviceToPorts(o)...)
+	case *corev1.Pod:
+		m.Workloads = append(m.Workloads, podToWorkload(o))
+	case *corev1.Namespace:
+		m.Namespaces = append(m.Namespaces, NamespaceRecord{Name: o.Name})
+	case *corev1.ServiceAccount:
+		m.ServiceAccounts = append(m.ServiceAccounts, ServiceAccountRecord{Namespace: o.Namespace, Name: o.Name})
+	case *istio.VirtualService:
+		m.VirtualServices = append(m.VirtualServices, virtualServiceToRecord(o))
+		m.VirtualServiceDestinations = append(m.VirtualServiceDestinations, virtualServiceToDestinations(o)...)
+		m.VirtualServiceDelegates = append(m.VirtualServiceDelegates, virtualServiceToDelegates(o)...)
+		m.VirtualServiceHosts = append(m.VirtualServiceHosts, virtualServiceToHosts(o)...)
+		m.VirtualServiceGateways = append(m.VirtualServiceGateways, virtualServiceToGateways(o)...)
+		routes, matches, headers := virtualServiceToHTTPMatches(o)
+		m.HTTPRoutes = append(m.HTTPRoutes, routes...)
+		m.HTTPMatches = append(m.HTTPMatches, matches...)
+		m.HTTPMatchHeaders = append(m.HTTPMatchHeaders, headers...)
+	case *istio.DestinationRule:
+		m.DestinationRules = append(m.DestinationRules, destinationRuleToRecord(o))
+		m.TrafficPolicies = append(m.TrafficPolicies, destinationRuleToTrafficPolicies(o)...)
+		m.DestinationRuleSubsets = append(m.DestinationRuleSubsets, destinationRuleToSubsets(o)...)
+	case *istio.Gateway:
+		m.Gateways = append(m.Gateways, GatewayRecord{Namespace: o.Namespace, Name: o.Name})
+	case *istio.ServiceEntry:
+		m.ServiceEntries = append(m.ServiceEntries, serviceEntryToRecords(o)...)
+	case *istio.Sidecar:
+		record, hosts := sidecarToRecords(o)
+		m.Sidecars = append(m.Sidecars, record)
+		m.SidecarEgressHosts = append(m.SidecarEgressHosts, hosts...)
+	case *security.PeerAuthentication:
+		record, ports := peerAuthenticationToRecords(o)
+		m.PeerAuthentications = append(m.PeerAuthentications, record)
+		m.PeerAuthenticationPorts = append(m.PeerAuthenticationPorts, ports...)
+	case *security.AuthorizationPolicy:
+		record, rules, sources, operations := authorizationPolicyToRecords(o)
+		m.AuthorizationPolicies = append(m.AuthorizationPolicies, record)
+		m.AuthorizationPolicyRules = append(m.AuthorizationPolicyRules, rules...)
+		m.AuthorizationPolicySources = append(m.AuthorizationPolicySources, sources...)
+		m.AuthorizationPolicyOperations = append(m.AuthorizationPolicyOperations, operations...)
+	case *meshv1alpha1.MeshService:
+		m.MeshServices = append(m.MeshServices, meshServiceToRecord(o))
+		m.MeshServiceSubsets = append(m.MeshServiceSubsets, meshServiceToSubsets(o)...)
+	case *gatewayv1.Gateway:
+		record, listeners, certificates := gatewayAPIGatewayToRecords(o)
+		m.GatewayAPIGateways = append(m.GatewayAPIGateways, record)
+		m.GatewayAPIListeners = append(m.GatewayAPIListeners, listeners...)
+		m.GatewayAPICertificateRefs = append(m.GatewayAPICertificateRefs, certificates...)
+	case *gatewayv1.HTTPRoute:
+		record, parents, backends := httpRouteToRecords(o)
+		m.GatewayAPIRoutes = append(m.GatewayAPIRoutes, record)
+		m.GatewayAPIParentRefs = append(m.GatewayAPIParentRefs, parents...)
+		m.GatewayAPIBackendRefs = append(m.GatewayAPIBackendRefs, backends...)
+	case *gatewayv1.GRPCRoute:
+		record, parents, backends := grpcRouteToRecords(o)
+		m.GatewayAPIRoutes = append(m.GatewayAPIRoutes, record)
+		m.GatewayAPIParentRefs = append(m.GatewayAPIParentRefs, parents...)
+		m.GatewayAPIBackendRefs = append(m.GatewayAPIBackendRefs, backends...)
+	case *gatewayv1beta1.ReferenceGrant:
+		m.ReferenceGrants = append(m.ReferenceGrants, referenceGrantToRecords(o)...)
+	}
+	return nil
+}
+
+// RemoveObject drops every record derived from the object, as if it was deleted
+func (m *RelationalModel) RemoveObject(obj client.Object) {
+	ns, name := obj.GetNamespace(), obj.GetName()
+
+	switch obj.(type) {
+	case *corev1.Service:
+		removeRecords(ns, name, "", &m.Services, &m.ServicePorts)
+	case *corev1.Pod:
+		removeRecords(ns, name, "", &m.Workloads)
+	case *corev1.Namespace:
+		removeRecords("", name, "", &m.Namespaces)
+	case *corev1.ServiceAccount:
+		removeRecords(ns, name, "", &m.ServiceAccounts)
+	case *istio.VirtualService:
+		removeRecords(ns, name, "", &m.VirtualServices, &m.VirtualServiceDestinations, &m.VirtualServiceDelegates,
+			&m.VirtualServiceHosts, &m.VirtualServiceGateways, &m.HTTPRoutes, &m.HTTPMatches, &m.HTTPMatchHeaders)
+	case *istio.DestinationRule:
+		removeRecords(ns, name, "", &m.DestinationRules, &m.TrafficPolicies, &m.DestinationRuleSubsets)
+	case *istio.Gateway:
+		removeRecords(ns, name, "", &m.Gateways)
+	case *istio.ServiceEntry:
+		removeRecords(ns, name, "", &m.ServiceEntries)
+	case *istio.Sidecar:
+		removeRecords(ns, name, "", &m.Sidecars, &m.SidecarEgressHosts)
+	case *security.PeerAuthentication:
+		removeRecords(ns, name, "", &m.PeerAuthentications, &m.PeerAuthenticationPorts)
+	case *security.AuthorizationPolicy:
+		removeRecords(ns, name, "", &m.AuthorizationPolicies, &m.AuthorizationPolicyRules,
+			&m.AuthorizationPolicySources, &m.AuthorizationPolicyOperations)
+	case *meshv1alpha1.MeshService:
+		removeRecords(ns, name, "", &m.MeshServices, &m.MeshServiceSubsets)
+	case *gatewayv1.Gateway:
+		removeRecords(ns, name, "", &m.GatewayAPIGateways, &m.GatewayAPIListeners, &m.GatewayAPICertificateRefs)
+	case *gatewayv1.HTTPRoute:
+		removeRecords(ns, name, "HTTPRoute", &m.GatewayAPIRoutes, &m.GatewayAPIParentRefs, &m.GatewayAPIBackendRefs)
+	case *gatewayv1.GRPCRoute:
+		removeRecords(ns, name, "GRPCRoute", &m.GatewayAPIRoutes, &m.GatewayAPIParentRefs, &m.GatewayAPIBackendRefs)
+	case *gatewayv1beta1.ReferenceGrant:
+		removeRecords(ns, name, "", &m.ReferenceGrants)
+	}
+}
+
+// IsModelObject reports whether AddObject and RemoveObject know the object type
+func IsModelObject(obj client.Object) bool {
+	switch obj.(type) {
+	case *corev1.Service, *corev1.Pod, *corev1.Namespace, *corev1.ServiceAccount,
+		*istio.VirtualService, *istio.DestinationRule, *istio.Gateway, *istio.ServiceEntry, *istio.Sidecar,
+		*security.PeerAuthentication, *security.AuthorizationPolicy, *meshv1alpha1.MeshService,
+		*gatewayv1.Gateway, *gatewayv1.HTTPRoute, *gatewayv1.GRPCRoute, *gatewayv1beta1.ReferenceGrant:
+		return true
+	}
+	return false
+}
+
+// isMeshManagedService reports whether the Service carries the mesh annotation
+func isMeshManagedService(svc *corev1.Service) bool {
+	_, ok := svc.Annotations[meshManagedAnnotation]
+	return ok
+}
+
+// removeRecords replaces record slices with copies that drop the records whose Namespace
+// and Name fields (and Kind, when given) identify the object. Records are plain structs
+// of the model, so the fields are looked up by name. Copies keep shallow copies of the
+// model intact.
+func removeRecords(namespace, name, kind string, slices ...any) {
+	for _, slice := range slices {
+		records := reflect.ValueOf(slice).Elem()
+		kept := reflect.MakeSlice(records.Type(), 0, records.Len())
+		for i := 0; i < records.Len(); i++ {
+			if record := records.Index(i); !recordBelongs(record, namespace, name, kind) {
+				kept = reflect.Append(kept, record)
+			}
+		}
+		records.Set(kept)
+	}
+}
+
+func recordBelongs(record reflect.Value, namespace, name, kind string) bool {
+	if record.FieldByName("Name").String() != name {
+		return false
+	}
+	if field := record.FieldByName("Namespace"); field.IsValid() && field.String() != namespace {
+		return false
+	}
+	if field := record.FieldByName("Kind"); kind != "" && field.IsValid() && field.String() != kind {
+		return false
+	}
+	return true
+}
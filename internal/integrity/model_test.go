@@ -4,6 +4,7 @@ package integrity
 import (
 	"testing"
 
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -102,3 +103,70 @@ func TestShouldProcessService(t *testing.T) {
 		})
 	}
 }
+
+func TestRelationalModelAddRemoveObject(t *testing.T) {
+	model := &RelationalModel{}
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "web",
+			Annotations: map[string]string{meshManagedAnnotation: "true"},
+		},
+		Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80}, {Name: "grpc", Port: 9090}}},
+	}
+	if err := model.AddObject(service); err != nil {
+		t.Fatalf("AddObject() error = %v", err)
+	}
+	if len(model.Services) != 1 || len(model.ServicePorts) != 2 {
+		t.Fatalf("Expected 1 service with 2 ports, got %d services and %d ports", len(model.Services), len(model.ServicePorts))
+	}
+
+	// Повторное добавление заменяет предыдущую версию
+	service.Spec.Ports = service.Spec.Ports[:1]
+	if err := model.AddObject(service); err != nil {
+		t.Fatalf("AddObject() error = %v", err)
+	}
+	if len(model.Services) != 1 || len(model.ServicePorts) != 1 {
+		t.Errorf("Expected update to keep 1 service with 1 port, got %d services and %d ports", len(model.Services), len(model.ServicePorts))
+	}
+
+	other := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web", Annotations: service.Annotations}}
+	if err := model.AddObject(other); err != nil {
+		t.Fatalf("AddObject() error = %v", err)
+	}
+	model.RemoveObject(service)
+	if len(model.Services) != 1 || model.Services[0].Namespace != "prod" {
+		t.Errorf("Expected only prod/web to remain, got %+v", model.Services)
+	}
+
+	// Service без аннотации не попадает в модель
+	unmanaged := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "legacy"}}
+	if err := model.AddObject(unmanaged); err != nil {
+		t.Fatalf("AddObject() error = %v", err)
+	}
+	if len(model.Services) != 1 {
+		t.Errorf("Expected unmanaged Service to be ignored, got %d services", len(model.Services))
+	}
+
+	if err := model.AddObject(&corev1.ConfigMap{}); err == nil {
+		t.Error("Expected AddObject to reject unsupported types")
+	}
+}
+
+func TestDiffViolations(t *testing.T) {
+	existing := meshv1alpha1.ConstraintViolation{Type: "ForeignKeyViolation", Resource: "VirtualService/default/a", Message: "m"}
+	introduced := meshv1alpha1.ConstraintViolation{Type: "ForeignKeyViolation", Resource: "VirtualService/default/b", Message: "m"}
+
+	diff := DiffViolations(
+		[]meshv1alpha1.ConstraintViolation{existing},
+		[]meshv1alpha1.ConstraintViolation{existing, introduced, existing},
+	)
+	if len(diff) != 2 || diff[0] != introduced || diff[1] != existing {
+		t.Errorf("Expected the new violation and the repeated one, got %+v", diff)
+	}
+
+	if diff := DiffViolations([]meshv1alpha1.ConstraintViolation{existing}, nil); len(diff) != 0 {
+		t.Errorf("Expected resolved violations to be ignored, got %+v", diff)
+	}
+}
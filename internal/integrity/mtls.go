@@ -0,0 +1,284 @@
+package integrity
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+)
+
+// istioRootNamespace is the Istio root config namespace holding mesh-wide policies
+const istioRootNamespace = "istio-system"
+
+// serverMTLS is the effective server-side mTLS mode of a workload port with the
+// chain of PeerAuthentication policies it was resolved from
+type serverMTLS struct {
+	mode  string
+	chain string
+}
+
+// peerAuthenticationPolicy is a PeerAuthentication loaded from the model
+type peerAuthenticationPolicy struct {
+	namespace string
+	name      string
+	selector  string
+	mode      string
+	ports     map[int32]string
+}
+
+// checkMTLSViolations сравнивает клиентский TLS mode из DestinationRule с серверным
+// mTLS mode (PeerAuthentication) для workload целевого сервиса
+func (o *SQLiteIntegrityOperator) checkMTLSViolations(db *sql.DB) ([]meshv1alpha1.ConstraintViolation, error) {
+	var violations []meshv1alpha1.ConstraintViolation
+
+	policies, err := loadPeerAuthentications(db)
+	if err != nil {
+		return nil, err
+	}
+
+	// Клиентский TLS mode DestinationRule: portLevelSettings переопределяют политику своего
+	// уровня для порта. Политика subset переопределяет верхний уровень для трафика в subset
+	// и проверяется только для workload, которые выбирают labels subset; порты, для которых
+	// subset не задает tls, наследуют верхний уровень и проверяются вместе с ним.
+	rows, err := db.Query(`
+		SELECT dr.namespace, dr.name, tp.subset, s.namespace, s.name, sp.port, sp.target_port, tp.tls_mode, w.name
+		FROM destination_rules dr
+		JOIN services s ON lower(s.host) = dr.canonical_host
+		JOIN service_ports sp ON sp.namespace = s.namespace AND sp.name = s.name
+		JOIN traffic_policies tp ON tp.namespace = dr.namespace AND tp.name = dr.name
+		 AND tp.tls_mode <> ''
+		 AND (tp.port = sp.port OR (tp.port = 0 AND NOT EXISTS (
+			SELECT 1 FROM traffic_policies pp
+			WHERE pp.namespace = dr.namespace AND pp.name = dr.name AND pp.subset = tp.subset
+			  AND pp.port = sp.port AND pp.tls_mode <> ''
+		 )))
+		JOIN workloads w ON w.namespace = s.namespace AND s.selector <> ''
+		 AND ` + selectorMatchesSQL("Service", "s.namespace", "s.name", "w") + `
+		 AND (tp.subset = '' OR ` + selectorMatchesSQL("DestinationRuleSubset", "dr.namespace", "dr.name || '/' || tp.subset", "w") + `)
+		ORDER BY dr.namespace, dr.name, tp.subset, sp.port, w.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	type conflictKey struct {
+		drNamespace, drName, subset, svcNamespace, svcName string
+		port                                               int32
+		client, server, chain                              string
+	}
+	var keys []conflictKey
+	workloads := map[conflictKey][]string{}
+	type clientTarget struct {
+		key                 conflictKey
+		namespace, workload string
+		port                int32
+	}
+	var targets []clientTarget
+	for rows.Next() {
+		var drNs, drName, subset, svcNs, svcName, clientMode, workload string
+		var port, targetPort int32
+		if err := rows.Scan(&drNs, &drName, &subset, &svcNs, &svcName, &port, &targetPort, &clientMode, &workload); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if targetPort == 0 {
+			targetPort = port
+		}
+		targets = append(targets, clientTarget{
+			key: conflictKey{drNamespace: drNs, drName: drName, subset: subset, svcNamespace: svcNs, svcName: svcName,
+				port: port, client: clientMode},
+			namespace: svcNs,
+			workload:  workload,
+			port:      targetPort,
+		})
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// Серверный mTLS mode резолвится после закрытия rows: для workload-level политик нужен отдельный запрос
+	for _, target := range targets {
+		server, err := resolveServerMTLS(db, policies, target.namespace, target.workload, target.port)
+		if err != nil {
+			return nil, err
+		}
+		if !mtlsConflict(target.key.client, server.mode) {
+			continue
+		}
+		key := target.key
+		key.server = server.mode
+		key.chain = server.chain
+		if _, ok := workloads[key]; !ok {
+			keys = append(keys, key)
+		}
+		workloads[key] = append(workloads[key], target.workload)
+	}
+
+	for _, key := range keys {
+		client := fmt.Sprintf("Client TLS mode %s on port %d", key.client, key.port)
+		if key.subset != "" {
+			client += fmt.Sprintf(" of subset %s", key.subset)
+		}
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "MTLSViolation",
+			Resource: fmt.Sprintf("DestinationRule/%s/%s", key.drNamespace, key.drName),
+			Message: fmt.Sprintf("%s conflicts with server mTLS %s of Service/%s/%s workloads (%s); policy chain: %s",
+				client, key.server, key.svcNamespace, key.svcName, strings.Join(workloads[key], ", "), key.chain),
+			Severity: "Error",
+		})
+	}
+
+	return violations, nil
+}
+
+// mtlsConflict reports whether a client TLS mode cannot talk to a server mTLS mode.
+// An unset client mode relies on auto mTLS and PERMISSIVE accepts both.
+func mtlsConflict(client, server string) bool {
+	switch server {
+	case "STRICT":
+		return client == "DISABLE" || client == "SIMPLE" || client == "MUTUAL"
+	case "DISABLE":
+		return client == "ISTIO_MUTUAL"
+	}
+	return false
+}
+
+// loadPeerAuthentications reads all PeerAuthentication policies with their port-level modes,
+// the policies of a namespace oldest first like Istio resolves conflicts
+func loadPeerAuthentications(db *sql.DB) (map[string][]*peerAuthenticationPolicy, error) {
+	rows, err := db.Query(`
+		SELECT namespace, name, selector, mode FROM peer_authentications
+		ORDER BY namespace, created_at = '', created_at, name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	policies := map[string][]*peerAuthenticationPolicy{}
+	byName := map[string]*peerAuthenticationPolicy{}
+	for rows.Next() {
+		policy := &peerAuthenticationPolicy{ports: map[int32]string{}}
+		if err := rows.Scan(&policy.namespace, &policy.name, &policy.selector, &policy.mode); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		policies[policy.namespace] = append(policies[policy.namespace], policy)
+		byName[policy.namespace+"/"+policy.name] = policy
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(`SELECT namespace, name, port, mode FROM peer_authentication_ports`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ns, name, mode string
+		var port int32
+		if err := rows.Scan(&ns, &name, &port, &mode); err != nil {
+			return nil, err
+		}
+		if policy, ok := byName[ns+"/"+name]; ok {
+			policy.ports[port] = mode
+		}
+	}
+	return policies, rows.Err()
+}
+
+// resolveServerMTLS resolves the effective mTLS mode of a workload port:
+// workload policy (portLevelMtls, then mtls) -> namespace policy -> mesh policy -> PERMISSIVE
+func resolveServerMTLS(db *sql.DB, policies map[string][]*peerAuthenticationPolicy, namespace, workload string, port int32) (serverMTLS, error) {
+	var chain []string
+	mode := ""
+
+	// mesh-wide: root namespace без selector
+	meshPolicy := namespacePolicy(policies[istioRootNamespace])
+	chain = append(chain, describePolicy("mesh", meshPolicy))
+
+	// namespace-wide: без selector
+	nsPolicy := namespacePolicy(policies[namespace])
+	if namespace == istioRootNamespace {
+		nsPolicy = nil
+	}
+	chain = append(chain, describePolicy("namespace", nsPolicy))
+
+	// workload: selector совпадает с labels workload, при нескольких Istio применяет самую старую
+	workloadPolicy, err := matchingWorkloadPolicy(db, policies[namespace], namespace, workload)
+	if err != nil {
+		return serverMTLS{}, err
+	}
+	workloadDescription := describePolicy("workload", workloadPolicy)
+	if workloadPolicy != nil {
+		if portMode, ok := workloadPolicy.ports[port]; ok {
+			workloadDescription += fmt.Sprintf(", port %d=%s", port, portMode)
+		}
+	}
+	chain = append(chain, workloadDescription)
+
+	for _, policy := range []*peerAuthenticationPolicy{meshPolicy, nsPolicy, workloadPolicy} {
+		if policy != nil && policy.mode != "" && policy.mode != "UNSET" {
+			mode = policy.mode
+		}
+	}
+	if workloadPolicy != nil {
+		if portMode, ok := workloadPolicy.ports[port]; ok && portMode != "UNSET" {
+			mode = portMode
+		}
+	}
+	if mode == "" {
+		mode = "PERMISSIVE"
+	}
+
+	return serverMTLS{mode: mode, chain: strings.Join(chain, " -> ") + " => " + mode}, nil
+}
+
+func namespacePolicy(policies []*peerAuthenticationPolicy) *peerAuthenticationPolicy {
+	for _, policy := range policies {
+		if policy.selector == "" {
+			return policy
+		}
+	}
+	return nil
+}
+
+// matchingWorkloadPolicy returns the oldest workload-level policy whose selector matches the
+// workload, by name among policies of the same age or without creationTimestamp
+func matchingWorkloadPolicy(db *sql.DB, policies []*peerAuthenticationPolicy, namespace, workload string) (*peerAuthenticationPolicy, error) {
+	var name string
+	err := db.QueryRow(`
+		SELECT pa.name
+		FROM peer_authentications pa
+		JOIN workloads w ON w.namespace = pa.namespace
+		WHERE pa.selector <> '' AND w.namespace = ? AND w.name = ?
+		  AND `+selectorMatchesSQL("PeerAuthentication", "pa.namespace", "pa.name", "w")+`
+		ORDER BY pa.created_at = '', pa.created_at, pa.name
+		LIMIT 1
+	`, namespace, workload).Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, policy := range policies {
+		if policy.name == name {
+			return policy, nil
+		}
+	}
+	return nil, nil
+}
+
+func describePolicy(level string, policy *peerAuthenticationPolicy) string {
+	if policy == nil {
+		return level + " (none)"
+	}
+	return fmt.Sprintf("%s %s/%s=%s", level, policy.namespace, policy.name, policy.mode)
+}
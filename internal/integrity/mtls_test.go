@@ -0,0 +1,100 @@
+// Тесты для согласованности mTLS между PeerAuthentication и DestinationRule
+package integrity
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckMTLSViolations(t *testing.T) {
+	model, err := parseYAMLResources("testdata/mtls-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse YAML file: %v", err)
+	}
+
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	var portMode string
+	if err := db.QueryRow("SELECT mode FROM peer_authentication_ports WHERE namespace = 'prod' AND name = 'ratings-plaintext' AND port = 9080").Scan(&portMode); err != nil {
+		t.Fatalf("Failed to read portLevelMtls: %v", err)
+	}
+	if portMode != "PERMISSIVE" {
+		t.Errorf("Expected portLevelMtls PERMISSIVE, got %s", portMode)
+	}
+
+	var createdAt string
+	if err := db.QueryRow("SELECT created_at FROM peer_authentications WHERE namespace = 'prod' AND name = 'z-inventory-permissive'").Scan(&createdAt); err != nil {
+		t.Fatalf("Failed to read creationTimestamp: %v", err)
+	}
+	if createdAt != "2025-01-01T00:00:00Z" {
+		t.Errorf("Expected creationTimestamp 2025-01-01T00:00:00Z, got %q", createdAt)
+	}
+	policies, err := loadPeerAuthentications(db)
+	if err != nil {
+		t.Fatalf("Failed to load PeerAuthentications: %v", err)
+	}
+	server, err := resolveServerMTLS(db, policies, "prod", "inventory-0", 8080)
+	if err != nil {
+		t.Fatalf("Failed to resolve server mTLS: %v", err)
+	}
+	if server.mode != "PERMISSIVE" || !strings.Contains(server.chain, "workload prod/z-inventory-permissive=PERMISSIVE") {
+		t.Errorf("Expected the oldest workload policy to apply, got %s", server.chain)
+	}
+
+	violations, err := operator.checkMTLSViolations(db)
+	if err != nil {
+		t.Fatalf("Failed to check mTLS violations: %v", err)
+	}
+
+	found := map[string]int{}
+	for _, violation := range violations {
+		t.Logf("⚠️ Violation: %s %s - %s", violation.Type, violation.Resource, violation.Message)
+		if violation.Type != "MTLSViolation" {
+			t.Errorf("Expected violation type 'MTLSViolation', got '%s'", violation.Type)
+		}
+		found[violation.Resource]++
+
+		switch violation.Resource {
+		case "DestinationRule/prod/reviews":
+			if !strings.Contains(violation.Message, "reviews-v1, reviews-v2") {
+				t.Errorf("Expected both reviews workloads to be listed, got %q", violation.Message)
+			}
+			if !strings.Contains(violation.Message, "mesh istio-system/default=STRICT") {
+				t.Errorf("Expected mesh policy in the chain, got %q", violation.Message)
+			}
+		case "DestinationRule/prod/details":
+			if !strings.Contains(violation.Message, "SIMPLE on port 9090") {
+				t.Errorf("Expected only port 9090 to be reported, got %q", violation.Message)
+			}
+		case "DestinationRule/legacy/billing":
+			if !strings.Contains(violation.Message, "namespace legacy/default=DISABLE") {
+				t.Errorf("Expected namespace policy in the chain, got %q", violation.Message)
+			}
+		case "DestinationRule/prod/payments":
+			// Subset переопределяет верхний уровень только для своих workload
+			if !strings.Contains(violation.Message, "DISABLE on port 8080 of subset v1 conflicts with server mTLS STRICT of Service/prod/payments workloads (payments-v1)") &&
+				!strings.Contains(violation.Message, "DISABLE on port 8443 of subset v1 conflicts with server mTLS STRICT of Service/prod/payments workloads (payments-v1)") &&
+				!strings.Contains(violation.Message, "DISABLE on port 8443 of subset v2 conflicts with server mTLS STRICT of Service/prod/payments workloads (payments-v2)") {
+				t.Errorf("Unexpected subset violation %q", violation.Message)
+			}
+		default:
+			t.Errorf("Unexpected violation for %s", violation.Resource)
+		}
+	}
+
+	for resource, count := range map[string]int{
+		"DestinationRule/prod/reviews":   1,
+		"DestinationRule/prod/details":   1,
+		"DestinationRule/legacy/billing": 1,
+		"DestinationRule/prod/payments":  3,
+	} {
+		if found[resource] != count {
+			t.Errorf("Expected %d mTLS violation(s) for %s, got %d", count, resource, found[resource])
+		}
+	}
+}
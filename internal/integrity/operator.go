@@ -5,13 +5,20 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"sync/atomic"
 
 	_ "github.com/mattn/go-sqlite3"
 	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+	istio "istio.io/client-go/pkg/apis/networking/v1beta1"
+	security "istio.io/client-go/pkg/apis/security/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 )
 
 type SQLiteIntegrityOperator struct {
@@ -30,6 +37,44 @@ type RelationalModel struct {
 	VirtualServices  []VirtualServiceRecord
 	Gateways         []GatewayRecord
 	DestinationRules []DestinationRuleRecord
+	ServiceEntries   []ServiceEntryRecord
+
+	DestinationRuleSubsets []DestinationRuleSubsetRecord
+
+	VirtualServiceDestinations []VirtualServiceDestinationRecord
+	VirtualServiceDelegates    []VirtualServiceDelegateRecord
+	VirtualServiceHosts        []VirtualServiceHostRecord
+	VirtualServiceGateways     []VirtualServiceGatewayRecord
+	HTTPRoutes                 []HTTPRouteRecord
+	HTTPMatches                []HTTPMatchRecord
+	HTTPMatchHeaders           []HTTPMatchHeaderRecord
+	TrafficPolicies            []TrafficPolicyRecord
+
+	ServicePorts            []ServicePortRecord
+	Workloads               []WorkloadRecord
+	PeerAuthentications     []PeerAuthenticationRecord
+	PeerAuthenticationPorts []PeerAuthenticationPortRecord
+
+	Namespaces                    []NamespaceRecord
+	ServiceAccounts               []ServiceAccountRecord
+	AuthorizationPolicies         []AuthorizationPolicyRecord
+	AuthorizationPolicyRules      []AuthorizationPolicyRuleRecord
+	AuthorizationPolicySources    []AuthorizationPolicySourceRecord
+	AuthorizationPolicyOperations []AuthorizationPolicyOperationRecord
+
+	Sidecars           []SidecarRecord
+	SidecarEgressHosts []SidecarEgressHostRecord
+	MeshServices       []MeshServiceRecord
+	MeshServiceSubsets []MeshServiceSubsetRecord
+
+	// Kubernetes Gateway API (gateway.networking.k8s.io) живет рядом с Istio API во время миграции
+	GatewayAPIGateways        []GatewayAPIGatewayRecord
+	GatewayAPIListeners       []GatewayAPIListenerRecord
+	GatewayAPICertificateRefs []GatewayAPICertificateRefRecord
+	GatewayAPIRoutes          []GatewayAPIRouteRecord
+	GatewayAPIParentRefs      []GatewayAPIParentRefRecord
+	GatewayAPIBackendRefs     []GatewayAPIBackendRefRecord
+	ReferenceGrants           []ReferenceGrantRecord
 }
 
 type ServiceRecord struct {
@@ -38,6 +83,26 @@ type ServiceRecord struct {
 	Host      string
 	Port      int32
 	Protocol  string
+	ExportTo  string // annotation networking.istio.io/exportTo, через запятую
+	Selector  string // spec.selector в виде k1=v1,k2=v2
+}
+
+// ServicePortRecord is a single port of a Kubernetes Service
+type ServicePortRecord struct {
+	Namespace  string
+	Name       string
+	PortName   string
+	Port       int32
+	TargetPort int32 // 0, если targetPort задан именем
+	Protocol   string
+}
+
+// WorkloadRecord is a pod (or a pod template) that Services and policies select by labels
+type WorkloadRecord struct {
+	Namespace      string
+	Name           string
+	ServiceAccount string
+	Labels         string // k1=v1,k2=v2
 }
 
 type VirtualServiceRecord struct {
@@ -48,6 +113,79 @@ type VirtualServiceRecord struct {
 	Host             string
 	ServiceNamespace string
 	ServiceName      string
+	ExportTo         string
+}
+
+// VirtualServiceDestinationRecord is a single http[].route[] destination of a VirtualService
+type VirtualServiceDestinationRecord struct {
+	Namespace  string // namespace VirtualService
+	Name       string // имя VirtualService
+	HTTPIndex  int    // индекс в spec.http
+	RouteIndex int    // индекс в spec.http[].route
+	Host       string
+	Subset     string
+	Port       int32
+	Weight     int32 // 0, если weight не указан
+}
+
+// VirtualServiceDelegateRecord is an http[].delegate edge from a root VirtualService to a child
+type VirtualServiceDelegateRecord struct {
+	Namespace         string // namespace корневого VirtualService
+	Name              string // имя корневого VirtualService
+	HTTPIndex         int    // индекс в spec.http
+	DelegateNamespace string // namespace дочернего VirtualService, по умолчанию namespace корневого
+	DelegateName      string
+}
+
+// VirtualServiceHostRecord is a single spec.hosts entry of a VirtualService
+type VirtualServiceHostRecord struct {
+	Namespace string
+	Name      string
+	Host      string
+}
+
+// VirtualServiceGatewayRecord is a single spec.gateways entry; the reserved "mesh"
+// gateway has an empty GatewayNamespace
+type VirtualServiceGatewayRecord struct {
+	Namespace        string
+	Name             string
+	GatewayNamespace string
+	GatewayName      string
+}
+
+// HTTPRouteRecord is a single spec.http[] entry. A route without matches is a catch-all.
+type HTTPRouteRecord struct {
+	Namespace string
+	Name      string
+	HTTPIndex int
+	RouteName string
+	Matches   int // количество match[]
+}
+
+// HTTPMatchRecord is a single http[].match[] condition. Conditions the model does not
+// store (scheme, authority, port, queryParams, sourceLabels, ...) set HasOtherConditions.
+type HTTPMatchRecord struct {
+	Namespace          string
+	Name               string
+	HTTPIndex          int
+	MatchIndex         int
+	URIType            string // exact, prefix, regex или пусто
+	URI                string
+	IgnoreURICase      bool
+	MethodType         string
+	Method             string
+	HasOtherConditions bool
+}
+
+// HTTPMatchHeaderRecord is a headers condition of an http[].match[] entry
+type HTTPMatchHeaderRecord struct {
+	Namespace  string
+	Name       string
+	HTTPIndex  int
+	MatchIndex int
+	Header     string // в нижнем регистре
+	MatchType  string // exact, prefix, regex
+	Value      string
 }
 
 type GatewayRecord struct {
@@ -82,184 +220,1200 @@ type DestinationRuleRecord struct {
 	// Указание полного доменного имени (<service>.<namespace>.svc.cluster.local) — рекомендуемая практика.
 	Host string // host: reviews.prod.svc.cluster.local  # ← Kubernetes Service
 
-}
+	ExportTo         string
+	WorkloadSelector string // matchLabels в виде k1=v1,k2=v2 (отсортированы)
+	CreatedAt        string // creationTimestamp в RFC3339, Istio выбирает самый старый
+}
+
+// DestinationRuleSubsetRecord is a single spec.subsets[] entry of a DestinationRule
+type DestinationRuleSubsetRecord struct {
+	Namespace string // namespace DestinationRule
+	Name      string // имя DestinationRule
+	Subset    string
+	Labels    string // labels в виде k1=v1,k2=v2 (отсортированы)
+}
+
+// TrafficPolicyRecord is a structured DestinationRule traffic policy.
+// Subset is empty for the top-level policy, Port is 0 unless it comes from portLevelSettings.
+type TrafficPolicyRecord struct {
+	Namespace string // namespace DestinationRule
+	Name      string // имя DestinationRule
+	Subset    string
+	Port      int32
+
+	LoadBalancer   string // loadBalancer.simple: ROUND_ROBIN, LEAST_REQUEST, ...
+	ConsistentHash string // loadBalancer.consistentHash: httpHeaderName:x-user-id, useSourceIp, ...
+
+	MaxConnections           int32
+	HTTP1MaxPendingRequests  int32
+	HTTP2MaxRequests         int32
+	MaxRequestsPerConnection int32
+
+	Consecutive5xxErrors int32
+	Interval             string
+	BaseEjectionTime     string
+	MaxEjectionPercent   int32
+
+	TLSMode string // DISABLE, SIMPLE, MUTUAL, ISTIO_MUTUAL
+}
+
+// PeerAuthenticationRecord is the server-side mTLS policy. An empty Selector means
+// namespace-wide policy, or mesh-wide when it lives in the Istio root namespace.
+type PeerAuthenticationRecord struct {
+	Namespace string
+	Name      string
+	Selector  string // matchLabels в виде k1=v1,k2=v2
+	Mode      string // UNSET, DISABLE, PERMISSIVE, STRICT
+	CreatedAt string // creationTimestamp в RFC3339, из нескольких подходящих Istio применяет самую старую
+}
+
+// PeerAuthenticationPortRecord is a portLevelMtls entry, keyed by the workload (target) port
+type PeerAuthenticationPortRecord struct {
+	Namespace string
+	Name      string
+	Port      int32
+	Mode      string
+}
+
+// ServiceEntryRecord is a single host of an Istio ServiceEntry
+type ServiceEntryRecord struct {
+	Namespace string
+	Name      string
+	Host      string
+	Location  string // MESH_EXTERNAL, MESH_INTERNAL
+	ExportTo  string
+}
+
+// NamespaceRecord is a declared Kubernetes Namespace
+type NamespaceRecord struct {
+	Name string
+}
+
+// ServiceAccountRecord is a Kubernetes ServiceAccount, the identity behind mTLS principals
+type ServiceAccountRecord struct {
+	Namespace string
+	Name      string
+}
+
+// AuthorizationPolicyRecord is an Istio AuthorizationPolicy. An empty Selector applies the
+// policy to the whole namespace, or to the whole mesh in the Istio root namespace.
+type AuthorizationPolicyRecord struct {
+	Namespace string
+	Name      string
+	Action    string // ALLOW, DENY, AUDIT, CUSTOM
+	Selector  string // matchLabels в виде k1=v1,k2=v2
+	Rules     int    // количество spec.rules
+}
+
+// AuthorizationPolicyRuleRecord is a single spec.rules[] entry
+type AuthorizationPolicyRuleRecord struct {
+	Namespace  string
+	Name       string
+	RuleIndex  int
+	Sources    int // количество from[]
+	Operations int // количество to[]
+}
+
+// AuthorizationPolicySourceRecord is a single value of rules[].from[].source.
+// Principals of the form <trust-domain>/ns/<ns>/sa/<sa> are split into PrincipalNamespace and
+// PrincipalServiceAccount, other forms leave them empty.
+type AuthorizationPolicySourceRecord struct {
+	Namespace               string
+	Name                    string
+	RuleIndex               int
+	FromIndex               int
+	Field                   string // principals, notPrincipals, namespaces, notNamespaces
+	Value                   string
+	PrincipalNamespace      string
+	PrincipalServiceAccount string
+}
+
+// AuthorizationPolicyOperationRecord is a rules[].to[].operation, lists are comma-separated
+type AuthorizationPolicyOperationRecord struct {
+	Namespace string
+	Name      string
+	RuleIndex int
+	ToIndex   int
+	Hosts     string
+	Ports     string
+	Methods   string
+	Paths     string
+}
+
+// SidecarRecord is an Istio Sidecar. An empty WorkloadSelector makes it the namespace default,
+// or the mesh default in the Istio root namespace.
+type SidecarRecord struct {
+	Namespace        string
+	Name             string
+	WorkloadSelector string // labels в виде k1=v1,k2=v2
+	EgressListeners  int    // число spec.egress, без egress Sidecar импортирует весь mesh
+	CreatedAt        string // creationTimestamp в RFC3339, из нескольких Sidecar namespace Istio применяет самый старый
+}
+
+// SidecarEgressHostRecord is a single egress[].hosts entry in ns/dnsName form
+type SidecarEgressHostRecord struct {
+	Namespace     string // namespace Sidecar
+	Name          string // имя Sidecar
+	ListenerIndex int    // индекс в spec.egress
+	HostNamespace string // *, ., ~ или имя namespace
+	Host          string // FQDN, *.example.com или *
+}
+
+// MeshServiceRecord is a MeshService spec: the VirtualService it declares in its own
+// namespace routes to the Kubernetes Service ServiceNamespace/ServiceName
+type MeshServiceRecord struct {
+	Namespace        string
+	Name             string
+	ServiceNamespace string
+	ServiceName      string
+	Hosts            string // через запятую
+	GatewayNamespace string
+	GatewayName      string
+}
+
+// MeshServiceSubsetRecord is a single spec.subsets[] entry of a MeshService
+type MeshServiceSubsetRecord struct {
+	Namespace string // namespace MeshService
+	Name      string // имя MeshService
+	Subset    string
+	Labels    string // labels в виде k1=v1,k2=v2 (отсортированы)
+	Weight    int32  // 0, если weight не указан
+}
+
+// GatewayAPIGatewayRecord is a gateway.networking.k8s.io Gateway
+type GatewayAPIGatewayRecord struct {
+	Namespace    string
+	Name         string
+	GatewayClass string
+}
+
+// GatewayAPIListenerRecord is a single spec.listeners[] entry of a Gateway API Gateway
+type GatewayAPIListenerRecord struct {
+	Namespace         string // namespace Gateway
+	Name              string // имя Gateway
+	ListenerName      string
+	Port              int32
+	Protocol          string
+	Hostname          string
+	AllowedNamespaces string // Same, All, Selector
+	AllowedKinds      string // через запятую, пусто - любые kinds для протокола
+}
+
+// GatewayAPICertificateRefRecord is a listener tls.certificateRefs[] entry with defaults applied
+type GatewayAPICertificateRefRecord struct {
+	Namespace    string // namespace Gateway
+	Name         string // имя Gateway
+	ListenerName string
+	RefIndex     int
+	RefGroup     string // "" для core API
+	RefKind      string
+	RefNamespace string
+	RefName      string
+}
+
+// GatewayAPIRouteRecord is an HTTPRoute or GRPCRoute
+type GatewayAPIRouteRecord struct {
+	Kind      string // HTTPRoute, GRPCRoute
+	Namespace string
+	Name      string
+	Hostnames string // через запятую
+}
+
+// GatewayAPIParentRefRecord is a route parentRef with defaults applied
+type GatewayAPIParentRefRecord struct {
+	Kind            string // kind маршрута
+	Namespace       string // namespace маршрута
+	Name            string // имя маршрута
+	RefIndex        int
+	ParentGroup     string
+	ParentKind      string
+	ParentNamespace string
+	ParentName      string
+	SectionName     string // имя listener, пусто - любой
+	Port            int32  // 0 - любой
+}
+
+// GatewayAPIBackendRefRecord is a rules[].backendRefs[] entry of a route with defaults applied
+type GatewayAPIBackendRefRecord struct {
+	Kind             string // kind маршрута
+	Namespace        string // namespace маршрута
+	Name             string // имя маршрута
+	RuleIndex        int
+	RefIndex         int
+	BackendGroup     string // "" для core API
+	BackendKind      string
+	BackendNamespace string
+	BackendName      string
+	Port             int32
+	Weight           int32
+}
+
+// ReferenceGrantRecord is one from x to pair of a ReferenceGrant; ToName is empty when
+// the grant covers every object of the kind
+type ReferenceGrantRecord struct {
+	Namespace     string
+	Name          string
+	FromGroup     string
+	FromKind      string
+	FromNamespace string
+	ToGroup       string
+	ToKind        string
+	ToName        string
+}
+
+// IntegrityReport contains the results of consistency checks
+type IntegrityReport struct {
+	IsConsistent bool                               `json:"isConsistent"`
+	Violations   []meshv1alpha1.ConstraintViolation `json:"violations"`
+	RepairPlans  []meshv1alpha1.RepairAction        `json:"repairPlans"`
+}
+
+// DiffViolations returns the violations of after that are not in before, compared by
+// type, resource and message. Repeated violations are matched one to one.
+func DiffViolations(before, after []meshv1alpha1.ConstraintViolation) []meshv1alpha1.ConstraintViolation {
+	seen := map[string]int{}
+	for _, violation := range before {
+		seen[violationKey(violation)]++
+	}
+
+	var introduced []meshv1alpha1.ConstraintViolation
+	for _, violation := range after {
+		key := violationKey(violation)
+		if seen[key] > 0 {
+			seen[key]--
+			continue
+		}
+		introduced = append(introduced, violation)
+	}
+	return introduced
+}
+
+func violationKey(violation meshv1alpha1.ConstraintViolation) string {
+	return violation.Type + "\x00" + violation.Resource + "\x00" + violation.Message
+}
+
+// modelLists are the kinds BuildRelationalModel reads. Optional kinds come from CRDs
+// (Istio security, Gateway API) that may not be installed in the cluster.
+func modelLists() []struct {
+	kind     string
+	list     client.ObjectList
+	optional bool
+} {
+	return []struct {
+		kind     string
+		list     client.ObjectList
+		optional bool
+	}{
+		{"Namespace", &corev1.NamespaceList{}, false},
+		{"ServiceAccount", &corev1.ServiceAccountList{}, false},
+		{"Service", &corev1.ServiceList{}, false},
+		{"Pod", &corev1.PodList{}, false},
+		{"VirtualService", &istio.VirtualServiceList{}, false},
+		{"DestinationRule", &istio.DestinationRuleList{}, false},
+		{"Gateway", &istio.GatewayList{}, false},
+		{"ServiceEntry", &istio.ServiceEntryList{}, false},
+		{"Sidecar", &istio.SidecarList{}, false},
+		{"PeerAuthentication", &security.PeerAuthenticationList{}, true},
+		{"AuthorizationPolicy", &security.AuthorizationPolicyList{}, true},
+		{"MeshService", &meshv1alpha1.MeshServiceList{}, false},
+		{"Gateway." + gatewayAPIGroup, &gatewayv1.GatewayList{}, true},
+		{"HTTPRoute", &gatewayv1.HTTPRouteList{}, true},
+		{"GRPCRoute", &gatewayv1.GRPCRouteList{}, true},
+		{"ReferenceGrant", &gatewayv1beta1.ReferenceGrantList{}, true},
+	}
+}
+
+// BuildRelationalModel collects and transforms Kubernetes resources
+func (o *SQLiteIntegrityOperator) BuildRelationalModel(ctx context.Context) (*RelationalModel, error) {
+	log := log.FromContext(ctx)
+	model := &RelationalModel{}
+
+	for _, source := range modelLists() {
+		if err := o.client.List(ctx, source.list); err != nil {
+			// CRD не установлен или тип не зарегистрирован в scheme клиента
+			if source.optional && (meta.IsNoMatchError(err) || runtime.IsNotRegisteredError(err)) {
+				log.V(1).Info("Skipping kind not served by the cluster", "kind", source.kind)
+				continue
+			}
+			return nil, fmt.Errorf("failed to list %s: %w", source.kind, err)
+		}
+
+		items, err := meta.ExtractList(source.list)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s items: %w", source.kind, err)
+		}
+		for _, item := range items {
+			obj, ok := item.(client.Object)
+			if !ok {
+				continue
+			}
+			if err := model.AddObject(obj); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	log.Info("Built relational model",
+		"services", len(model.Services),
+		"virtualServices", len(model.VirtualServices),
+		"destinationRules", len(model.DestinationRules),
+		"gateways", len(model.Gateways),
+		"workloads", len(model.Workloads))
+	return model, nil
+}
+
+func (o *SQLiteIntegrityOperator) shouldProcessService(svc *corev1.Service) bool {
+	// В модель попадают только Service с аннотацией mesh.operator.istio.io/managed
+	return isMeshManagedService(svc)
+}
+
+// databaseSeq делает имя in-memory базы уникальным: с cache=shared базы с одинаковым
+// именем общие для всех соединений процесса, а webhook проверяет запросы параллельно
+var databaseSeq atomic.Uint64
+
+// CreateInMemoryDB creates SQLite in-memory database with schema
+func (o *SQLiteIntegrityOperator) CreateInMemoryDB(model *RelationalModel) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:integrity-%d?mode=memory&cache=shared", databaseSeq.Add(1)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Включаем foreign keys
+	if _, err := db.Exec("PRAGMA foreign_keys = ON;"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+	}
+
+	// Создаем схему
+	if err := o.createSchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// Загружаем данные
+	if err := o.loadData(db, model); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load data: %w", err)
+	}
+
+	return db, nil
+}
+
+func (o *SQLiteIntegrityOperator) createSchema(db *sql.DB) error {
+	schema := `
+    CREATE TABLE IF NOT EXISTS services (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        host TEXT NOT NULL, 
+        port INTEGER NOT NULL,
+        protocol TEXT NOT NULL,
+        export_to TEXT NOT NULL DEFAULT '',
+        selector TEXT NOT NULL DEFAULT '',
+        PRIMARY KEY (namespace, name)
+    );
+
+    CREATE TABLE IF NOT EXISTS service_ports (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        port_name TEXT NOT NULL DEFAULT '',
+        port INTEGER NOT NULL,
+        target_port INTEGER NOT NULL DEFAULT 0,
+        protocol TEXT NOT NULL DEFAULT 'TCP',
+        PRIMARY KEY (namespace, name, port),
+        FOREIGN KEY (namespace, name) 
+            REFERENCES services(namespace, name) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS workloads (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        service_account TEXT NOT NULL DEFAULT 'default',
+        PRIMARY KEY (namespace, name)
+    );
+
+    CREATE TABLE IF NOT EXISTS workload_labels (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        key TEXT NOT NULL,
+        value TEXT NOT NULL,
+        PRIMARY KEY (namespace, name, key),
+        FOREIGN KEY (namespace, name) 
+            REFERENCES workloads(namespace, name) ON DELETE CASCADE
+    );
+
+    -- label selectors всех ресурсов: kind + namespace + name владельца
+    CREATE TABLE IF NOT EXISTS selector_labels (
+        kind TEXT NOT NULL,
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        key TEXT NOT NULL,
+        value TEXT NOT NULL,
+        PRIMARY KEY (kind, namespace, name, key)
+    );
+
+    CREATE TABLE IF NOT EXISTS peer_authentications (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        selector TEXT NOT NULL DEFAULT '',
+        mode TEXT NOT NULL DEFAULT 'UNSET',
+        created_at TEXT NOT NULL DEFAULT '',
+        PRIMARY KEY (namespace, name)
+    );
+
+    CREATE TABLE IF NOT EXISTS peer_authentication_ports (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        port INTEGER NOT NULL,
+        mode TEXT NOT NULL,
+        PRIMARY KEY (namespace, name, port),
+        FOREIGN KEY (namespace, name) 
+            REFERENCES peer_authentications(namespace, name) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS namespaces (
+        name TEXT NOT NULL PRIMARY KEY
+    );
+
+    CREATE TABLE IF NOT EXISTS service_accounts (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        PRIMARY KEY (namespace, name)
+    );
+
+    CREATE TABLE IF NOT EXISTS authorization_policies (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        action TEXT NOT NULL DEFAULT 'ALLOW',
+        selector TEXT NOT NULL DEFAULT '',
+        rules INTEGER NOT NULL DEFAULT 0,
+        PRIMARY KEY (namespace, name)
+    );
+
+    CREATE TABLE IF NOT EXISTS authorization_policy_rules (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        rule_index INTEGER NOT NULL,
+        sources INTEGER NOT NULL DEFAULT 0,
+        operations INTEGER NOT NULL DEFAULT 0,
+        PRIMARY KEY (namespace, name, rule_index),
+        FOREIGN KEY (namespace, name) 
+            REFERENCES authorization_policies(namespace, name) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS authorization_policy_sources (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        rule_index INTEGER NOT NULL,
+        from_index INTEGER NOT NULL,
+        field TEXT NOT NULL,               -- principals, notPrincipals, namespaces, notNamespaces
+        value TEXT NOT NULL,
+        principal_namespace TEXT NOT NULL DEFAULT '',
+        principal_service_account TEXT NOT NULL DEFAULT '',
+        PRIMARY KEY (namespace, name, rule_index, from_index, field, value),
+        FOREIGN KEY (namespace, name, rule_index) 
+            REFERENCES authorization_policy_rules(namespace, name, rule_index) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS authorization_policy_operations (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        rule_index INTEGER NOT NULL,
+        to_index INTEGER NOT NULL,
+        hosts TEXT NOT NULL DEFAULT '',
+        ports TEXT NOT NULL DEFAULT '',
+        methods TEXT NOT NULL DEFAULT '',
+        paths TEXT NOT NULL DEFAULT '',
+        PRIMARY KEY (namespace, name, rule_index, to_index),
+        FOREIGN KEY (namespace, name, rule_index) 
+            REFERENCES authorization_policy_rules(namespace, name, rule_index) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS sidecars (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        workload_selector TEXT NOT NULL DEFAULT '',
+        egress_listeners INTEGER NOT NULL DEFAULT 0,  -- 0: egress не задан, импортируется весь mesh
+        created_at TEXT NOT NULL DEFAULT '',
+        PRIMARY KEY (namespace, name)
+    );
+
+    CREATE TABLE IF NOT EXISTS sidecar_egress_hosts (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        listener_index INTEGER NOT NULL,
+        host_namespace TEXT NOT NULL,     -- *, ., ~ или имя namespace
+        host TEXT NOT NULL,
+        PRIMARY KEY (namespace, name, listener_index, host_namespace, host),
+        FOREIGN KEY (namespace, name) 
+            REFERENCES sidecars(namespace, name) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS mesh_services (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        service_namespace TEXT NOT NULL,
+        service_name TEXT NOT NULL,
+        hosts TEXT NOT NULL DEFAULT '',
+        gateway_namespace TEXT NOT NULL DEFAULT '',
+        gateway_name TEXT NOT NULL DEFAULT '',
+        PRIMARY KEY (namespace, name)
+    );
+
+    CREATE TABLE IF NOT EXISTS mesh_service_subsets (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        subset TEXT NOT NULL,
+        labels TEXT NOT NULL DEFAULT '',
+        weight INTEGER NOT NULL DEFAULT 0,
+        PRIMARY KEY (namespace, name, subset),
+        FOREIGN KEY (namespace, name) 
+            REFERENCES mesh_services(namespace, name) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS gateway_api_gateways (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        gateway_class TEXT NOT NULL DEFAULT '',
+        PRIMARY KEY (namespace, name)
+    );
+
+    CREATE TABLE IF NOT EXISTS gateway_api_listeners (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        listener_name TEXT NOT NULL,
+        port INTEGER NOT NULL,
+        protocol TEXT NOT NULL,
+        hostname TEXT NOT NULL DEFAULT '',
+        allowed_namespaces TEXT NOT NULL DEFAULT 'Same',
+        allowed_kinds TEXT NOT NULL DEFAULT '',
+        PRIMARY KEY (namespace, name, listener_name),
+        FOREIGN KEY (namespace, name) 
+            REFERENCES gateway_api_gateways(namespace, name) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS gateway_api_certificate_refs (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        listener_name TEXT NOT NULL,
+        ref_index INTEGER NOT NULL,
+        ref_group TEXT NOT NULL DEFAULT '',
+        ref_kind TEXT NOT NULL DEFAULT 'Secret',
+        ref_namespace TEXT NOT NULL,
+        ref_name TEXT NOT NULL,
+        PRIMARY KEY (namespace, name, listener_name, ref_index),
+        FOREIGN KEY (namespace, name, listener_name) 
+            REFERENCES gateway_api_listeners(namespace, name, listener_name) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS gateway_api_routes (
+        kind TEXT NOT NULL,               -- HTTPRoute, GRPCRoute
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        hostnames TEXT NOT NULL DEFAULT '',
+        PRIMARY KEY (kind, namespace, name)
+    );
+
+    CREATE TABLE IF NOT EXISTS gateway_api_parent_refs (
+        kind TEXT NOT NULL,
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        ref_index INTEGER NOT NULL,
+        parent_group TEXT NOT NULL,
+        parent_kind TEXT NOT NULL,
+        parent_namespace TEXT NOT NULL,
+        parent_name TEXT NOT NULL,
+        section_name TEXT NOT NULL DEFAULT '',
+        port INTEGER NOT NULL DEFAULT 0,
+        PRIMARY KEY (kind, namespace, name, ref_index),
+        FOREIGN KEY (kind, namespace, name) 
+            REFERENCES gateway_api_routes(kind, namespace, name) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS gateway_api_backend_refs (
+        kind TEXT NOT NULL,
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        rule_index INTEGER NOT NULL,
+        ref_index INTEGER NOT NULL,
+        backend_group TEXT NOT NULL DEFAULT '',
+        backend_kind TEXT NOT NULL DEFAULT 'Service',
+        backend_namespace TEXT NOT NULL,
+        backend_name TEXT NOT NULL,
+        port INTEGER NOT NULL DEFAULT 0,
+        weight INTEGER NOT NULL DEFAULT 1,
+        PRIMARY KEY (kind, namespace, name, rule_index, ref_index),
+        FOREIGN KEY (kind, namespace, name) 
+            REFERENCES gateway_api_routes(kind, namespace, name) ON DELETE CASCADE
+    );
+
+    -- ReferenceGrant, развернутый в пары from x to
+    CREATE TABLE IF NOT EXISTS reference_grants (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        from_group TEXT NOT NULL,
+        from_kind TEXT NOT NULL,
+        from_namespace TEXT NOT NULL,
+        to_group TEXT NOT NULL,
+        to_kind TEXT NOT NULL,
+        to_name TEXT NOT NULL DEFAULT '',  -- '' для всех объектов kind
+        PRIMARY KEY (namespace, name, from_group, from_kind, from_namespace, to_group, to_kind, to_name)
+    );
+
+    CREATE TABLE IF NOT EXISTS gateways (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        PRIMARY KEY (namespace, name)
+    );
+
+    CREATE TABLE IF NOT EXISTS virtual_services (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        gateway_namespace TEXT NOT NULL,
+        gateway_name TEXT NOT NULL,
+        host TEXT NOT NULL,
+        service_namespace TEXT NOT NULL,
+        service_name TEXT NOT NULL,
+        export_to TEXT NOT NULL DEFAULT '',
+        PRIMARY KEY (namespace, name),
+        FOREIGN KEY (gateway_namespace, gateway_name) 
+            REFERENCES gateways(namespace, name) ON DELETE CASCADE,
+        FOREIGN KEY (service_namespace, service_name) 
+            REFERENCES services(namespace, name) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS destination_rules (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        service_namespace TEXT NOT NULL,  -- ссылается на k8s service
+        service_name TEXT NOT NULL,       -- ссылается на k8s service  
+        subsets TEXT,
+        traffic_policy TEXT,
+		host TEXT NOT NULL,               -- ссылается на k8s service 
+        canonical_host TEXT NOT NULL DEFAULT '',  -- host в виде FQDN относительно namespace правила
+        export_to TEXT NOT NULL DEFAULT '',
+        workload_selector TEXT NOT NULL DEFAULT '',
+        created_at TEXT NOT NULL DEFAULT '',
+        PRIMARY KEY (namespace, name),
+        FOREIGN KEY (service_namespace, service_name) 
+            REFERENCES services(namespace, name) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS destination_rule_subsets (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        subset TEXT NOT NULL,
+        labels TEXT NOT NULL DEFAULT '',
+        PRIMARY KEY (namespace, name, subset),
+        FOREIGN KEY (namespace, name) 
+            REFERENCES destination_rules(namespace, name) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS service_entries (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        host TEXT NOT NULL,
+        location TEXT NOT NULL DEFAULT '',
+        export_to TEXT NOT NULL DEFAULT '',
+        PRIMARY KEY (namespace, name, host)
+    );
+
+    CREATE TABLE IF NOT EXISTS virtual_service_destinations (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        http_index INTEGER NOT NULL,
+        route_index INTEGER NOT NULL,
+        host TEXT NOT NULL,
+        canonical_host TEXT NOT NULL,
+        subset TEXT NOT NULL DEFAULT '',
+        port INTEGER NOT NULL DEFAULT 0,
+        weight INTEGER NOT NULL DEFAULT 0,
+        PRIMARY KEY (namespace, name, http_index, route_index),
+        FOREIGN KEY (namespace, name) 
+            REFERENCES virtual_services(namespace, name) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS virtual_service_delegates (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        http_index INTEGER NOT NULL,
+        delegate_namespace TEXT NOT NULL,
+        delegate_name TEXT NOT NULL,
+        PRIMARY KEY (namespace, name, http_index),
+        FOREIGN KEY (namespace, name) 
+            REFERENCES virtual_services(namespace, name) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS virtual_service_hosts (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        host TEXT NOT NULL,
+        canonical_host TEXT NOT NULL,
+        PRIMARY KEY (namespace, name, host),
+        FOREIGN KEY (namespace, name) 
+            REFERENCES virtual_services(namespace, name) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS virtual_service_gateways (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        gateway_namespace TEXT NOT NULL,  -- '' для mesh
+        gateway_name TEXT NOT NULL,
+        PRIMARY KEY (namespace, name, gateway_namespace, gateway_name),
+        FOREIGN KEY (namespace, name) 
+            REFERENCES virtual_services(namespace, name) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS http_routes (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        http_index INTEGER NOT NULL,
+        route_name TEXT NOT NULL DEFAULT '',
+        matches INTEGER NOT NULL DEFAULT 0,
+        PRIMARY KEY (namespace, name, http_index),
+        FOREIGN KEY (namespace, name) 
+            REFERENCES virtual_services(namespace, name) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS http_matches (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        http_index INTEGER NOT NULL,
+        match_index INTEGER NOT NULL,
+        uri_type TEXT NOT NULL DEFAULT '',
+        uri TEXT NOT NULL DEFAULT '',
+        ignore_uri_case BOOLEAN NOT NULL DEFAULT 0,
+        method_type TEXT NOT NULL DEFAULT '',
+        method TEXT NOT NULL DEFAULT '',
+        has_other_conditions BOOLEAN NOT NULL DEFAULT 0,
+        PRIMARY KEY (namespace, name, http_index, match_index),
+        FOREIGN KEY (namespace, name, http_index) 
+            REFERENCES http_routes(namespace, name, http_index) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS http_match_headers (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        http_index INTEGER NOT NULL,
+        match_index INTEGER NOT NULL,
+        header TEXT NOT NULL,
+        match_type TEXT NOT NULL,
+        value TEXT NOT NULL DEFAULT '',
+        PRIMARY KEY (namespace, name, http_index, match_index, header),
+        FOREIGN KEY (namespace, name, http_index, match_index) 
+            REFERENCES http_matches(namespace, name, http_index, match_index) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS traffic_policies (
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        subset TEXT NOT NULL DEFAULT '',   -- '' для trafficPolicy верхнего уровня
+        port INTEGER NOT NULL DEFAULT 0,   -- 0, если не из portLevelSettings
+        lb_simple TEXT NOT NULL DEFAULT '',
+        lb_consistent_hash TEXT NOT NULL DEFAULT '',
+        max_connections INTEGER NOT NULL DEFAULT 0,
+        http1_max_pending_requests INTEGER NOT NULL DEFAULT 0,
+        http2_max_requests INTEGER NOT NULL DEFAULT 0,
+        max_requests_per_connection INTEGER NOT NULL DEFAULT 0,
+        outlier_consecutive_5xx_errors INTEGER NOT NULL DEFAULT 0,
+        outlier_interval TEXT NOT NULL DEFAULT '',
+        outlier_base_ejection_time TEXT NOT NULL DEFAULT '',
+        outlier_max_ejection_percent INTEGER NOT NULL DEFAULT 0,
+        tls_mode TEXT NOT NULL DEFAULT '',
+        PRIMARY KEY (namespace, name, subset, port),
+        FOREIGN KEY (namespace, name) 
+            REFERENCES destination_rules(namespace, name) ON DELETE CASCADE
+    );
+
+    -- exportTo, развернутый в строки: export_namespace = '*' для всех namespace,
+    -- '.' заменяется на namespace самого ресурса, '~' не дает ни одной строки
+    CREATE TABLE IF NOT EXISTS exports (
+        kind TEXT NOT NULL,
+        namespace TEXT NOT NULL,
+        name TEXT NOT NULL,
+        export_namespace TEXT NOT NULL,
+        PRIMARY KEY (kind, namespace, name, export_namespace)
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_vs_host_gateway 
+        ON virtual_services(host, gateway_namespace, gateway_name);
+	CREATE INDEX IF NOT EXISTS idx_services_host_port 
+		ON services(host, port);
+	CREATE INDEX IF NOT EXISTS idx_services_ns_name 
+		ON services(namespace, name);
+	CREATE INDEX IF NOT EXISTS idx_vs_svc_ref 
+		ON virtual_services(service_namespace, service_name);
+	CREATE INDEX IF NOT EXISTS idx_vs_host_gw 
+		ON virtual_services(host, gateway_namespace, gateway_name);
+	CREATE INDEX IF NOT EXISTS idx_dr_canonical_host 
+		ON destination_rules(canonical_host, namespace);
+    `
+
+	_, err := db.Exec(schema)
+	return err
+}
+
+func (o *SQLiteIntegrityOperator) loadData(db *sql.DB, model *RelationalModel) error {
+	if _, err := db.Exec("PRAGMA foreign_keys = OFF"); err != nil {
+		return fmt.Errorf("failed to disable foreign keys: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Загружаем данные и собираем ВСЕ нарушения
+	for _, gw := range model.Gateways {
+		if _, err := tx.Exec(
+			"INSERT INTO gateways (namespace, name) VALUES (?, ?)",
+			gw.Namespace, gw.Name,
+		); err != nil {
+			return err
+		}
+	}
+
+	for _, svc := range model.Services {
+		if _, err := tx.Exec(
+			"INSERT INTO services (namespace, name, host, port, protocol, export_to, selector) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			svc.Namespace, svc.Name, svc.Host, svc.Port, svc.Protocol, svc.ExportTo, svc.Selector,
+		); err != nil {
+			return err
+		}
+		if err := insertSelector(tx, "Service", svc.Namespace, svc.Name, svc.Selector); err != nil {
+			return err
+		}
+		if err := insertExports(tx, "Service", svc.Namespace, svc.Name, svc.ExportTo); err != nil {
+			return err
+		}
+	}
+
+	for _, vs := range model.VirtualServices {
+		if _, err := tx.Exec(
+			"INSERT INTO virtual_services (namespace, name, gateway_namespace, gateway_name, host, service_namespace, service_name, export_to) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			vs.Namespace, vs.Name, vs.GatewayNamespace, vs.GatewayName, vs.Host, vs.ServiceNamespace, vs.ServiceName, vs.ExportTo,
+		); err != nil {
+			return err
+		}
+		if err := insertExports(tx, "VirtualService", vs.Namespace, vs.Name, vs.ExportTo); err != nil {
+			return err
+		}
+	}
+
+	for _, dr := range model.DestinationRules {
+		if _, err := tx.Exec(
+			"INSERT INTO destination_rules (namespace, name, host, canonical_host, subsets, traffic_policy, service_namespace, service_name, export_to, workload_selector, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			dr.Namespace, dr.Name, dr.Host, canonicalHost(dr.Host, dr.Namespace), dr.Subsets, dr.TrafficPolicy, dr.ServiceNamespace, dr.ServiceName, dr.ExportTo, dr.WorkloadSelector, dr.CreatedAt,
+		); err != nil {
+			return err
+		}
+		if err := insertExports(tx, "DestinationRule", dr.Namespace, dr.Name, dr.ExportTo); err != nil {
+			return err
+		}
+	}
+
+	for _, subset := range model.DestinationRuleSubsets {
+		if _, err := tx.Exec(
+			"INSERT OR IGNORE INTO destination_rule_subsets (namespace, name, subset, labels) VALUES (?, ?, ?, ?)",
+			subset.Namespace, subset.Name, subset.Subset, subset.Labels,
+		); err != nil {
+			return err
+		}
+		if err := insertSelector(tx, "DestinationRuleSubset", subset.Namespace, subset.Name+"/"+subset.Subset, subset.Labels); err != nil {
+			return err
+		}
+	}
+
+	for _, se := range model.ServiceEntries {
+		if _, err := tx.Exec(
+			"INSERT INTO service_entries (namespace, name, host, location, export_to) VALUES (?, ?, ?, ?, ?)",
+			se.Namespace, se.Name, se.Host, se.Location, se.ExportTo,
+		); err != nil {
+			return err
+		}
+		if err := insertExports(tx, "ServiceEntry", se.Namespace, se.Name, se.ExportTo); err != nil {
+			return err
+		}
+	}
+
+	for _, dest := range model.VirtualServiceDestinations {
+		if _, err := tx.Exec(
+			"INSERT INTO virtual_service_destinations (namespace, name, http_index, route_index, host, canonical_host, subset, port, weight) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			dest.Namespace, dest.Name, dest.HTTPIndex, dest.RouteIndex, dest.Host, canonicalHost(dest.Host, dest.Namespace), dest.Subset, dest.Port, dest.Weight,
+		); err != nil {
+			return err
+		}
+	}
+
+	for _, delegate := range model.VirtualServiceDelegates {
+		if _, err := tx.Exec(
+			"INSERT INTO virtual_service_delegates (namespace, name, http_index, delegate_namespace, delegate_name) VALUES (?, ?, ?, ?, ?)",
+			delegate.Namespace, delegate.Name, delegate.HTTPIndex, delegate.DelegateNamespace, delegate.DelegateName,
+		); err != nil {
+			return err
+		}
+	}
+
+	for _, host := range model.VirtualServiceHosts {
+		if _, err := tx.Exec(
+			"INSERT OR IGNORE INTO virtual_service_hosts (namespace, name, host, canonical_host) VALUES (?, ?, ?, ?)",
+			host.Namespace, host.Name, host.Host, canonicalHost(host.Host, host.Namespace),
+		); err != nil {
+			return err
+		}
+	}
+
+	for _, gw := range model.VirtualServiceGateways {
+		if _, err := tx.Exec(
+			"INSERT OR IGNORE INTO virtual_service_gateways (namespace, name, gateway_namespace, gateway_name) VALUES (?, ?, ?, ?)",
+			gw.Namespace, gw.Name, gw.GatewayNamespace, gw.GatewayName,
+		); err != nil {
+			return err
+		}
+	}
+
+	for _, route := range model.HTTPRoutes {
+		if _, err := tx.Exec(
+			"INSERT INTO http_routes (namespace, name, http_index, route_name, matches) VALUES (?, ?, ?, ?, ?)",
+			route.Namespace, route.Name, route.HTTPIndex, route.RouteName, route.Matches,
+		); err != nil {
+			return err
+		}
+	}
+
+	for _, match := range model.HTTPMatches {
+		if _, err := tx.Exec(
+			"INSERT INTO http_matches (namespace, name, http_index, match_index, uri_type, uri, ignore_uri_case, method_type, method, has_other_conditions) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			match.Namespace, match.Name, match.HTTPIndex, match.MatchIndex, match.URIType, match.URI, match.IgnoreURICase, match.MethodType, match.Method, match.HasOtherConditions,
+		); err != nil {
+			return err
+		}
+	}
 
-// IntegrityReport contains the results of consistency checks
-type IntegrityReport struct {
-	IsConsistent bool
-	Violations   []meshv1alpha1.ConstraintViolation
-	RepairPlans  []meshv1alpha1.RepairAction
-}
+	for _, header := range model.HTTPMatchHeaders {
+		if _, err := tx.Exec(
+			"INSERT INTO http_match_headers (namespace, name, http_index, match_index, header, match_type, value) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			header.Namespace, header.Name, header.HTTPIndex, header.MatchIndex, header.Header, header.MatchType, header.Value,
+		); err != nil {
+			return err
+		}
+	}
 
-// BuildRelationalModel collects and transforms Kubernetes resources
-func (o *SQLiteIntegrityOperator) BuildRelationalModel(ctx context.Context) (*RelationalModel, error) {
-	log := log.FromContext(ctx)
-	model := &RelationalModel{}
+	for _, tp := range model.TrafficPolicies {
+		if _, err := tx.Exec(
+			`INSERT INTO traffic_policies (namespace, name, subset, port, lb_simple, lb_consistent_hash,
+				max_connections, http1_max_pending_requests, http2_max_requests, max_requests_per_connection,
+				outlier_consecutive_5xx_errors, outlier_interval, outlier_base_ejection_time, outlier_max_ejection_percent, tls_mode)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			tp.Namespace, tp.Name, tp.Subset, tp.Port, tp.LoadBalancer, tp.ConsistentHash,
+			tp.MaxConnections, tp.HTTP1MaxPendingRequests, tp.HTTP2MaxRequests, tp.MaxRequestsPerConnection,
+			tp.Consecutive5xxErrors, tp.Interval, tp.BaseEjectionTime, tp.MaxEjectionPercent, tp.TLSMode,
+		); err != nil {
+			return err
+		}
+	}
+
+	for _, port := range model.ServicePorts {
+		if _, err := tx.Exec(
+			"INSERT INTO service_ports (namespace, name, port_name, port, target_port, protocol) VALUES (?, ?, ?, ?, ?, ?)",
+			port.Namespace, port.Name, port.PortName, port.Port, port.TargetPort, port.Protocol,
+		); err != nil {
+			return err
+		}
+	}
 
-	// Collect all services
-	var services corev1.ServiceList
-	if err := o.client.List(ctx, &services); err != nil {
-		return nil, fmt.Errorf("failed to list services: %w", err)
-	}
-
-	for _, svc := range services.Items {
-		// Only process services with specific annotations or labels
-		if o.shouldProcessService(&svc) {
-			for _, port := range svc.Spec.Ports {
-				model.Services = append(model.Services, ServiceRecord{
-					Namespace: svc.Namespace,
-					Name:      svc.Name,
-					Host:      fmt.Sprintf("%s.%s.svc.cluster.local", svc.Name, svc.Namespace),
-					Port:      port.Port,
-					Protocol:  string(port.Protocol),
-				})
+	for _, wl := range model.Workloads {
+		if _, err := tx.Exec(
+			"INSERT INTO workloads (namespace, name, service_account) VALUES (?, ?, ?)",
+			wl.Namespace, wl.Name, wl.ServiceAccount,
+		); err != nil {
+			return err
+		}
+		for key, value := range splitLabels(wl.Labels) {
+			if _, err := tx.Exec(
+				"INSERT INTO workload_labels (namespace, name, key, value) VALUES (?, ?, ?, ?)",
+				wl.Namespace, wl.Name, key, value,
+			); err != nil {
+				return err
 			}
 		}
 	}
 
-	log.Info("Built relational model", "services", len(model.Services))
-	return model, nil
-}
+	for _, pa := range model.PeerAuthentications {
+		if _, err := tx.Exec(
+			"INSERT INTO peer_authentications (namespace, name, selector, mode, created_at) VALUES (?, ?, ?, ?, ?)",
+			pa.Namespace, pa.Name, pa.Selector, pa.Mode, pa.CreatedAt,
+		); err != nil {
+			return err
+		}
+		if err := insertSelector(tx, "PeerAuthentication", pa.Namespace, pa.Name, pa.Selector); err != nil {
+			return err
+		}
+	}
 
-func (o *SQLiteIntegrityOperator) shouldProcessService(svc *corev1.Service) bool {
-	// Add your logic to determine which services to process
-	// For example, check for specific annotations
-	_, hasMeshAnnotation := svc.Annotations["mesh.operator.istio.io/managed"]
-	return hasMeshAnnotation
-}
+	for _, port := range model.PeerAuthenticationPorts {
+		if _, err := tx.Exec(
+			"INSERT INTO peer_authentication_ports (namespace, name, port, mode) VALUES (?, ?, ?, ?)",
+			port.Namespace, port.Name, port.Port, port.Mode,
+		); err != nil {
+			return err
+		}
+	}
 
-// CreateInMemoryDB creates SQLite in-memory database with schema
-func (o *SQLiteIntegrityOperator) CreateInMemoryDB(model *RelationalModel) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+	for _, ns := range model.Namespaces {
+		if _, err := tx.Exec("INSERT INTO namespaces (name) VALUES (?)", ns.Name); err != nil {
+			return err
+		}
 	}
 
-	// Включаем foreign keys
-	if _, err := db.Exec("PRAGMA foreign_keys = ON;"); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+	for _, sa := range model.ServiceAccounts {
+		if _, err := tx.Exec(
+			"INSERT INTO service_accounts (namespace, name) VALUES (?, ?)",
+			sa.Namespace, sa.Name,
+		); err != nil {
+			return err
+		}
 	}
 
-	// Создаем схему
-	if err := o.createSchema(db); err != nil {
-		db.Close()
-		return nil, err
+	for _, ap := range model.AuthorizationPolicies {
+		if _, err := tx.Exec(
+			"INSERT INTO authorization_policies (namespace, name, action, selector, rules) VALUES (?, ?, ?, ?, ?)",
+			ap.Namespace, ap.Name, ap.Action, ap.Selector, ap.Rules,
+		); err != nil {
+			return err
+		}
+		if err := insertSelector(tx, "AuthorizationPolicy", ap.Namespace, ap.Name, ap.Selector); err != nil {
+			return err
+		}
 	}
 
-	// Загружаем данные
-	if err := o.loadData(db, model); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to load data: %w", err)
+	for _, rule := range model.AuthorizationPolicyRules {
+		if _, err := tx.Exec(
+			"INSERT INTO authorization_policy_rules (namespace, name, rule_index, sources, operations) VALUES (?, ?, ?, ?, ?)",
+			rule.Namespace, rule.Name, rule.RuleIndex, rule.Sources, rule.Operations,
+		); err != nil {
+			return err
+		}
 	}
 
-	return db, nil
-}
+	for _, source := range model.AuthorizationPolicySources {
+		if _, err := tx.Exec(
+			"INSERT OR IGNORE INTO authorization_policy_sources (namespace, name, rule_index, from_index, field, value, principal_namespace, principal_service_account) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			source.Namespace, source.Name, source.RuleIndex, source.FromIndex, source.Field, source.Value, source.PrincipalNamespace, source.PrincipalServiceAccount,
+		); err != nil {
+			return err
+		}
+	}
 
-func (o *SQLiteIntegrityOperator) createSchema(db *sql.DB) error {
-	schema := `
-    CREATE TABLE IF NOT EXISTS services (
-        namespace TEXT NOT NULL,
-        name TEXT NOT NULL,
-        host TEXT NOT NULL, 
-        port INTEGER NOT NULL,
-        protocol TEXT NOT NULL,
-        PRIMARY KEY (namespace, name)
-    );
+	for _, op := range model.AuthorizationPolicyOperations {
+		if _, err := tx.Exec(
+			"INSERT INTO authorization_policy_operations (namespace, name, rule_index, to_index, hosts, ports, methods, paths) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			op.Namespace, op.Name, op.RuleIndex, op.ToIndex, op.Hosts, op.Ports, op.Methods, op.Paths,
+		); err != nil {
+			return err
+		}
+	}
 
-    CREATE TABLE IF NOT EXISTS gateways (
-        namespace TEXT NOT NULL,
-        name TEXT NOT NULL,
-        PRIMARY KEY (namespace, name)
-    );
+	for _, sc := range model.Sidecars {
+		if _, err := tx.Exec(
+			"INSERT INTO sidecars (namespace, name, workload_selector, egress_listeners, created_at) VALUES (?, ?, ?, ?, ?)",
+			sc.Namespace, sc.Name, sc.WorkloadSelector, sc.EgressListeners, sc.CreatedAt,
+		); err != nil {
+			return err
+		}
+		if err := insertSelector(tx, "Sidecar", sc.Namespace, sc.Name, sc.WorkloadSelector); err != nil {
+			return err
+		}
+	}
 
-    CREATE TABLE IF NOT EXISTS virtual_services (
-        namespace TEXT NOT NULL,
-        name TEXT NOT NULL,
-        gateway_namespace TEXT NOT NULL,
-        gateway_name TEXT NOT NULL,
-        host TEXT NOT NULL,
-        service_namespace TEXT NOT NULL,
-        service_name TEXT NOT NULL,
-        PRIMARY KEY (namespace, name),
-        FOREIGN KEY (gateway_namespace, gateway_name) 
-            REFERENCES gateways(namespace, name) ON DELETE CASCADE,
-        FOREIGN KEY (service_namespace, service_name) 
-            REFERENCES services(namespace, name) ON DELETE CASCADE
-    );
+	for _, host := range model.SidecarEgressHosts {
+		if _, err := tx.Exec(
+			"INSERT OR IGNORE INTO sidecar_egress_hosts (namespace, name, listener_index, host_namespace, host) VALUES (?, ?, ?, ?, ?)",
+			host.Namespace, host.Name, host.ListenerIndex, host.HostNamespace, host.Host,
+		); err != nil {
+			return err
+		}
+	}
 
-    CREATE TABLE IF NOT EXISTS destination_rules (
-        namespace TEXT NOT NULL,
-        name TEXT NOT NULL,
-        service_namespace TEXT NOT NULL,  -- ссылается на k8s service
-        service_name TEXT NOT NULL,       -- ссылается на k8s service  
-        subsets TEXT,
-        traffic_policy TEXT,
-		host TEXT NOT NULL,               -- ссылается на k8s service 
-        PRIMARY KEY (namespace, name),
-        FOREIGN KEY (service_namespace, service_name) 
-            REFERENCES services(namespace, name) ON DELETE CASCADE
-    );
+	for _, ms := range model.MeshServices {
+		if _, err := tx.Exec(
+			"INSERT INTO mesh_services (namespace, name, service_namespace, service_name, hosts, gateway_namespace, gateway_name) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			ms.Namespace, ms.Name, ms.ServiceNamespace, ms.ServiceName, ms.Hosts, ms.GatewayNamespace, ms.GatewayName,
+		); err != nil {
+			return err
+		}
+	}
 
-    CREATE INDEX IF NOT EXISTS idx_vs_host_gateway 
-        ON virtual_services(host, gateway_namespace, gateway_name);
-	CREATE INDEX IF NOT EXISTS idx_services_host_port 
-		ON services(host, port);
-	CREATE INDEX IF NOT EXISTS idx_services_ns_name 
-		ON services(namespace, name);
-	CREATE INDEX IF NOT EXISTS idx_vs_svc_ref 
-		ON virtual_services(service_namespace, service_name);
-	CREATE INDEX IF NOT EXISTS idx_vs_host_gw 
-		ON virtual_services(host, gateway_namespace, gateway_name);
-    `
+	for _, subset := range model.MeshServiceSubsets {
+		if _, err := tx.Exec(
+			"INSERT OR IGNORE INTO mesh_service_subsets (namespace, name, subset, labels, weight) VALUES (?, ?, ?, ?, ?)",
+			subset.Namespace, subset.Name, subset.Subset, subset.Labels, subset.Weight,
+		); err != nil {
+			return err
+		}
+		if err := insertSelector(tx, "MeshServiceSubset", subset.Namespace, subset.Name+"/"+subset.Subset, subset.Labels); err != nil {
+			return err
+		}
+	}
 
-	_, err := db.Exec(schema)
-	return err
-}
+	for _, gw := range model.GatewayAPIGateways {
+		if _, err := tx.Exec(
+			"INSERT INTO gateway_api_gateways (namespace, name, gateway_class) VALUES (?, ?, ?)",
+			gw.Namespace, gw.Name, gw.GatewayClass,
+		); err != nil {
+			return err
+		}
+	}
 
-func (o *SQLiteIntegrityOperator) loadData(db *sql.DB, model *RelationalModel) error {
-	if _, err := db.Exec("PRAGMA foreign_keys = OFF"); err != nil {
-		return fmt.Errorf("failed to disable foreign keys: %w", err)
+	for _, listener := range model.GatewayAPIListeners {
+		if _, err := tx.Exec(
+			"INSERT INTO gateway_api_listeners (namespace, name, listener_name, port, protocol, hostname, allowed_namespaces, allowed_kinds) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			listener.Namespace, listener.Name, listener.ListenerName, listener.Port, listener.Protocol, listener.Hostname, listener.AllowedNamespaces, listener.AllowedKinds,
+		); err != nil {
+			return err
+		}
 	}
 
-	tx, err := db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+	for _, ref := range model.GatewayAPICertificateRefs {
+		if _, err := tx.Exec(
+			"INSERT INTO gateway_api_certificate_refs (namespace, name, listener_name, ref_index, ref_group, ref_kind, ref_namespace, ref_name) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			ref.Namespace, ref.Name, ref.ListenerName, ref.RefIndex, ref.RefGroup, ref.RefKind, ref.RefNamespace, ref.RefName,
+		); err != nil {
+			return err
+		}
 	}
-	defer tx.Rollback()
 
-	// Загружаем данные и собираем ВСЕ нарушения
-	for _, gw := range model.Gateways {
+	for _, route := range model.GatewayAPIRoutes {
 		if _, err := tx.Exec(
-			"INSERT INTO gateways (namespace, name) VALUES (?, ?)",
-			gw.Namespace, gw.Name,
+			"INSERT INTO gateway_api_routes (kind, namespace, name, hostnames) VALUES (?, ?, ?, ?)",
+			route.Kind, route.Namespace, route.Name, route.Hostnames,
 		); err != nil {
 			return err
 		}
 	}
 
-	for _, svc := range model.Services {
+	for _, ref := range model.GatewayAPIParentRefs {
 		if _, err := tx.Exec(
-			"INSERT INTO services (namespace, name, host, port, protocol) VALUES (?, ?, ?, ?, ?)",
-			svc.Namespace, svc.Name, svc.Host, svc.Port, svc.Protocol,
+			"INSERT INTO gateway_api_parent_refs (kind, namespace, name, ref_index, parent_group, parent_kind, parent_namespace, parent_name, section_name, port) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			ref.Kind, ref.Namespace, ref.Name, ref.RefIndex, ref.ParentGroup, ref.ParentKind, ref.ParentNamespace, ref.ParentName, ref.SectionName, ref.Port,
 		); err != nil {
 			return err
 		}
 	}
 
-	for _, vs := range model.VirtualServices {
+	for _, ref := range model.GatewayAPIBackendRefs {
 		if _, err := tx.Exec(
-			"INSERT INTO virtual_services (namespace, name, gateway_namespace, gateway_name, host, service_namespace, service_name) VALUES (?, ?, ?, ?, ?, ?, ?)",
-			vs.Namespace, vs.Name, vs.GatewayNamespace, vs.GatewayName, vs.Host, vs.ServiceNamespace, vs.ServiceName,
+			"INSERT INTO gateway_api_backend_refs (kind, namespace, name, rule_index, ref_index, backend_group, backend_kind, backend_namespace, backend_name, port, weight) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			ref.Kind, ref.Namespace, ref.Name, ref.RuleIndex, ref.RefIndex, ref.BackendGroup, ref.BackendKind, ref.BackendNamespace, ref.BackendName, ref.Port, ref.Weight,
 		); err != nil {
 			return err
 		}
 	}
 
-	for _, dr := range model.DestinationRules {
+	for _, grant := range model.ReferenceGrants {
 		if _, err := tx.Exec(
-			"INSERT INTO destination_rules (namespace, name, host, subsets, service_namespace, service_name) VALUES (?, ?, ?, ?, ?, ?)",
-			dr.Namespace, dr.Name, dr.Host, dr.Subsets, dr.ServiceNamespace, dr.ServiceName,
+			"INSERT OR IGNORE INTO reference_grants (namespace, name, from_group, from_kind, from_namespace, to_group, to_kind, to_name) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			grant.Namespace, grant.Name, grant.FromGroup, grant.FromKind, grant.FromNamespace, grant.ToGroup, grant.ToKind, grant.ToName,
 		); err != nil {
 			return err
 		}
@@ -296,6 +1450,69 @@ func (o *SQLiteIntegrityOperator) CheckIntegrity(db *sql.DB) (*IntegrityReport,
 	}
 	report.Violations = append(report.Violations, uniqueViolations...)
 
+	// 3. Check exportTo visibility of cross-namespace references
+	visibilityViolations, err := o.checkVisibilityViolations(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check visibility violations: %w", err)
+	}
+	report.Violations = append(report.Violations, visibilityViolations...)
+
+	// 4. Check DestinationRule traffic policy contradictions
+	trafficPolicyViolations, err := o.checkTrafficPolicyViolations(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check traffic policy violations: %w", err)
+	}
+	report.Violations = append(report.Violations, trafficPolicyViolations...)
+
+	// 5. Check client (DestinationRule) against server (PeerAuthentication) mTLS
+	mtlsViolations, err := o.checkMTLSViolations(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check mTLS violations: %w", err)
+	}
+	report.Violations = append(report.Violations, mtlsViolations...)
+
+	// 6. Check AuthorizationPolicy references and effective deny-all
+	authorizationViolations, err := o.checkAuthorizationPolicyViolations(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check authorization policy violations: %w", err)
+	}
+	report.Violations = append(report.Violations, authorizationViolations...)
+
+	// 7. Check that Sidecar egress hosts admit declared service dependencies
+	sidecarViolations, err := o.checkSidecarEgressViolations(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check sidecar egress violations: %w", err)
+	}
+	report.Violations = append(report.Violations, sidecarViolations...)
+
+	// 8. Check Gateway API references (parentRefs, backendRefs, ReferenceGrant)
+	gatewayAPIViolations, err := o.checkGatewayAPIViolations(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check Gateway API violations: %w", err)
+	}
+	report.Violations = append(report.Violations, gatewayAPIViolations...)
+
+	// 9. Check VirtualService delegate references, cycles and depth
+	delegateViolations, err := o.checkDelegateViolations(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check delegate violations: %w", err)
+	}
+	report.Violations = append(report.Violations, delegateViolations...)
+
+	// 10. Check shadowed HTTP routes and overlaps between merged VirtualServices
+	routeViolations, err := o.checkHTTPRouteViolations(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check HTTP route violations: %w", err)
+	}
+	report.Violations = append(report.Violations, routeViolations...)
+
+	// 11. Check weighted splits of VirtualService routes and MeshService subsets
+	weightViolations, err := o.checkWeightViolations(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check weight violations: %w", err)
+	}
+	report.Violations = append(report.Violations, weightViolations...)
+
 	// Final consistency flag
 	report.IsConsistent = len(report.Violations) == 0
 	return report, nil
@@ -305,12 +1522,12 @@ func (o *SQLiteIntegrityOperator) CheckIntegrity(db *sql.DB) (*IntegrityReport,
 func (o *SQLiteIntegrityOperator) checkForeignKeyViolations(db *sql.DB) ([]meshv1alpha1.ConstraintViolation, error) {
 	var violations []meshv1alpha1.ConstraintViolation
 
-	// 1. VirtualService -> Gateway
+	// 1. VirtualService -> Gateway (mesh-only и delegate VirtualService без gateways пропускаются)
 	rows, err := db.Query(`
 		SELECT vs.namespace, vs.name, vs.gateway_namespace, vs.gateway_name
 		FROM virtual_services vs
 		LEFT JOIN gateways gw ON vs.gateway_namespace = gw.namespace AND vs.gateway_name = gw.name
-		WHERE gw.namespace IS NULL
+		WHERE vs.gateway_name <> '' AND gw.namespace IS NULL
 	`)
 	if err != nil {
 		return nil, err
@@ -330,12 +1547,12 @@ func (o *SQLiteIntegrityOperator) checkForeignKeyViolations(db *sql.DB) ([]meshv
 	}
 	rows.Close()
 
-	// 2. VirtualService -> Service
+	// 2. VirtualService -> Service (корневой VirtualService с delegate может не иметь route)
 	rows, err = db.Query(`
 		SELECT vs.namespace, vs.name, vs.service_namespace, vs.service_name
 		FROM virtual_services vs
 		LEFT JOIN services s ON vs.service_namespace = s.namespace AND vs.service_name = s.name
-		WHERE s.namespace IS NULL
+		WHERE vs.service_name <> '' AND s.namespace IS NULL
 	`)
 	if err != nil {
 		return nil, err
@@ -380,7 +1597,43 @@ func (o *SQLiteIntegrityOperator) checkForeignKeyViolations(db *sql.DB) ([]meshv
 	}
 	rows.Close()
 
-	// 4. (Опционально) DestinationRule -> Service by host?
+	// 4. destination.port VirtualService -> порт Service (если порты сервиса известны)
+	rows, err = db.Query(`
+		SELECT DISTINCT d.namespace, d.name, d.http_index, d.route_index, d.port, s.namespace, s.name
+		FROM virtual_service_destinations d
+		JOIN services s ON lower(s.host) = d.canonical_host
+		WHERE d.port <> 0
+		  AND EXISTS (
+			SELECT 1 FROM service_ports sp
+			WHERE sp.namespace = s.namespace AND sp.name = s.name
+		  )
+		  AND NOT EXISTS (
+			SELECT 1 FROM service_ports sp
+			WHERE sp.namespace = s.namespace AND sp.name = s.name AND sp.port = d.port
+		  )
+		ORDER BY d.namespace, d.name, d.http_index, d.route_index
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var ns, name, svcNs, svcName string
+		var httpIndex, routeIndex int
+		var port int32
+		if err := rows.Scan(&ns, &name, &httpIndex, &routeIndex, &port, &svcNs, &svcName); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "ForeignKeyViolation",
+			Resource: fmt.Sprintf("VirtualService/%s/%s", ns, name),
+			Message:  fmt.Sprintf("http[%d].route[%d] references port %d not exposed by Service/%s/%s", httpIndex, routeIndex, port, svcNs, svcName),
+			Severity: "Error",
+		})
+	}
+	rows.Close()
+
+	// 5. (Опционально) DestinationRule -> Service by host?
 	// ❌ Рекомендуется УДАЛИТЬ из схемы FOREIGN KEY (host) REFERENCES services(host)
 	// Потому что host — не ключ, и может быть несколько сервисов с одним host? (в норме — нет)
 	// Но если вы всё же хотите проверить:
@@ -401,12 +1654,26 @@ func (o *SQLiteIntegrityOperator) checkForeignKeyViolations(db *sql.DB) ([]meshv
 func (o *SQLiteIntegrityOperator) checkUniqueConstraintViolations(db *sql.DB) ([]meshv1alpha1.ConstraintViolation, error) {
 	var violations []meshv1alpha1.ConstraintViolation
 
-	// 1. Дубликаты host:port в services
+	// 1. Дубликаты host:port в services по всем портам service_ports, services.port - только
+	// первый порт и используется для Service без service_ports
 	rows, err := db.Query(`
+		WITH ports(namespace, name, host, port) AS (
+			SELECT s.namespace, s.name, s.host, sp.port
+			FROM services s
+			JOIN service_ports sp ON sp.namespace = s.namespace AND sp.name = s.name
+			UNION
+			SELECT s.namespace, s.name, s.host, s.port
+			FROM services s
+			WHERE NOT EXISTS (
+				SELECT 1 FROM service_ports sp
+				WHERE sp.namespace = s.namespace AND sp.name = s.name
+			)
+		)
 		SELECT host, port, COUNT(*) as count
-		FROM services
+		FROM ports
 		GROUP BY host, port
 		HAVING COUNT(*) > 1
+		ORDER BY host, port
 	`)
 	if err != nil {
 		return nil, err
@@ -483,12 +1750,20 @@ func (o *SQLiteIntegrityOperator) checkUniqueConstraintViolations(db *sql.DB) ([
 	}
 	rows.Close()
 
+	// 4. Несколько DestinationRule для одного host
+	drViolations, err := o.checkDestinationRuleHostConflicts(db)
+	if err != nil {
+		return nil, err
+	}
+	violations = append(violations, drViolations...)
+
 	return violations, nil
 }
 
 // ComputeRepairPlans generates repair actions based on violations
 func (o *SQLiteIntegrityOperator) ComputeRepairPlans(db *sql.DB, report *IntegrityReport) ([]meshv1alpha1.RepairAction, error) {
 	var repairs []meshv1alpha1.RepairAction
+	missingGrants := false
 
 	for _, violation := range report.Violations {
 		switch violation.Type {
@@ -512,7 +1787,18 @@ func (o *SQLiteIntegrityOperator) ComputeRepairPlans(db *sql.DB, report *Integri
 				Action:   "Resolve host:port conflict",
 				Reason:   violation.Message,
 			})
+		case "ReferenceGrantViolation":
+			missingGrants = true
+		}
+	}
+
+	// Несколько ссылок закрываются одним ReferenceGrant, поэтому план строится по модели
+	if missingGrants {
+		grantRepairs, err := referenceGrantRepairs(db)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute ReferenceGrant repairs: %w", err)
 		}
+		repairs = append(repairs, grantRepairs...)
 	}
 
 	return repairs, nil
@@ -0,0 +1,82 @@
+package integrity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Overlay is a set of local changes on top of a base model: objects of Apply replace the
+// objects with the same kind, namespace and name or are added, objects of Delete are removed
+type Overlay struct {
+	Apply  []client.Object
+	Delete []client.Object
+}
+
+// OverlayReport lists the violations the overlay introduces and the repair plans for them
+type OverlayReport struct {
+	// Report holds only the introduced violations
+	Report *IntegrityReport
+	// Resolved are violations of the base the overlay removes
+	Resolved []meshv1alpha1.ConstraintViolation
+}
+
+// AnalyzeOverlay проверяет базовую модель и модель с наложенными изменениями и возвращает
+// только нарушения, которые появились после наложения. Базовая модель не изменяется.
+func (o *SQLiteIntegrityOperator) AnalyzeOverlay(ctx context.Context, base *RelationalModel, overlay Overlay) (*OverlayReport, error) {
+	db, err := o.CreateInMemoryDB(base)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return o.AnalyzeOverlayDB(ctx, db, overlay)
+}
+
+// AnalyzeOverlayDB накладывает изменения на копию базы, например импортированного снимка.
+// Объекты Apply загружаются как манифесты: Service без аннотации тоже попадают в модель.
+func (o *SQLiteIntegrityOperator) AnalyzeOverlayDB(ctx context.Context, db *sql.DB, overlay Overlay) (*OverlayReport, error) {
+	before, err := o.CheckIntegrity(db)
+	if err != nil {
+		return nil, err
+	}
+
+	copied, err := o.CopyDB(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer copied.Close()
+
+	// Строки заменяемых объектов удаляются так же, как строки удаляемых
+	part := &RelationalModel{}
+	for _, obj := range append(slices.Clip(overlay.Delete), overlay.Apply...) {
+		if !IsModelObject(obj) {
+			return nil, fmt.Errorf("unsupported object type %T", obj)
+		}
+		if err := o.applyMutation(ctx, copied, Mutation{Operation: MutationDelete, Object: obj}); err != nil {
+			return nil, fmt.Errorf("failed to remove %s: %w", ObjectResource(obj), err)
+		}
+	}
+	for _, obj := range overlay.Apply {
+		if err := part.AddManifestObject(obj); err != nil {
+			return nil, err
+		}
+	}
+	if err := o.loadData(copied, part); err != nil {
+		return nil, fmt.Errorf("failed to load overlay: %w", err)
+	}
+
+	after, err := o.CheckIntegrity(copied)
+	if err != nil {
+		return nil, err
+	}
+	introduced := DiffViolations(before.Violations, after.Violations)
+	report := &IntegrityReport{IsConsistent: len(introduced) == 0, Violations: introduced}
+	if report.RepairPlans, err = o.ComputeRepairPlans(copied, report); err != nil {
+		return nil, err
+	}
+	return &OverlayReport{Report: report, Resolved: DiffViolations(after.Violations, before.Violations)}, nil
+}
@@ -0,0 +1,83 @@
+// Тесты наложения локальных изменений на живое состояние
+package integrity
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	networking "istio.io/api/networking/v1beta1"
+	istio "istio.io/client-go/pkg/apis/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestAnalyzeOverlay(t *testing.T) {
+	base, err := parseYAMLResources("testdata/invalid-mesh-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse YAML file: %v", err)
+	}
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(base)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+	baseReport, err := operator.CheckIntegrity(db)
+	if err != nil {
+		t.Fatalf("Integrity check failed: %v", err)
+	}
+	if len(baseReport.Violations) == 0 {
+		t.Fatal("Expected the base model to have violations")
+	}
+	baseServices := len(base.VirtualServices)
+
+	newVS := &istio.VirtualService{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "new-vs"},
+		Spec: networking.VirtualService{
+			Hosts:    []string{"new.example.com"},
+			Gateways: []string{"istio-system/missing-gateway"},
+		},
+	}
+	brokenVS := &istio.VirtualService{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "broken-vs"}}
+
+	tests := []struct {
+		name       string
+		overlay    Overlay
+		introduced []string // фрагменты "Resource: Message"
+		resolved   bool
+	}{
+		{"unchanged", Overlay{}, nil, false},
+		{"new object", Overlay{Apply: []client.Object{newVS}},
+			[]string{"VirtualService/default/new-vs: References non-existent Gateway/istio-system/missing-gateway"}, false},
+		{"deleted object", Overlay{Delete: []client.Object{brokenVS}}, nil, true},
+	}
+
+	for _, tt := range tests {
+		result, err := operator.AnalyzeOverlay(context.Background(), base, tt.overlay)
+		if err != nil {
+			t.Fatalf("%s: overlay analysis failed: %v", tt.name, err)
+		}
+		var introduced []string
+		for _, violation := range result.Report.Violations {
+			introduced = append(introduced, violation.Resource+": "+violation.Message)
+			t.Logf("⚠️ %s: %s %s", tt.name, violation.Type, introduced[len(introduced)-1])
+		}
+		if len(introduced) != len(tt.introduced) {
+			t.Errorf("%s: expected %d introduced violations, got %v", tt.name, len(tt.introduced), introduced)
+			continue
+		}
+		for i, fragment := range tt.introduced {
+			if !strings.Contains(introduced[i], fragment) {
+				t.Errorf("%s: expected %q, got %q", tt.name, fragment, introduced[i])
+			}
+		}
+		if (len(result.Resolved) > 0) != tt.resolved || result.Report.IsConsistent != (len(introduced) == 0) {
+			t.Errorf("%s: unexpected resolved violations %v", tt.name, result.Resolved)
+		}
+	}
+
+	if len(base.VirtualServices) != baseServices {
+		t.Errorf("Expected the base model to stay unchanged, got %d VirtualServices instead of %d", len(base.VirtualServices), baseServices)
+	}
+}
@@ -0,0 +1,296 @@
+package integrity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Limits of ad-hoc queries over the relational model
+const (
+	// DefaultQueryTimeout bounds the execution of a query
+	DefaultQueryTimeout = 5 * time.Second
+	// DefaultQueryMaxRows bounds the rows a query returns, the rest is dropped
+	DefaultQueryMaxRows = 1000
+	// DefaultQueryMaxBytes bounds the size of the values a query returns, also of a single
+	// value sqlite builds while running it
+	DefaultQueryMaxBytes = 8 << 20
+	// MaxQueryLength bounds the length of a statement
+	MaxQueryLength = 64 << 10
+)
+
+// SchemaQuery lists the tables and indexes of the model with the statements of createSchema
+const SchemaQuery = `SELECT type, name, tbl_name, sql FROM sqlite_master
+WHERE name NOT LIKE 'sqlite_%' AND sql IS NOT NULL
+ORDER BY tbl_name, type DESC, name`
+
+// ErrQueryRejected is returned for statements other than a single read-only SELECT
+var ErrQueryRejected = errors.New("query rejected")
+
+// QueryOptions limit a query, zero values select the defaults
+type QueryOptions struct {
+	Timeout  time.Duration
+	MaxRows  int
+	MaxBytes int
+}
+
+// QueryResult holds the rows of a query, values in the order of Columns
+type QueryResult struct {
+	Columns []string `json:"columns"`
+	Rows    [][]any  `json:"rows"`
+	// Truncated is set when the query returned more than MaxRows rows or MaxBytes bytes
+	Truncated bool `json:"truncated"`
+}
+
+// Query выполняет один SELECT над моделью. Защита в три слоя: лексическая проверка
+// (один оператор, начинается с SELECT или WITH), sqlite3_stmt_readonly подготовленного
+// оператора и PRAGMA query_only на соединении на время запроса.
+func (o *SQLiteIntegrityOperator) Query(ctx context.Context, db *sql.DB, query string, opts QueryOptions) (*QueryResult, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultQueryTimeout
+	}
+	if opts.MaxRows <= 0 {
+		opts.MaxRows = DefaultQueryMaxRows
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultQueryMaxBytes
+	}
+	statement, err := ValidateQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := checkReadonly(ctx, conn, statement); err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, "PRAGMA query_only = ON"); err != nil {
+		return nil, fmt.Errorf("failed to enable query_only: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "PRAGMA query_only = OFF")
+	// SQLITE_LIMIT_LENGTH не дает собрать строку или BLOB больше бюджета, например hex(zeroblob(...))
+	restoreLength, err := limitLength(conn, opts.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	defer restoreLength()
+
+	result, err := readQuery(ctx, conn, statement, opts.MaxRows, opts.MaxBytes)
+	if err != nil {
+		// Прерванный по таймауту запрос sqlite возвращает как "interrupted"
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("query exceeded %s: %w", opts.Timeout, ctx.Err())
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+func readQuery(ctx context.Context, conn *sql.Conn, statement string, maxRows, maxBytes int) (*QueryResult, error) {
+	rows, err := conn.QueryContext(ctx, statement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := &QueryResult{Columns: columns, Rows: [][]any{}}
+	size := 0
+	for rows.Next() {
+		if len(result.Rows) == maxRows {
+			result.Truncated = true
+			break
+		}
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		for i, value := range values {
+			// BLOB и TEXT без типа колонки приходят как []byte
+			if b, ok := value.([]byte); ok {
+				values[i] = string(b)
+			}
+			size += valueSize(values[i])
+		}
+		if size > maxBytes {
+			result.Truncated = true
+			break
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	return result, rows.Err()
+}
+
+// valueSize estimates the size of a value in the result, numbers count as 8 bytes
+func valueSize(value any) int {
+	switch v := value.(type) {
+	case string:
+		return len(v)
+	case nil:
+		return 0
+	}
+	return 8
+}
+
+// limitLength sets SQLITE_LIMIT_LENGTH of the connection and returns the function restoring it
+func limitLength(conn *sql.Conn, length int) (func(), error) {
+	previous := -1
+	err := conn.Raw(func(driverConn any) error {
+		sqliteConn, ok := driverConn.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T", driverConn)
+		}
+		previous = sqliteConn.SetLimit(sqlite3.SQLITE_LIMIT_LENGTH, length)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		conn.Raw(func(driverConn any) error {
+			driverConn.(*sqlite3.SQLiteConn).SetLimit(sqlite3.SQLITE_LIMIT_LENGTH, previous)
+			return nil
+		})
+	}, nil
+}
+
+// checkReadonly prepares the statement and rejects it unless sqlite reports it read-only,
+// e.g. WITH ... DELETE passes the lexical check
+func checkReadonly(ctx context.Context, conn *sql.Conn, statement string) error {
+	return conn.Raw(func(driverConn any) error {
+		sqliteConn, ok := driverConn.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T", driverConn)
+		}
+		stmt, err := sqliteConn.PrepareContext(ctx, statement)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		if sqliteStmt, ok := stmt.(*sqlite3.SQLiteStmt); !ok || !sqliteStmt.Readonly() {
+			return fmt.Errorf("%w: the statement modifies the database", ErrQueryRejected)
+		}
+		return nil
+	})
+}
+
+// ValidateQuery checks that the query is a single SELECT or WITH statement and returns it
+// without the trailing semicolon
+func ValidateQuery(query string) (string, error) {
+	if len(query) > MaxQueryLength {
+		return "", fmt.Errorf("%w: the statement is longer than %d bytes", ErrQueryRejected, MaxQueryLength)
+	}
+	end, rest := splitStatement(query)
+	statement := strings.TrimSpace(query[:end])
+	if strings.TrimSpace(stripComments(rest)) != "" {
+		return "", fmt.Errorf("%w: only a single statement is allowed", ErrQueryRejected)
+	}
+	keyword := firstKeyword(statement)
+	switch keyword {
+	case "SELECT", "WITH":
+		return statement, nil
+	case "":
+		return "", fmt.Errorf("%w: empty statement", ErrQueryRejected)
+	}
+	return "", fmt.Errorf("%w: only SELECT statements are allowed, got %s", ErrQueryRejected, keyword)
+}
+
+// splitStatement returns the end of the first statement and the text after its semicolon.
+// Точки с запятой внутри строк, идентификаторов в кавычках и комментариев не разделяют операторы.
+func splitStatement(query string) (int, string) {
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; c {
+		case '\'', '"', '`':
+			i = skipQuoted(query, i, c)
+		case '[':
+			i = skipQuoted(query, i, ']')
+		case '-':
+			if strings.HasPrefix(query[i:], "--") {
+				i = skipLineComment(query, i)
+			}
+		case '/':
+			if strings.HasPrefix(query[i:], "/*") {
+				i = skipBlockComment(query, i)
+			}
+		case ';':
+			return i, query[i+1:]
+		}
+	}
+	return len(query), ""
+}
+
+// skipQuoted returns the index of the closing quote, a doubled quote is an escaped one
+func skipQuoted(query string, start int, closing byte) int {
+	for i := start + 1; i < len(query); i++ {
+		if query[i] != closing {
+			continue
+		}
+		if closing != ']' && i+1 < len(query) && query[i+1] == closing {
+			i++
+			continue
+		}
+		return i
+	}
+	return len(query)
+}
+
+func skipLineComment(query string, start int) int {
+	if end := strings.IndexByte(query[start:], '\n'); end >= 0 {
+		return start + end
+	}
+	return len(query)
+}
+
+func skipBlockComment(query string, start int) int {
+	if end := strings.Index(query[start+2:], "*/"); end >= 0 {
+		return start + 2 + end + 1
+	}
+	return len(query)
+}
+
+// stripComments removes the comments around and between the statements
+func stripComments(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch {
+		case strings.HasPrefix(text[i:], "--"):
+			i = skipLineComment(text, i)
+		case strings.HasPrefix(text[i:], "/*"):
+			i = skipBlockComment(text, i)
+		default:
+			b.WriteByte(text[i])
+		}
+	}
+	return b.String()
+}
+
+// firstKeyword returns the first word of the statement in upper case, skipping comments
+func firstKeyword(statement string) string {
+	text := strings.TrimSpace(stripComments(statement))
+	end := strings.IndexFunc(text, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+	})
+	if end < 0 {
+		end = len(text)
+	}
+	return strings.ToUpper(text[:end])
+}
@@ -0,0 +1,106 @@
+// Тесты ad-hoc запросов к реляционной модели
+package integrity
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQuery(t *testing.T) {
+	ctx := context.Background()
+	model, err := parseYAMLResources("testdata/traffic-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse YAML file: %v", err)
+	}
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	result, err := operator.Query(ctx, db, "SELECT namespace, name, port FROM services ORDER BY name; -- services", QueryOptions{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	expected := &QueryResult{
+		Columns: []string{"namespace", "name", "port"},
+		Rows:    [][]any{{"shop", "cart", int64(80)}, {"shop", "web", int64(80)}},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	// Точка с запятой в строке и в комментарии не разделяет операторы
+	if _, err := operator.Query(ctx, db, "/* a; b */ WITH x AS (SELECT ';' AS v) SELECT v FROM x;", QueryOptions{}); err != nil {
+		t.Errorf("Expected a single statement, got %v", err)
+	}
+
+	schema, err := operator.Query(ctx, db, SchemaQuery, QueryOptions{})
+	if err != nil {
+		t.Fatalf("Schema query failed: %v", err)
+	}
+	tables := map[any]bool{}
+	for _, row := range schema.Rows {
+		if row[0] == "table" {
+			tables[row[1]] = true
+		}
+	}
+	if !tables["services"] || !tables["virtual_service_destinations"] {
+		t.Errorf("Expected the tables of createSchema, got %v", schema.Rows)
+	}
+
+	result, err = operator.Query(ctx, db, "SELECT name FROM sqlite_master", QueryOptions{MaxRows: 2})
+	if err != nil || len(result.Rows) != 2 || !result.Truncated {
+		t.Errorf("Expected two rows and a truncated result, got %v (%v)", result, err)
+	}
+
+	result, err = operator.Query(ctx, db, "SELECT name FROM services ORDER BY name", QueryOptions{MaxBytes: 6})
+	if err != nil || len(result.Rows) != 1 || !result.Truncated {
+		t.Errorf("Expected one row within the byte limit and a truncated result, got %v (%v)", result, err)
+	}
+
+	// Значение больше лимита sqlite не строит, лимит не остается на соединении
+	if _, err := operator.Query(ctx, db, "SELECT hex(zeroblob(1024))", QueryOptions{MaxBytes: 1024}); err == nil {
+		t.Error("Expected a value above the byte limit to fail")
+	}
+	var length int
+	if err := db.QueryRow("SELECT length(hex(zeroblob(1024)))").Scan(&length); err != nil || length != 2048 {
+		t.Errorf("Expected the length limit to be restored, got %d (%v)", length, err)
+	}
+
+	// Бесконечный рекурсивный запрос прерывается по таймауту
+	_, err = operator.Query(ctx, db, "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT count(*) FROM c",
+		QueryOptions{Timeout: 100 * time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the query to time out, got %v", err)
+	}
+
+	for _, query := range []string{
+		"",
+		"-- nothing",
+		"DELETE FROM services",
+		"SELECT 1; DELETE FROM services",
+		"WITH x AS (SELECT 1) DELETE FROM services",
+		"PRAGMA query_only = OFF",
+		"ATTACH DATABASE '/tmp/mesh.db' AS other",
+		"VACUUM INTO '/tmp/mesh.db'",
+		"select 1 /* ; */ ; insert into namespaces (name) values ('x')",
+	} {
+		if _, err := operator.Query(ctx, db, query, QueryOptions{}); !errors.Is(err, ErrQueryRejected) {
+			t.Errorf("%q: expected the statement to be rejected, got %v", query, err)
+		}
+	}
+
+	// Соединение возвращается в пул без query_only, модель не изменилась
+	var services int
+	if err := db.QueryRow("SELECT count(*) FROM services").Scan(&services); err != nil || services != 2 {
+		t.Errorf("Expected the services to be kept, got %d (%v)", services, err)
+	}
+	if _, err := db.Exec("UPDATE services SET port = port"); err != nil {
+		t.Errorf("Expected query_only to be reset after the query, got %v", err)
+	}
+}
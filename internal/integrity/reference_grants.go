@@ -0,0 +1,209 @@
+package integrity
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+	"sigs.k8s.io/yaml"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+)
+
+// crossNamespaceReference is a Gateway API reference into another namespace that
+// has to be permitted by a ReferenceGrant in the target namespace
+type crossNamespaceReference struct {
+	fromGroup, fromKind, fromNamespace, fromName string
+	field                                        string // путь ссылки в spec
+	toGroup, toKind, toNamespace, toName         string
+}
+
+// grantName is the name proposed for the ReferenceGrant that would permit the reference
+func (r crossNamespaceReference) grantName() string {
+	return fmt.Sprintf("allow-%s-%s", r.fromNamespace, strings.ToLower(r.fromKind))
+}
+
+// fromResource is the referencing object in Kind/namespace/name form, a Gateway API Gateway
+// is named by gatewayAPIGatewayKind as in the rest of the report
+func (r crossNamespaceReference) fromResource() string {
+	kind := r.fromKind
+	if kind == "Gateway" {
+		kind = gatewayAPIGatewayKind
+	}
+	return fmt.Sprintf("%s/%s/%s", kind, r.fromNamespace, r.fromName)
+}
+
+// grantResource is the proposed ReferenceGrant in Kind/namespace/name form
+func (r crossNamespaceReference) grantResource() string {
+	return fmt.Sprintf("ReferenceGrant/%s/%s", r.toNamespace, r.grantName())
+}
+
+// missingReferenceGrants возвращает cross-namespace backendRefs и certificateRefs,
+// которые не разрешены ни одним ReferenceGrant в namespace цели
+func missingReferenceGrants(db *sql.DB) ([]crossNamespaceReference, error) {
+	rows, err := db.Query(`
+		WITH refs(from_group, from_kind, from_namespace, from_name, field, to_group, to_kind, to_namespace, to_name) AS (
+			SELECT ?, b.kind, b.namespace, b.name,
+				'rules[' || b.rule_index || '].backendRefs[' || b.ref_index || ']',
+				b.backend_group, b.backend_kind, b.backend_namespace, b.backend_name
+			FROM gateway_api_backend_refs b
+			UNION ALL
+			SELECT ?, 'Gateway', c.namespace, c.name,
+				'listeners[' || c.listener_name || '].tls.certificateRefs[' || c.ref_index || ']',
+				c.ref_group, c.ref_kind, c.ref_namespace, c.ref_name
+			FROM gateway_api_certificate_refs c
+		)
+		SELECT r.from_group, r.from_kind, r.from_namespace, r.from_name, r.field,
+			r.to_group, r.to_kind, r.to_namespace, r.to_name
+		FROM refs r
+		WHERE r.to_namespace <> r.from_namespace
+		  AND NOT EXISTS (
+			SELECT 1 FROM reference_grants rg
+			WHERE rg.namespace = r.to_namespace
+			  AND rg.from_group = r.from_group AND rg.from_kind = r.from_kind AND rg.from_namespace = r.from_namespace
+			  AND rg.to_group = r.to_group AND rg.to_kind = r.to_kind
+			  AND (rg.to_name = '' OR rg.to_name = r.to_name)
+		  )
+		ORDER BY r.from_kind, r.from_namespace, r.from_name, r.field
+	`, gatewayAPIGroup, gatewayAPIGroup)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []crossNamespaceReference
+	for rows.Next() {
+		var ref crossNamespaceReference
+		if err := rows.Scan(&ref.fromGroup, &ref.fromKind, &ref.fromNamespace, &ref.fromName, &ref.field,
+			&ref.toGroup, &ref.toKind, &ref.toNamespace, &ref.toName); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// checkReferenceGrantViolations сообщает о cross-namespace ссылках без ReferenceGrant
+// и называет ReferenceGrant, который их разрешит
+func (o *SQLiteIntegrityOperator) checkReferenceGrantViolations(db *sql.DB) ([]meshv1alpha1.ConstraintViolation, error) {
+	var violations []meshv1alpha1.ConstraintViolation
+
+	refs, err := missingReferenceGrants(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "ReferenceGrantViolation",
+			Resource: ref.fromResource(),
+			Message: fmt.Sprintf("%s to %s/%s/%s is not permitted by any ReferenceGrant in namespace %s: missing %s from %s in %s to %s %s",
+				ref.field, ref.toKind, ref.toNamespace, ref.toName, ref.toNamespace,
+				ref.grantResource(), groupKind(ref.fromGroup, ref.fromKind), ref.fromNamespace, groupKind(ref.toGroup, ref.toKind), ref.toName),
+			Severity: "Error",
+		})
+	}
+
+	return violations, nil
+}
+
+// referenceGrantRepairs предлагает по одному ReferenceGrant на namespace цели и
+// источник (group, kind, namespace), перечисляя все объекты, на которые нужны ссылки
+func referenceGrantRepairs(db *sql.DB) ([]meshv1alpha1.RepairAction, error) {
+	refs, err := missingReferenceGrants(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var grants []string
+	targets := map[string][]string{}
+	sources := map[string][]string{}
+	descriptions := map[string]string{}
+	objects := map[string]*gatewayv1beta1.ReferenceGrant{}
+	for _, ref := range refs {
+		grant := ref.grantResource()
+		if _, ok := descriptions[grant]; !ok {
+			grants = append(grants, grant)
+			descriptions[grant] = fmt.Sprintf("from %s in %s", groupKind(ref.fromGroup, ref.fromKind), ref.fromNamespace)
+			objects[grant] = &gatewayv1beta1.ReferenceGrant{
+				TypeMeta:   metav1.TypeMeta{APIVersion: gatewayv1beta1.GroupVersion.String(), Kind: "ReferenceGrant"},
+				ObjectMeta: metav1.ObjectMeta{Namespace: ref.toNamespace, Name: ref.grantName()},
+				Spec: gatewayv1beta1.ReferenceGrantSpec{
+					From: []gatewayv1beta1.ReferenceGrantFrom{{
+						Group:     gatewayv1beta1.Group(ref.fromGroup),
+						Kind:      gatewayv1beta1.Kind(ref.fromKind),
+						Namespace: gatewayv1beta1.Namespace(ref.fromNamespace),
+					}},
+				},
+			}
+		}
+		target := fmt.Sprintf("%s %s", groupKind(ref.toGroup, ref.toKind), ref.toName)
+		if !containsString(targets[grant], target) {
+			targets[grant] = append(targets[grant], target)
+			name := gatewayv1beta1.ObjectName(ref.toName)
+			objects[grant].Spec.To = append(objects[grant].Spec.To, gatewayv1beta1.ReferenceGrantTo{
+				Group: gatewayv1beta1.Group(ref.toGroup),
+				Kind:  gatewayv1beta1.Kind(ref.toKind),
+				Name:  &name,
+			})
+		}
+		sources[grant] = append(sources[grant], ref.fromResource()+" "+ref.field)
+	}
+
+	var repairs []meshv1alpha1.RepairAction
+	for _, grant := range grants {
+		sort.Strings(targets[grant])
+		to := objects[grant].Spec.To
+		sort.Slice(to, func(i, j int) bool {
+			return groupKind(string(to[i].Group), string(to[i].Kind))+" "+string(*to[i].Name) <
+				groupKind(string(to[j].Group), string(to[j].Kind))+" "+string(*to[j].Name)
+		})
+		manifest, err := objectManifest(objects[grant])
+		if err != nil {
+			return nil, err
+		}
+		repairs = append(repairs, meshv1alpha1.RepairAction{
+			Type:     "Create",
+			Resource: grant,
+			Action:   fmt.Sprintf("Create ReferenceGrant %s to %s", descriptions[grant], strings.Join(targets[grant], ", ")),
+			Reason:   "Permits cross-namespace references: " + strings.Join(sources[grant], ", "),
+			Manifest: manifest,
+		})
+	}
+	return repairs, nil
+}
+
+// objectManifest renders the object as YAML without the fields the API server sets
+func objectManifest(obj runtime.Object) (string, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return "", err
+	}
+	unstructured.RemoveNestedField(content, "metadata", "creationTimestamp")
+	data, err := yaml.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// groupKind formats a group/kind pair, "core" stands for the empty core API group
+func groupKind(group, kind string) string {
+	if group == "" {
+		group = "core"
+	}
+	return group + "/" + kind
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+// Тесты для ReferenceGrant и плана их создания
+package integrity
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckReferenceGrantViolations(t *testing.T) {
+	model, err := parseYAMLResources("testdata/gateway-api-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse YAML file: %v", err)
+	}
+
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	violations, err := operator.checkReferenceGrantViolations(db)
+	if err != nil {
+		t.Fatalf("Failed to check ReferenceGrant violations: %v", err)
+	}
+
+	expected := map[string]string{
+		"HTTPRoute/shop/billing":                         "missing ReferenceGrant/payments/allow-shop-httproute from gateway.networking.k8s.io/HTTPRoute in shop to core/Service billing",
+		"Gateway.gateway.networking.k8s.io/infra/public": "missing ReferenceGrant/certs/allow-infra-gateway from gateway.networking.k8s.io/Gateway in infra to core/Secret wildcard-cert",
+	}
+	for _, violation := range violations {
+		t.Logf("⚠️ Violation: %s %s - %s", violation.Type, violation.Resource, violation.Message)
+		fragment, ok := expected[violation.Resource]
+		if !ok {
+			t.Errorf("Unexpected violation for %s", violation.Resource)
+			continue
+		}
+		if !strings.Contains(violation.Message, fragment) {
+			t.Errorf("Expected message for %s to contain %q, got %q", violation.Resource, fragment, violation.Message)
+		}
+		delete(expected, violation.Resource)
+	}
+	for resource := range expected {
+		t.Errorf("Expected ReferenceGrant violation for %s", resource)
+	}
+}
+
+// certificateGrant is the ReferenceGrant permitting the certificateRef of Gateway infra/public
+const certificateGrant = `apiVersion: gateway.networking.k8s.io/v1beta1
+kind: ReferenceGrant
+metadata:
+  name: allow-infra-gateway
+  namespace: certs
+spec:
+  from:
+  - group: gateway.networking.k8s.io
+    kind: Gateway
+    namespace: infra
+  to:
+  - group: ""
+    kind: Secret
+    name: wildcard-cert
+`
+
+func TestComputeRepairPlans_ReferenceGrant(t *testing.T) {
+	model, err := parseYAMLResources("testdata/gateway-api-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse YAML file: %v", err)
+	}
+
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	report, err := operator.CheckIntegrity(db)
+	if err != nil {
+		t.Fatalf("Failed to check integrity: %v", err)
+	}
+
+	repairs, err := operator.ComputeRepairPlans(db, report)
+	if err != nil {
+		t.Fatalf("Failed to compute repair plans: %v", err)
+	}
+
+	expected := map[string]string{
+		"ReferenceGrant/payments/allow-shop-httproute": "from gateway.networking.k8s.io/HTTPRoute in shop to core/Service billing",
+		"ReferenceGrant/certs/allow-infra-gateway":     "from gateway.networking.k8s.io/Gateway in infra to core/Secret wildcard-cert",
+	}
+	for _, repair := range repairs {
+		t.Logf("🔧 Repair: %s %s - %s", repair.Type, repair.Resource, repair.Action)
+		fragment, ok := expected[repair.Resource]
+		if !ok {
+			continue
+		}
+		if repair.Type != "Create" {
+			t.Errorf("Expected Create repair for %s, got %s", repair.Resource, repair.Type)
+		}
+		if !strings.Contains(repair.Action, fragment) {
+			t.Errorf("Expected action for %s to contain %q, got %q", repair.Resource, fragment, repair.Action)
+		}
+		if repair.Resource == "ReferenceGrant/certs/allow-infra-gateway" && repair.Manifest != certificateGrant {
+			t.Errorf("Expected manifest of %s:\n%s\ngot:\n%s", repair.Resource, certificateGrant, repair.Manifest)
+		}
+		delete(expected, repair.Resource)
+	}
+	for resource := range expected {
+		t.Errorf("Expected repair action creating %s", resource)
+	}
+}
@@ -0,0 +1,171 @@
+// Package render renders Helm charts and kustomizations in-process and decodes the
+// output with the manifest loader. Rendering never contacts a cluster or a repository:
+// chart dependencies must be present in the charts directory.
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	helmloader "helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/getter"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity/loader"
+)
+
+// Defaults of helm template
+const (
+	DefaultReleaseName = "release-name"
+	DefaultNamespace   = "default"
+)
+
+// HelmOptions are the release settings and values of a chart, as for helm template
+type HelmOptions struct {
+	ReleaseName string
+	Namespace   string
+	// ValueFiles are merged in order, later files win
+	ValueFiles []string
+	// Values are key=value pairs of --set, applied after ValueFiles
+	Values []string
+	// KubeVersion is the Kubernetes version of .Capabilities, the Helm default when empty
+	KubeVersion string
+}
+
+// sourcePrefix starts the comment Helm puts before every rendered document
+const sourcePrefix = "# Source: "
+
+// Helm renders a local chart directory or archive like helm template and decodes the output.
+// Sources point at the template file of each document; lines are lines of the rendered
+// document and EndLine is 0, the rendered text is not the content of the template.
+func Helm(chartPath string, options HelmOptions) ([]loader.Object, error) {
+	chrt, err := helmloader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart %s: %w", chartPath, err)
+	}
+	// Зависимости не скачиваются: рендеринг работает без сети
+	if err := action.CheckDependencies(chrt, chrt.Metadata.Dependencies); err != nil {
+		return nil, fmt.Errorf("chart %s: %w, run helm dependency build", chartPath, err)
+	}
+	if chrt.Metadata.Type != "" && chrt.Metadata.Type != "application" {
+		return nil, fmt.Errorf("chart %s is a %s chart and cannot be rendered", chartPath, chrt.Metadata.Type)
+	}
+
+	valueOptions := values.Options{ValueFiles: options.ValueFiles, Values: options.Values}
+	vals, err := valueOptions.MergeValues(getter.Providers{})
+	if err != nil {
+		return nil, err
+	}
+
+	install := action.NewInstall(&action.Configuration{Log: func(string, ...interface{}) {}})
+	install.DryRun = true
+	install.ClientOnly = true
+	install.Replace = true
+	install.ReleaseName = options.ReleaseName
+	if install.ReleaseName == "" {
+		install.ReleaseName = DefaultReleaseName
+	}
+	install.Namespace = options.Namespace
+	if install.Namespace == "" {
+		install.Namespace = DefaultNamespace
+	}
+	if options.KubeVersion != "" {
+		if install.KubeVersion, err = chartutil.ParseKubeVersion(options.KubeVersion); err != nil {
+			return nil, err
+		}
+	}
+	release, err := install.Run(chrt, vals)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render chart %s: %w", chartPath, err)
+	}
+
+	manifests := release.Manifest
+	for _, hook := range release.Hooks {
+		manifests += "\n---\n" + sourcePrefix + hook.Path + "\n" + hook.Manifest
+	}
+	return loadRendered(chartPath, chrt, manifests, install.Namespace)
+}
+
+// loadRendered decodes every rendered document under the path of its template. Objects
+// without a namespace get the release namespace, as helm install applies them.
+func loadRendered(chartPath string, chrt *chart.Chart, manifests, namespace string) ([]loader.Object, error) {
+	var objects []loader.Object
+	var parseErrors loader.ParseErrors
+	load := func(source string, document []byte) {
+		if source == "" {
+			return
+		}
+		// Путь в комментарии начинается с имени чарта, а не с каталога
+		file := filepath.Join(chartPath, strings.TrimPrefix(source, chrt.Name()+"/"))
+		loaded, err := loader.Load(file, document)
+		for _, obj := range loaded {
+			if _, ok := obj.Object.(*corev1.Namespace); !ok && obj.GetNamespace() == "" {
+				obj.SetNamespace(namespace)
+			}
+		}
+		objects = append(objects, rendered(loaded)...)
+		var errs loader.ParseErrors
+		if errors.As(err, &errs) {
+			parseErrors = append(parseErrors, errs...)
+		}
+	}
+
+	var source string
+	var document bytes.Buffer
+	scanner := bufio.NewScanner(strings.NewReader(manifests))
+	scanner.Buffer(nil, len(manifests)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, sourcePrefix) && document.Len() == 0 {
+			source = strings.TrimPrefix(line, sourcePrefix)
+		}
+		if line == "---" {
+			load(source, document.Bytes())
+			source = ""
+			document.Reset()
+			continue
+		}
+		document.WriteString(line)
+		document.WriteByte('\n')
+	}
+	load(source, document.Bytes())
+
+	if len(parseErrors) > 0 {
+		return objects, parseErrors
+	}
+	return objects, nil
+}
+
+// Kustomize builds a kustomization directory like kustomize build and decodes the output.
+// Sources are the directory with the line of the object in the build output.
+func Kustomize(path string) ([]loader.Object, error) {
+	resources, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(filesys.MakeFsOnDisk(), path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kustomization %s: %w", path, err)
+	}
+	data, err := resources.AsYaml()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kustomization %s: %w", path, err)
+	}
+	objects, err := loader.Load(path, data)
+	return rendered(objects), err
+}
+
+// rendered clears EndLine: the range of a rendered document is not a range of the source
+// file, and report fixes must not delete it
+func rendered(objects []loader.Object) []loader.Object {
+	for i := range objects {
+		objects[i].Source.EndLine = 0
+	}
+	return objects
+}
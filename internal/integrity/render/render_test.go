@@ -0,0 +1,127 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity/loader"
+)
+
+// resources formats the objects as Kind/namespace/name@file:line
+func resources(objects []loader.Object) []string {
+	var result []string
+	for _, obj := range objects {
+		kind := fmt.Sprintf("%T", obj.Object)
+		kind = kind[strings.LastIndex(kind, ".")+1:]
+		result = append(result, fmt.Sprintf("%s/%s/%s@%s:%d", kind, obj.GetNamespace(), obj.GetName(),
+			filepath.ToSlash(obj.Source.File), obj.Source.Line))
+		if obj.Source.EndLine != 0 {
+			result = append(result, "EndLine must be 0 for rendered objects")
+		}
+	}
+	return result
+}
+
+func TestHelm(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  HelmOptions
+		expected []string
+	}{
+		{"values.yaml", HelmOptions{Namespace: "bookinfo"}, []string{
+			"Service/bookinfo/reviews@testdata/chart/templates/routing.yaml:2",
+			"Gateway/bookinfo/public-gateway@testdata/chart/templates/gateway.yaml:2",
+			"VirtualService/bookinfo/reviews@testdata/chart/templates/routing.yaml:2",
+		}},
+		{"values file", HelmOptions{ValueFiles: []string{"testdata/chart/values-broken.yaml"}}, []string{
+			"Service/default/reviews@testdata/chart/templates/routing.yaml:2",
+			"VirtualService/default/reviews@testdata/chart/templates/routing.yaml:2",
+		}},
+		// --set применяется после файлов значений
+		{"set", HelmOptions{ValueFiles: []string{"testdata/chart/values-broken.yaml"}, Values: []string{"gateway.create=true"}}, []string{
+			"Service/default/reviews@testdata/chart/templates/routing.yaml:2",
+			"Gateway/default/missing-gateway@testdata/chart/templates/gateway.yaml:2",
+			"VirtualService/default/reviews@testdata/chart/templates/routing.yaml:2",
+		}},
+	}
+	for _, tt := range tests {
+		objects, err := Helm("testdata/chart", tt.options)
+		if err != nil {
+			t.Fatalf("%s: failed to render chart: %v", tt.name, err)
+		}
+		if got := resources(objects); strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tt.name, strings.Join(tt.expected, "\n"), strings.Join(got, "\n"))
+		}
+	}
+
+	// Отсутствующие зависимости не скачиваются
+	chart := t.TempDir()
+	os.WriteFile(filepath.Join(chart, "Chart.yaml"), []byte(`apiVersion: v2
+name: umbrella
+version: 0.1.0
+dependencies:
+- name: reviews
+  version: 0.1.0
+  repository: https://charts.example.com
+`), 0o644)
+	if _, err := Helm(chart, HelmOptions{}); err == nil || !strings.Contains(err.Error(), "helm dependency build") {
+		t.Errorf("Expected an error for a missing dependency, got %v", err)
+	}
+
+	// Документ, который не декодируется, сообщается с файлом шаблона
+	os.WriteFile(filepath.Join(chart, "Chart.yaml"), []byte("apiVersion: v2\nname: broken\nversion: 0.1.0\n"), 0o644)
+	os.MkdirAll(filepath.Join(chart, "templates"), 0o755)
+	os.WriteFile(filepath.Join(chart, "templates", "service.yaml"), []byte("apiVersion: v1\nkind: Service\nmetadata:\n  labels: {}\n"), 0o644)
+	_, err := Helm(chart, HelmOptions{})
+	var parseErrors loader.ParseErrors
+	if !strings.Contains(fmt.Sprint(err), filepath.Join(chart, "templates", "service.yaml")) || !errors.As(err, &parseErrors) {
+		t.Errorf("Expected a parse error in templates/service.yaml, got %v", err)
+	}
+
+	for _, options := range []HelmOptions{
+		{ValueFiles: []string{"testdata/chart/does-not-exist.yaml"}},
+		{Values: []string{"gateway.create"}},
+		{KubeVersion: "not-a-version"},
+	} {
+		if _, err := Helm("testdata/chart", options); err == nil {
+			t.Errorf("%+v: expected an error", options)
+		}
+	}
+	if _, err := Helm("testdata/does-not-exist", HelmOptions{}); err == nil {
+		t.Error("Expected an error for a missing chart")
+	}
+}
+
+func TestKustomize(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{"testdata/kustomize/base", []string{
+			"Gateway/bookinfo/public-gateway@testdata/kustomize/base:1",
+			"Service/bookinfo/reviews@testdata/kustomize/base:17",
+			"VirtualService/bookinfo/reviews@testdata/kustomize/base:29",
+		}},
+		{"testdata/kustomize/overlays/broken", []string{
+			"Service/bookinfo/reviews@testdata/kustomize/overlays/broken:1",
+			"VirtualService/bookinfo/reviews@testdata/kustomize/overlays/broken:13",
+		}},
+	}
+	for _, tt := range tests {
+		objects, err := Kustomize(tt.path)
+		if err != nil {
+			t.Fatalf("%s: failed to build kustomization: %v", tt.path, err)
+		}
+		if got := resources(objects); strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tt.path, strings.Join(tt.expected, "\n"), strings.Join(got, "\n"))
+		}
+	}
+
+	if _, err := Kustomize("testdata/chart"); err == nil {
+		t.Error("Expected an error for a directory without a kustomization")
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity/loader"
+)
+
+// JSONVersion is the version of the JSON report schema. Fields are only added within
+// a version; renaming or removing a field starts a new version.
+const JSONVersion = "mesh-integrity/v1"
+
+// JSONReport is the stable JSON schema of an integrity.IntegrityReport for CI
+type JSONReport struct {
+	Version     string          `json:"version"`
+	Consistent  bool            `json:"consistent"`
+	Summary     JSONSummary     `json:"summary"`
+	Violations  []JSONViolation `json:"violations"`
+	RepairPlans []JSONRepair    `json:"repairPlans"`
+}
+
+// JSONSummary counts violations by severity and repair plans
+type JSONSummary struct {
+	Errors      int `json:"errors"`
+	Warnings    int `json:"warnings"`
+	RepairPlans int `json:"repairPlans"`
+}
+
+// JSONViolation is a ConstraintViolation with the manifests of its resource
+type JSONViolation struct {
+	Rule      string          `json:"rule"`
+	Severity  string          `json:"severity"`
+	Resource  string          `json:"resource"`
+	Message   string          `json:"message"`
+	Locations []loader.Source `json:"locations,omitempty"`
+}
+
+// JSONRepair is a RepairAction
+type JSONRepair struct {
+	Type     string `json:"type"`
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+	Reason   string `json:"reason"`
+	Manifest string `json:"manifest,omitempty"`
+}
+
+// JSON converts the report into the versioned JSON schema
+func JSON(report *integrity.IntegrityReport, locations Locations) *JSONReport {
+	result := &JSONReport{
+		Version:     JSONVersion,
+		Consistent:  len(report.Violations) == 0,
+		Violations:  []JSONViolation{},
+		RepairPlans: []JSONRepair{},
+	}
+	for _, violation := range report.Violations {
+		if violation.Severity == "Error" {
+			result.Summary.Errors++
+		} else {
+			result.Summary.Warnings++
+		}
+		result.Violations = append(result.Violations, JSONViolation{
+			Rule:      violation.Type,
+			Severity:  violation.Severity,
+			Resource:  violation.Resource,
+			Message:   violation.Message,
+			Locations: locations[violation.Resource],
+		})
+	}
+	for _, repair := range report.RepairPlans {
+		result.RepairPlans = append(result.RepairPlans, JSONRepair(repair))
+	}
+	result.Summary.RepairPlans = len(result.RepairPlans)
+	return result
+}
+
+// WriteJSON writes the report as indented JSON
+func WriteJSON(w io.Writer, report *integrity.IntegrityReport, locations Locations) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(JSON(report, locations))
+}
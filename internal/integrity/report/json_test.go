@@ -0,0 +1,32 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	report, objects := checkManifests(t, "../testdata/invalid-mesh-resources.yaml")
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, report, LocationsOf(objects)); err != nil {
+		t.Fatalf("Failed to write JSON: %v", err)
+	}
+	var decoded JSONReport
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+
+	if decoded.Version != JSONVersion || decoded.Consistent {
+		t.Errorf("Expected an inconsistent %s report, got %s consistent=%v", JSONVersion, decoded.Version, decoded.Consistent)
+	}
+	if decoded.Summary.Errors+decoded.Summary.Warnings != len(report.Violations) || decoded.Summary.RepairPlans != len(report.RepairPlans) {
+		t.Errorf("Unexpected summary %+v for %d violations and %d repair plans", decoded.Summary, len(report.Violations), len(report.RepairPlans))
+	}
+	for _, violation := range decoded.Violations {
+		if len(violation.Locations) == 0 || violation.Locations[0].Line == 0 {
+			t.Errorf("Expected %s %s to have a location", violation.Rule, violation.Resource)
+		}
+	}
+}
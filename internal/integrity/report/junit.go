@@ -0,0 +1,137 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"encoding/xml"
+	"io"
+	"sort"
+	"strings"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+)
+
+// JUnitTestSuites is the root element of a JUnit XML report
+type JUnitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []JUnitTestSuite `xml:"testsuite"`
+}
+
+// JUnitTestSuite holds the test cases of one rule
+type JUnitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []JUnitTestCase `xml:"testcase"`
+}
+
+// JUnitTestCase is a rule checked against one resource
+type JUnitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// JUnitFailure lists the Error violations of a rule for a resource
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit converts the report into one test suite per rule with one test case per resource.
+// Every rule is checked against every resource of the locations and every resource with
+// violations, so passing resources show up as passed test cases. Error violations fail
+// the test case, Warning violations are kept in its output.
+func JUnit(report *integrity.IntegrityReport, locations Locations) *JUnitTestSuites {
+	byCase := map[[2]string][]meshv1alpha1.ConstraintViolation{}
+	rules := map[string]bool{}
+	resources := map[string]bool{}
+	for rule := range ruleDescriptions {
+		rules[rule] = true
+	}
+	for resource := range locations {
+		resources[resource] = true
+	}
+	for _, violation := range report.Violations {
+		rules[violation.Type] = true
+		resources[violation.Resource] = true
+		key := [2]string{violation.Type, violation.Resource}
+		byCase[key] = append(byCase[key], violation)
+	}
+
+	suites := &JUnitTestSuites{Name: toolName}
+	for _, rule := range sortedKeys(rules) {
+		suite := JUnitTestSuite{Name: rule}
+		for _, resource := range sortedKeys(resources) {
+			testCase := JUnitTestCase{Name: resource, ClassName: rule}
+			if sources := locations[resource]; len(sources) > 0 {
+				testCase.File, testCase.Line = sources[0].File, sources[0].Line
+			}
+
+			var errs, warnings []string
+			for _, violation := range byCase[[2]string{rule, resource}] {
+				if violation.Severity == "Error" {
+					errs = append(errs, violation.Message)
+				} else {
+					warnings = append(warnings, "warning: "+violation.Message)
+				}
+			}
+			if len(errs) > 0 {
+				testCase.Failure = &JUnitFailure{Message: errs[0], Type: "Error", Text: strings.Join(errs, "\n")}
+				suite.Failures++
+			}
+			testCase.SystemOut = strings.Join(warnings, "\n")
+			suite.Cases = append(suite.Cases, testCase)
+		}
+		suite.Tests = len(suite.Cases)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Suites = append(suites.Suites, suite)
+	}
+	return suites
+}
+
+// WriteJUnit writes the report as JUnit XML
+func WriteJUnit(w io.Writer, report *integrity.IntegrityReport, locations Locations) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(JUnit(report, locations)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,51 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestJUnit(t *testing.T) {
+	report, objects := checkManifests(t, "../testdata/invalid-mesh-resources.yaml")
+	locations := LocationsOf(objects)
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, report, locations); err != nil {
+		t.Fatalf("Failed to write JUnit: %v", err)
+	}
+	var suites JUnitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("Failed to decode JUnit: %v", err)
+	}
+
+	// Каждое правило проверяется на каждом ресурсе
+	if len(suites.Suites) < len(ruleDescriptions) {
+		t.Fatalf("Expected a test suite per rule, got %d", len(suites.Suites))
+	}
+	failed := map[[2]string]bool{}
+	for _, violation := range report.Violations {
+		if violation.Severity == "Error" {
+			failed[[2]string{violation.Type, violation.Resource}] = true
+		}
+	}
+	tests, failures := 0, 0
+	for _, suite := range suites.Suites {
+		if suite.Tests != len(suite.Cases) || suite.Tests < len(locations) {
+			t.Errorf("Suite %s: expected a test case per resource, got %d", suite.Name, suite.Tests)
+		}
+		for _, testCase := range suite.Cases {
+			if (testCase.Failure != nil) != failed[[2]string{suite.Name, testCase.Name}] {
+				t.Errorf("%s %s: unexpected failure %+v", suite.Name, testCase.Name, testCase.Failure)
+			}
+			if testCase.Failure != nil {
+				failures++
+				t.Logf("❌ %s %s (%s:%d): %s", suite.Name, testCase.Name, testCase.File, testCase.Line, testCase.Failure.Message)
+			}
+		}
+		tests += suite.Tests
+	}
+	if suites.Tests != tests || suites.Failures != failures || failures != len(failed) {
+		t.Errorf("Expected %d tests and %d failures, got %d and %d", tests, len(failed), suites.Tests, suites.Failures)
+	}
+}
@@ -0,0 +1,340 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package report formats an integrity.IntegrityReport for tools outside of the operator:
+// code review systems and CI dashboards.
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity/loader"
+)
+
+const (
+	// SARIFVersion is the version of the SARIF specification of the output
+	SARIFVersion = "2.1.0"
+	// SARIFMediaType is the media type of SARIF logs
+	SARIFMediaType = "application/sarif+json"
+
+	sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName    = "mesh-integrity"
+	toolURI     = "https://github.com/mdarin/istio-integrity-operator"
+)
+
+// SARIFLog is the root object of a SARIF 2.1.0 log, only the properties the report uses
+type SARIFLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+// SARIFRun is a single run of the integrity checks
+type SARIFRun struct {
+	Tool    SARIFTool     `json:"tool"`
+	Results []SARIFResult `json:"results"`
+}
+
+// SARIFTool describes mesh-integrity and its rules
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+// SARIFDriver is the tool component producing the results
+type SARIFDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []SARIFRule `json:"rules"`
+}
+
+// SARIFRule is a reportingDescriptor of a violation type
+type SARIFRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     SARIFMessage       `json:"shortDescription"`
+	DefaultConfiguration SARIFConfiguration `json:"defaultConfiguration"`
+}
+
+// SARIFConfiguration is the default reportingConfiguration of a rule
+type SARIFConfiguration struct {
+	Level string `json:"level"`
+}
+
+// SARIFMessage is a plain text message
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+// SARIFResult is a ConstraintViolation
+type SARIFResult struct {
+	RuleID     string           `json:"ruleId"`
+	RuleIndex  int              `json:"ruleIndex"`
+	Level      string           `json:"level"`
+	Message    SARIFMessage     `json:"message"`
+	Locations  []SARIFLocation  `json:"locations"`
+	Fixes      []SARIFFix       `json:"fixes,omitempty"`
+	Properties *SARIFProperties `json:"properties,omitempty"`
+}
+
+// SARIFProperties is the property bag of a result: repair plans that are not edits of
+// a manifest, e.g. Update, or whose resource has no location in a file
+type SARIFProperties struct {
+	Repairs []meshv1alpha1.RepairAction `json:"repairs"`
+}
+
+// SARIFLocation is the manifest of the resource and the resource itself
+type SARIFLocation struct {
+	PhysicalLocation *SARIFPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []SARIFLogicalLocation `json:"logicalLocations"`
+}
+
+// SARIFPhysicalLocation is a region of a manifest file
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+	Region           *SARIFRegion          `json:"region,omitempty"`
+}
+
+// SARIFArtifactLocation is the URI of a manifest file
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// SARIFRegion is a range of lines, columns start at 1
+type SARIFRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// SARIFLogicalLocation is a Kubernetes resource formatted as Kind/namespace/name
+type SARIFLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// SARIFFix is a repair plan as an edit of the manifest
+type SARIFFix struct {
+	Description     SARIFMessage          `json:"description"`
+	ArtifactChanges []SARIFArtifactChange `json:"artifactChanges"`
+}
+
+// SARIFArtifactChange is the edit of one manifest file
+type SARIFArtifactChange struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+	Replacements     []SARIFReplacement    `json:"replacements"`
+}
+
+// SARIFReplacement replaces a region, an empty region inserts the content
+type SARIFReplacement struct {
+	DeletedRegion   SARIFRegion   `json:"deletedRegion"`
+	InsertedContent *SARIFContent `json:"insertedContent,omitempty"`
+}
+
+// SARIFContent is inserted text
+type SARIFContent struct {
+	Text string `json:"text"`
+}
+
+// ruleDescriptions описывают правила по типам нарушений CheckIntegrity
+var ruleDescriptions = map[string]string{
+	"ForeignKeyViolation":          "A reference points to a resource that does not exist",
+	"UniqueConstraintViolation":    "Several resources claim the same host, port or gateway binding",
+	"ReferenceGrantViolation":      "A cross-namespace Gateway API reference is not permitted by a ReferenceGrant",
+	"VisibilityViolation":          "A reference points to a resource that is not exported to the namespace",
+	"TrafficPolicyViolation":       "A traffic policy is inconsistent with the ports or subsets of its host",
+	"MTLSViolation":                "The TLS mode of a DestinationRule conflicts with the PeerAuthentication of its host",
+	"AuthorizationPolicyViolation": "An AuthorizationPolicy references principals, namespaces or ports that do not exist",
+	"SidecarEgressViolation":       "A workload routes to a host its Sidecar does not import",
+	"DelegateViolation":            "A VirtualService delegation is missing, cyclic or not a delegate",
+	"ShadowedRouteViolation":       "An HTTP route can never match because an earlier route shadows it",
+	"RouteOverlapViolation":        "VirtualServices bound to the same host and gateway have overlapping routes",
+	"WeightViolation":              "Route or subset weights do not add up to 100",
+}
+
+// Locations maps resources formatted by integrity.ObjectResource, like ConstraintViolation.Resource,
+// to the manifests they were loaded from. A resource declared in several manifests has
+// several locations.
+type Locations map[string][]loader.Source
+
+// LocationsOf indexes the sources of loaded objects
+func LocationsOf(objects []loader.Object) Locations {
+	locations := Locations{}
+	for _, obj := range objects {
+		resource := integrity.ObjectResource(obj.Object)
+		locations[resource] = append(locations[resource], obj.Source)
+	}
+	return locations
+}
+
+// SARIF converts the violations and repair plans of the report into a SARIF log.
+// Violations of resources with a location point to their manifest; repair plans become
+// fixes of the results they resolve: Delete removes the document of the resource and Create
+// inserts the manifest of the new object before it. Other plans and plans of resources
+// without a location are kept in the properties of the result.
+func SARIF(report *integrity.IntegrityReport, locations Locations) *SARIFLog {
+	driver := SARIFDriver{Name: toolName, InformationURI: toolURI, Rules: []SARIFRule{}}
+	ruleIndex := map[string]int{}
+	results := []SARIFResult{}
+
+	for _, violation := range report.Violations {
+		level := sarifLevel(violation.Severity)
+		index, ok := ruleIndex[violation.Type]
+		if !ok {
+			index = len(driver.Rules)
+			ruleIndex[violation.Type] = index
+			description := ruleDescriptions[violation.Type]
+			if description == "" {
+				description = violation.Type
+			}
+			driver.Rules = append(driver.Rules, SARIFRule{
+				ID:                   violation.Type,
+				ShortDescription:     SARIFMessage{Text: description},
+				DefaultConfiguration: SARIFConfiguration{Level: level},
+			})
+		}
+
+		sources := locations[violation.Resource]
+		result := SARIFResult{
+			RuleID:    violation.Type,
+			RuleIndex: index,
+			Level:     level,
+			Message:   SARIFMessage{Text: violation.Message},
+			Locations: sarifLocations(violation.Resource, sources),
+		}
+		for _, repair := range repairsOf(violation, report.RepairPlans) {
+			fix, ok := sarifFix(repair, sources)
+			if !ok {
+				if result.Properties == nil {
+					result.Properties = &SARIFProperties{}
+				}
+				result.Properties.Repairs = append(result.Properties.Repairs, repair)
+				continue
+			}
+			result.Fixes = append(result.Fixes, fix)
+		}
+		results = append(results, result)
+	}
+
+	return &SARIFLog{
+		Schema:  sarifSchema,
+		Version: SARIFVersion,
+		Runs:    []SARIFRun{{Tool: SARIFTool{Driver: driver}, Results: results}},
+	}
+}
+
+// WriteSARIF writes the report as an indented SARIF log
+func WriteSARIF(w io.Writer, report *integrity.IntegrityReport, locations Locations) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(SARIF(report, locations))
+}
+
+func sarifLevel(severity string) string {
+	if severity == "Error" {
+		return "error"
+	}
+	return "warning"
+}
+
+func sarifLocations(resource string, sources []loader.Source) []SARIFLocation {
+	logical := []SARIFLogicalLocation{{FullyQualifiedName: resource, Kind: "resource"}}
+	var locations []SARIFLocation
+	for _, source := range sources {
+		uri, ok := artifactURI(source)
+		if !ok {
+			continue
+		}
+		locations = append(locations, SARIFLocation{
+			PhysicalLocation: &SARIFPhysicalLocation{
+				ArtifactLocation: SARIFArtifactLocation{URI: uri},
+				Region:           &SARIFRegion{StartLine: source.Line, EndLine: source.EndLine},
+			},
+			LogicalLocations: logical,
+		})
+	}
+	if len(locations) == 0 {
+		locations = append(locations, SARIFLocation{LogicalLocations: logical})
+	}
+	return locations
+}
+
+// artifactURI returns the URI of the manifest file, stdin has none
+func artifactURI(source loader.Source) (string, bool) {
+	if source.File == "" || source.File == loader.StdinName {
+		return "", false
+	}
+	uri := filepath.ToSlash(source.File)
+	if filepath.IsAbs(source.File) {
+		uri = "file://" + uri
+	}
+	return uri, true
+}
+
+// repairsOf returns the repair plans resolving the violation: plans ComputeRepairPlans
+// derived from it and ReferenceGrants listing the resource among the references they permit
+func repairsOf(violation meshv1alpha1.ConstraintViolation, repairs []meshv1alpha1.RepairAction) []meshv1alpha1.RepairAction {
+	var result []meshv1alpha1.RepairAction
+	for _, repair := range repairs {
+		switch {
+		case repair.Resource == violation.Resource && repair.Reason == violation.Message:
+			result = append(result, repair)
+		case repair.Type == "Create" && violation.Type == "ReferenceGrantViolation" &&
+			strings.Contains(repair.Reason, violation.Resource+" "):
+			result = append(result, repair)
+		}
+	}
+	return result
+}
+
+// sarifFix выражает план как правку манифеста: Delete удаляет документ ресурса целиком,
+// Create вставляет перед ним отдельным документом манифест нового объекта. Правки нужен
+// собственный документ ресурса, элементы List не редактируются.
+func sarifFix(repair meshv1alpha1.RepairAction, sources []loader.Source) (SARIFFix, bool) {
+	if repair.Type != "Delete" && (repair.Type != "Create" || repair.Manifest == "") {
+		return SARIFFix{}, false
+	}
+	fix := SARIFFix{Description: SARIFMessage{Text: repair.Action}}
+	for _, source := range sources {
+		uri, ok := artifactURI(source)
+		if !ok || source.EndLine == 0 {
+			continue
+		}
+		var replacement SARIFReplacement
+		if repair.Type == "Delete" {
+			replacement.DeletedRegion = SARIFRegion{StartLine: source.Line, StartColumn: 1, EndLine: source.EndLine + 1, EndColumn: 1}
+		} else {
+			replacement.DeletedRegion = SARIFRegion{StartLine: source.Line, StartColumn: 1, EndLine: source.Line, EndColumn: 1}
+			replacement.InsertedContent = &SARIFContent{Text: repair.Manifest + "---\n"}
+		}
+		fix.ArtifactChanges = append(fix.ArtifactChanges, SARIFArtifactChange{
+			ArtifactLocation: SARIFArtifactLocation{URI: uri},
+			Replacements:     []SARIFReplacement{replacement},
+		})
+	}
+	if len(fix.ArtifactChanges) == 0 {
+		return SARIFFix{}, false
+	}
+	sort.Slice(fix.ArtifactChanges, func(i, j int) bool {
+		return fix.ArtifactChanges[i].ArtifactLocation.URI < fix.ArtifactChanges[j].ArtifactLocation.URI
+	})
+	return fix, true
+}
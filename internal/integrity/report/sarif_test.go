@@ -0,0 +1,167 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity/loader"
+)
+
+// checkManifests загружает фикстуру и возвращает отчет с планами исправления
+func checkManifests(t *testing.T, path string) (*integrity.IntegrityReport, []loader.Object) {
+	t.Helper()
+	objects, err := loader.LoadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load %s: %v", path, err)
+	}
+	model := &integrity.RelationalModel{}
+	for _, obj := range objects {
+		if err := model.AddManifestObject(obj.Object); err != nil {
+			t.Fatalf("Failed to add %s: %v", obj.Source, err)
+		}
+	}
+
+	operator := integrity.NewSQLiteIntegrityOperator(nil)
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	report, err := operator.CheckIntegrity(db)
+	if err != nil {
+		t.Fatalf("Integrity check failed: %v", err)
+	}
+	if report.RepairPlans, err = operator.ComputeRepairPlans(db, report); err != nil {
+		t.Fatalf("Failed to compute repair plans: %v", err)
+	}
+	return report, objects
+}
+
+func TestSARIF(t *testing.T) {
+	report, objects := checkManifests(t, "../testdata/gateway-api-resources.yaml")
+	locations := LocationsOf(objects)
+	// Gateway API Gateway индексируется в форме ObjectResource, как и его нарушения
+	if len(locations["Gateway.gateway.networking.k8s.io/infra/public"]) != 1 || len(locations["Gateway/infra/public"]) != 0 {
+		t.Errorf("Expected Gateway API Gateway infra/public to be keyed with its group, got %v", locations)
+	}
+	log := SARIF(report, locations)
+
+	if log.Version != SARIFVersion || len(log.Runs) != 1 {
+		t.Fatalf("Expected a single SARIF %s run, got %s with %d runs", SARIFVersion, log.Version, len(log.Runs))
+	}
+	run := log.Runs[0]
+	if len(run.Results) != len(report.Violations) {
+		t.Fatalf("Expected %d results, got %d", len(report.Violations), len(run.Results))
+	}
+
+	rules := map[string]bool{}
+	for i, rule := range run.Tool.Driver.Rules {
+		if rules[rule.ID] {
+			t.Errorf("Rule %s is described twice", rule.ID)
+		}
+		rules[rule.ID] = true
+		if rule.ShortDescription.Text == rule.ID {
+			t.Errorf("Rule %d %s has no description", i, rule.ID)
+		}
+	}
+
+	deletes, creates := 0, 0
+	for _, result := range run.Results {
+		if run.Tool.Driver.Rules[result.RuleIndex].ID != result.RuleID {
+			t.Errorf("Result %s points to rule %d", result.RuleID, result.RuleIndex)
+		}
+		location := result.Locations[0]
+		if location.PhysicalLocation == nil || location.PhysicalLocation.Region.StartLine == 0 {
+			t.Errorf("Expected %s to have a location in the manifest", location.LogicalLocations[0].FullyQualifiedName)
+			continue
+		}
+		for _, fix := range result.Fixes {
+			replacement := fix.ArtifactChanges[0].Replacements[0]
+			if replacement.InsertedContent == nil {
+				deletes++
+				if replacement.DeletedRegion.StartLine != location.PhysicalLocation.Region.StartLine {
+					t.Errorf("Expected the fix %q to delete the document at line %d, got %+v",
+						fix.Description.Text, location.PhysicalLocation.Region.StartLine, replacement.DeletedRegion)
+				}
+			} else {
+				creates++
+				// Create вставляет манифест ReferenceGrant отдельным документом
+				text := replacement.InsertedContent.Text
+				if !strings.HasPrefix(text, "apiVersion: gateway.networking.k8s.io/v1beta1\nkind: ReferenceGrant\n") || !strings.HasSuffix(text, "\n---\n") {
+					t.Errorf("Expected the fix %q to insert a ReferenceGrant document, got %q", fix.Description.Text, text)
+				}
+			}
+		}
+		t.Logf("⚠️ %s %s:%d %s (%d fixes)", result.RuleID, location.PhysicalLocation.ArtifactLocation.URI,
+			location.PhysicalLocation.Region.StartLine, result.Message.Text, len(result.Fixes))
+	}
+	if deletes == 0 || creates == 0 {
+		t.Errorf("Expected Delete and Create repair plans as fixes, got %d and %d", deletes, creates)
+	}
+}
+
+func TestSARIFWithoutLocations(t *testing.T) {
+	report := &integrity.IntegrityReport{
+		Violations: []meshv1alpha1.ConstraintViolation{{
+			Type: "ForeignKeyViolation", Resource: "VirtualService/default/web",
+			Message: "References non-existent Gateway/istio-system/public", Severity: "Error",
+		}, {
+			Type: "CustomViolation", Resource: "Service/default/web", Message: "custom", Severity: "Warning",
+		}},
+		RepairPlans: []meshv1alpha1.RepairAction{{
+			Type: "Delete", Resource: "VirtualService/default/web",
+			Action: "Delete broken VirtualService reference", Reason: "References non-existent Gateway/istio-system/public",
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, report, nil); err != nil {
+		t.Fatalf("Failed to write SARIF: %v", err)
+	}
+	var log SARIFLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("Failed to decode SARIF: %v", err)
+	}
+
+	results := log.Runs[0].Results
+	if len(results) != 2 || results[0].Level != "error" || results[1].Level != "warning" {
+		t.Fatalf("Unexpected results %+v", results)
+	}
+	// Без файла план нельзя выразить правкой, он остается в properties
+	if results[0].Fixes != nil || results[0].Properties == nil || len(results[0].Properties.Repairs) != 1 {
+		t.Errorf("Expected the repair plan in the properties of the result, got %+v", results[0])
+	}
+	if results[0].Locations[0].PhysicalLocation != nil || results[0].Locations[0].LogicalLocations[0].FullyQualifiedName != "VirtualService/default/web" {
+		t.Errorf("Expected only a logical location, got %+v", results[0].Locations[0])
+	}
+	if !strings.Contains(buf.String(), `"$schema"`) {
+		t.Errorf("Expected the SARIF schema in the log")
+	}
+}
+
+func TestSARIFUpdateRepair(t *testing.T) {
+	report := &integrity.IntegrityReport{
+		Violations: []meshv1alpha1.ConstraintViolation{{
+			Type: "UniqueConstraintViolation", Resource: "VirtualService/default/web",
+			Message: "Host web.example.com is bound by several VirtualServices", Severity: "Error",
+		}},
+		RepairPlans: []meshv1alpha1.RepairAction{{
+			Type: "Update", Resource: "VirtualService/default/web",
+			Action: "Remove host web.example.com", Reason: "Host web.example.com is bound by several VirtualServices",
+		}},
+	}
+	locations := Locations{"VirtualService/default/web": {{File: "web.yaml", Line: 1, EndLine: 12}}}
+
+	// Update не выражается правкой манифеста и остается в properties, а не в комментарии-fix
+	result := SARIF(report, locations).Runs[0].Results[0]
+	if result.Fixes != nil || result.Properties == nil || len(result.Properties.Repairs) != 1 {
+		t.Errorf("Expected the Update plan in the properties of the result, got %+v", result)
+	}
+	if result.Locations[0].PhysicalLocation == nil {
+		t.Errorf("Expected the result to point to web.yaml, got %+v", result.Locations[0])
+	}
+}
@@ -0,0 +1,387 @@
+package integrity
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+)
+
+// stringCondition is a single uri, method or header condition of an HTTP match.
+// Type is exact, prefix, regex, present (header without value) or empty for no condition.
+type stringCondition struct {
+	Type       string
+	Value      string
+	IgnoreCase bool
+}
+
+// httpMatch is one http[].match[] entry; a route without matches has a single empty match
+type httpMatch struct {
+	uri     stringCondition
+	method  stringCondition
+	headers map[string]stringCondition
+	other   bool // условия, которые модель не хранит
+}
+
+// httpRoute is one http[] entry of a VirtualService in declaration order
+type httpRoute struct {
+	namespace string
+	name      string
+	index     int
+	routeName string
+	matches   []httpMatch
+}
+
+func (r *httpRoute) label() string {
+	if r.routeName != "" {
+		return fmt.Sprintf("http[%d] (%s)", r.index, r.routeName)
+	}
+	return fmt.Sprintf("http[%d]", r.index)
+}
+
+// checkHTTPRouteViolations ищет http маршруты, недостижимые из-за более ранних маршрутов того же
+// VirtualService, и пересекающиеся match у VirtualService, которые Istio сливает для одного host
+// на одном gateway: порядок маршрутов между ними не определен.
+func (o *SQLiteIntegrityOperator) checkHTTPRouteViolations(db *sql.DB) ([]meshv1alpha1.ConstraintViolation, error) {
+	var violations []meshv1alpha1.ConstraintViolation
+
+	routes, order, err := loadHTTPRoutes(db)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. Маршрут затенен, если каждый его match покрыт match одного из предыдущих маршрутов
+	for _, vs := range order {
+		vsRoutes := routes[vs]
+		for i, route := range vsRoutes {
+			shadowedBy := shadowingRoutes(vsRoutes[:i], route)
+			if len(shadowedBy) == 0 {
+				continue
+			}
+			var labels []string
+			for _, earlier := range shadowedBy {
+				labels = append(labels, earlier.label())
+			}
+			violations = append(violations, meshv1alpha1.ConstraintViolation{
+				Type:     "ShadowedRouteViolation",
+				Resource: "VirtualService/" + vs,
+				Message: fmt.Sprintf("%s is unreachable: every request it matches is already matched by %s",
+					route.label(), strings.Join(labels, ", ")),
+				Severity: "Warning",
+			})
+		}
+	}
+
+	// 2. VirtualService, привязанные к одному gateway и host, с пересекающимися match
+	rows, err := db.Query(`
+		SELECT a.namespace, a.name, b.namespace, b.name, ga.gateway_namespace, ga.gateway_name, ha.canonical_host
+		FROM virtual_service_gateways ga
+		JOIN virtual_service_hosts ha ON ha.namespace = ga.namespace AND ha.name = ga.name
+		JOIN virtual_service_gateways gb
+			ON gb.gateway_namespace = ga.gateway_namespace AND gb.gateway_name = ga.gateway_name
+		JOIN virtual_service_hosts hb
+			ON hb.namespace = gb.namespace AND hb.name = gb.name AND hb.canonical_host = ha.canonical_host
+		JOIN virtual_services a ON a.namespace = ga.namespace AND a.name = ga.name
+		JOIN virtual_services b ON b.namespace = gb.namespace AND b.name = gb.name
+		WHERE ga.gateway_name <> 'mesh'
+		  AND (a.namespace < b.namespace OR (a.namespace = b.namespace AND a.name < b.name))
+		ORDER BY a.namespace, a.name, b.namespace, b.name, ga.gateway_namespace, ga.gateway_name, ha.canonical_host
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reported := map[string]bool{}
+	for rows.Next() {
+		var aNs, aName, bNs, bName, gwNs, gwName, host string
+		if err := rows.Scan(&aNs, &aName, &bNs, &bName, &gwNs, &gwName, &host); err != nil {
+			return nil, err
+		}
+		a, b := aNs+"/"+aName, bNs+"/"+bName
+		if reported[a+","+b] {
+			continue
+		}
+		routeA, routeB := overlappingRoutes(routes[a], routes[b])
+		if routeA == nil {
+			continue
+		}
+		reported[a+","+b] = true
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "RouteOverlapViolation",
+			Resource: "VirtualService/" + a,
+			Message: fmt.Sprintf("%s overlaps %s of VirtualService/%s for host %s on Gateway/%s/%s; route order between merged VirtualServices is undefined",
+				routeA.label(), routeB.label(), b, host, gwNs, gwName),
+			Severity: "Warning",
+		})
+	}
+
+	return violations, rows.Err()
+}
+
+// loadHTTPRoutes reads http routes with their matches keyed by "namespace/name" of the
+// VirtualService, and returns the keys in stable order
+func loadHTTPRoutes(db *sql.DB) (map[string][]*httpRoute, []string, error) {
+	rows, err := db.Query(`
+		SELECT r.namespace, r.name, r.http_index, r.route_name, m.match_index,
+			COALESCE(m.uri_type, ''), COALESCE(m.uri, ''), COALESCE(m.ignore_uri_case, 0),
+			COALESCE(m.method_type, ''), COALESCE(m.method, ''), COALESCE(m.has_other_conditions, 0)
+		FROM http_routes r
+		LEFT JOIN http_matches m ON m.namespace = r.namespace AND m.name = r.name AND m.http_index = r.http_index
+		ORDER BY r.namespace, r.name, r.http_index, m.match_index
+	`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	routes := map[string][]*httpRoute{}
+	var order []string
+	type matchPosition struct {
+		route *httpRoute
+		index int
+	}
+	matchPositions := map[string]matchPosition{}
+	for rows.Next() {
+		var ns, name, routeName, uriType, uri, methodType, method string
+		var httpIndex int
+		var matchIndex sql.NullInt64
+		var ignoreCase, other bool
+		if err := rows.Scan(&ns, &name, &httpIndex, &routeName, &matchIndex,
+			&uriType, &uri, &ignoreCase, &methodType, &method, &other); err != nil {
+			return nil, nil, err
+		}
+
+		key := ns + "/" + name
+		vsRoutes, ok := routes[key]
+		if !ok {
+			order = append(order, key)
+		}
+		var route *httpRoute
+		if len(vsRoutes) > 0 && vsRoutes[len(vsRoutes)-1].index == httpIndex {
+			route = vsRoutes[len(vsRoutes)-1]
+		} else {
+			route = &httpRoute{namespace: ns, name: name, index: httpIndex, routeName: routeName}
+			routes[key] = append(vsRoutes, route)
+		}
+
+		if !matchIndex.Valid {
+			// Маршрут без match принимает все запросы
+			route.matches = append(route.matches, httpMatch{})
+			continue
+		}
+		// prefix "/" совпадает с любым путем
+		if uriType == "prefix" && uri == "/" {
+			uriType, uri = "", ""
+		}
+		route.matches = append(route.matches, httpMatch{
+			uri:    stringCondition{Type: uriType, Value: uri, IgnoreCase: ignoreCase},
+			method: stringCondition{Type: methodType, Value: method},
+			other:  other,
+		})
+		matchPositions[fmt.Sprintf("%s/%d/%d", key, httpIndex, matchIndex.Int64)] = matchPosition{route, len(route.matches) - 1}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	rows.Close()
+
+	rows, err = db.Query(`
+		SELECT namespace, name, http_index, match_index, header, match_type, value
+		FROM http_match_headers
+		ORDER BY namespace, name, http_index, match_index, header
+	`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ns, name, header, matchType, value string
+		var httpIndex, matchIndex int
+		if err := rows.Scan(&ns, &name, &httpIndex, &matchIndex, &header, &matchType, &value); err != nil {
+			return nil, nil, err
+		}
+		position, ok := matchPositions[fmt.Sprintf("%s/%s/%d/%d", ns, name, httpIndex, matchIndex)]
+		if !ok {
+			continue
+		}
+		match := &position.route.matches[position.index]
+		if match.headers == nil {
+			match.headers = map[string]stringCondition{}
+		}
+		match.headers[header] = stringCondition{Type: matchType, Value: value}
+	}
+	return routes, order, rows.Err()
+}
+
+// shadowingRoutes returns the earlier routes that together match everything route matches,
+// or nil when at least one match of route is reachable
+func shadowingRoutes(earlier []*httpRoute, route *httpRoute) []*httpRoute {
+	var shadowedBy []*httpRoute
+	for _, match := range route.matches {
+		var coveredBy *httpRoute
+		for _, candidate := range earlier {
+			for _, earlierMatch := range candidate.matches {
+				if matchCovers(earlierMatch, match) {
+					coveredBy = candidate
+					break
+				}
+			}
+			if coveredBy != nil {
+				break
+			}
+		}
+		if coveredBy == nil {
+			return nil
+		}
+		if !containsRoute(shadowedBy, coveredBy) {
+			shadowedBy = append(shadowedBy, coveredBy)
+		}
+	}
+	return shadowedBy
+}
+
+// overlappingRoutes returns the first pair of routes of two VirtualServices that can match the same request
+func overlappingRoutes(a, b []*httpRoute) (*httpRoute, *httpRoute) {
+	for _, routeA := range a {
+		for _, routeB := range b {
+			for _, matchA := range routeA.matches {
+				for _, matchB := range routeB.matches {
+					if matchesOverlap(matchA, matchB) {
+						return routeA, routeB
+					}
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// matchCovers reports whether every request matching b also matches a.
+// Conditions the model does not store make a unable to cover anything.
+func matchCovers(a, b httpMatch) bool {
+	if a.other {
+		return false
+	}
+	if !conditionCovers(a.uri, b.uri) || !conditionCovers(a.method, b.method) {
+		return false
+	}
+	for header, condition := range a.headers {
+		if !conditionCovers(condition, b.headers[header]) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesOverlap reports whether some request provably matches both a and b.
+// Unknown conditions and regex against non-exact values are not treated as overlapping.
+func matchesOverlap(a, b httpMatch) bool {
+	if a.other || b.other {
+		return false
+	}
+	if !conditionsOverlap(a.uri, b.uri) || !conditionsOverlap(a.method, b.method) {
+		return false
+	}
+	for header, condition := range a.headers {
+		if other, ok := b.headers[header]; ok && !conditionsOverlap(condition, other) {
+			return false
+		}
+	}
+	return true
+}
+
+// conditionCovers reports whether every value accepted by b is accepted by a
+func conditionCovers(a, b stringCondition) bool {
+	switch {
+	case a.Type == "":
+		return true
+	case b.Type == "":
+		return false
+	case a.Type == "present":
+		return true
+	case b.Type == "present":
+		return false
+	case b.IgnoreCase && !a.IgnoreCase:
+		// b принимает варианты регистра, которые a отвергает
+		return false
+	}
+
+	aValue, bValue := a.Value, b.Value
+	if a.IgnoreCase {
+		aValue, bValue = strings.ToLower(aValue), strings.ToLower(bValue)
+	}
+	switch a.Type {
+	case "exact":
+		return b.Type == "exact" && aValue == bValue
+	case "prefix":
+		return (b.Type == "exact" || b.Type == "prefix") && strings.HasPrefix(bValue, aValue)
+	case "regex":
+		if b.Type == "regex" {
+			return a.Value == b.Value
+		}
+		return b.Type == "exact" && regexMatches(a, b.Value)
+	}
+	return false
+}
+
+// conditionsOverlap reports whether a value provably satisfies both conditions
+func conditionsOverlap(a, b stringCondition) bool {
+	switch {
+	case a.Type == "" || b.Type == "":
+		return true
+	case a.Type == "present" || b.Type == "present":
+		return true
+	case a.Type == "exact" && b.Type == "exact":
+		if a.IgnoreCase || b.IgnoreCase {
+			return strings.EqualFold(a.Value, b.Value)
+		}
+		return a.Value == b.Value
+	case a.Type == "regex" && b.Type == "regex":
+		return a.Value == b.Value
+	case a.Type == "regex":
+		return b.Type == "exact" && regexMatches(a, b.Value)
+	case b.Type == "regex":
+		return a.Type == "exact" && regexMatches(b, a.Value)
+	}
+
+	// exact/prefix и prefix/prefix: одно значение является префиксом другого
+	aValue, bValue := a.Value, b.Value
+	if a.IgnoreCase || b.IgnoreCase {
+		aValue, bValue = strings.ToLower(aValue), strings.ToLower(bValue)
+	}
+	switch {
+	case a.Type == "prefix" && b.Type == "prefix":
+		return strings.HasPrefix(aValue, bValue) || strings.HasPrefix(bValue, aValue)
+	case a.Type == "prefix":
+		return strings.HasPrefix(bValue, aValue)
+	default:
+		return strings.HasPrefix(aValue, bValue)
+	}
+}
+
+// regexMatches reports whether the RE2 regex of the condition fully matches value,
+// as Envoy safe_regex does. An invalid regex matches nothing.
+func regexMatches(condition stringCondition, value string) bool {
+	expr := "^(?:" + condition.Value + ")$"
+	if condition.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return false
+	}
+	return re.MatchString(value)
+}
+
+func containsRoute(routes []*httpRoute, route *httpRoute) bool {
+	for _, r := range routes {
+		if r == route {
+			return true
+		}
+	}
+	return false
+}
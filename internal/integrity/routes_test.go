@@ -0,0 +1,89 @@
+// Тесты для затененных и пересекающихся http маршрутов VirtualService
+package integrity
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckHTTPRouteViolations(t *testing.T) {
+	model, err := parseYAMLResources("testdata/route-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse YAML file: %v", err)
+	}
+
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	var matches int
+	db.QueryRow("SELECT COUNT(*) FROM http_matches").Scan(&matches)
+	if matches != 11 {
+		t.Errorf("Expected 11 http matches, got %d", matches)
+	}
+
+	violations, err := operator.checkHTTPRouteViolations(db)
+	if err != nil {
+		t.Fatalf("Failed to check HTTP route violations: %v", err)
+	}
+
+	expected := []struct {
+		violationType string
+		resource      string
+		fragment      string
+	}{
+		{"ShadowedRouteViolation", "VirtualService/shop/storefront", "http[1] (api-v2) is unreachable: every request it matches is already matched by http[0] (api)"},
+		{"ShadowedRouteViolation", "VirtualService/shop/storefront", "http[3] (admin) is unreachable: every request it matches is already matched by http[2] (catch-all)"},
+		{"ShadowedRouteViolation", "VirtualService/shop/canary", "http[1] (canary-users) is unreachable: every request it matches is already matched by http[0] (canary)"},
+		{"RouteOverlapViolation", "VirtualService/shop/legacy", "http[0] (legacy-api) overlaps http[0] (api) of VirtualService/shop/storefront for host shop.example.com on Gateway/shop/public-gw"},
+		{"RouteOverlapViolation", "VirtualService/shop/mobile", "http[0] overlaps http[2] (catch-all) of VirtualService/shop/storefront"},
+	}
+
+	for _, violation := range violations {
+		t.Logf("⚠️ Violation: %s %s - %s", violation.Type, violation.Resource, violation.Message)
+	}
+	for _, want := range expected {
+		found := false
+		for _, violation := range violations {
+			if violation.Type == want.violationType && violation.Resource == want.resource && strings.Contains(violation.Message, want.fragment) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Expected %s for %s containing %q", want.violationType, want.resource, want.fragment)
+		}
+	}
+	if len(violations) != len(expected) {
+		t.Errorf("Expected %d route violations, got %d", len(expected), len(violations))
+	}
+}
+
+func TestConditionCovers(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   stringCondition
+		covers bool
+	}{
+		{"empty covers exact", stringCondition{}, stringCondition{Type: "exact", Value: "/a"}, true},
+		{"exact does not cover empty", stringCondition{Type: "exact", Value: "/a"}, stringCondition{}, false},
+		{"prefix covers longer prefix", stringCondition{Type: "prefix", Value: "/a"}, stringCondition{Type: "prefix", Value: "/ab"}, true},
+		{"prefix does not cover shorter prefix", stringCondition{Type: "prefix", Value: "/ab"}, stringCondition{Type: "prefix", Value: "/a"}, false},
+		{"regex covers matching exact", stringCondition{Type: "regex", Value: "/v[0-9]+"}, stringCondition{Type: "exact", Value: "/v2"}, true},
+		{"regex is anchored", stringCondition{Type: "regex", Value: "/v[0-9]"}, stringCondition{Type: "exact", Value: "/v2/x"}, false},
+		{"case-sensitive does not cover ignoreCase", stringCondition{Type: "prefix", Value: "/a"}, stringCondition{Type: "prefix", Value: "/a", IgnoreCase: true}, false},
+		{"ignoreCase covers other case", stringCondition{Type: "prefix", Value: "/A", IgnoreCase: true}, stringCondition{Type: "exact", Value: "/ab"}, true},
+		{"present covers prefix", stringCondition{Type: "present"}, stringCondition{Type: "prefix", Value: "x"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := conditionCovers(tt.a, tt.b); got != tt.covers {
+				t.Errorf("conditionCovers(%+v, %+v) = %v, want %v", tt.a, tt.b, got, tt.covers)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package integrity
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// splitLabels parses the k1=v1,k2=v2 form produced by joinLabels
+func splitLabels(labels string) map[string]string {
+	result := map[string]string{}
+	for _, pair := range strings.Split(labels, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || key == "" {
+			continue
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// insertSelector stores the label selector of a resource in selector_labels
+func insertSelector(tx *sql.Tx, kind, namespace, name, selector string) error {
+	for key, value := range splitLabels(selector) {
+		if _, err := tx.Exec(
+			"INSERT OR IGNORE INTO selector_labels (kind, namespace, name, key, value) VALUES (?, ?, ?, ?, ?)",
+			kind, namespace, name, key, value,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectorMatchesSQL returns a predicate that holds when the workload aliased as workload
+// carries every label of the selector stored under (kind, namespace, name).
+// An empty selector matches every workload, so callers restrict the namespace themselves.
+func selectorMatchesSQL(kind, namespaceExpr, nameExpr, workload string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+			SELECT 1 FROM selector_labels sl
+			WHERE sl.kind = '%s' AND sl.namespace = %s AND sl.name = %s
+			  AND NOT EXISTS (
+				SELECT 1 FROM workload_labels wl
+				WHERE wl.namespace = %s.namespace AND wl.name = %s.name
+				  AND wl.key = sl.key AND wl.value = sl.value
+			  )
+		)`, kind, namespaceExpr, nameExpr, workload, workload)
+}
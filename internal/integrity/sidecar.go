@@ -0,0 +1,211 @@
+package integrity
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+)
+
+// sidecarEgress is a namespace-wide Sidecar with its egress hosts
+type sidecarEgress struct {
+	namespace string
+	name      string
+	// importAll: Sidecar без egress наследует egress по умолчанию и видит весь mesh
+	importAll bool
+	hosts     []SidecarEgressHostRecord
+}
+
+// checkSidecarEgressViolations проверяет, что Sidecar namespace-источника импортирует host,
+// к которому ведут VirtualService destinations шлюза mesh и MeshService.
+//
+// Зависимости известны на уровне namespace, поэтому учитывается Sidecar без workloadSelector
+// в namespace источника, а при его отсутствии - Sidecar по умолчанию из root namespace.
+// Без Sidecar workload видит все сервисы mesh.
+func (o *SQLiteIntegrityOperator) checkSidecarEgressViolations(db *sql.DB) ([]meshv1alpha1.ConstraintViolation, error) {
+	var violations []meshv1alpha1.ConstraintViolation
+
+	sidecars, err := loadNamespaceSidecars(db)
+	if err != nil {
+		return nil, err
+	}
+	if len(sidecars) == 0 {
+		return nil, nil
+	}
+
+	// Маршруты VirtualService, привязанного только к ingress Gateway, выполняет pod шлюза,
+	// Sidecar namespace их не ограничивает: проверяются VirtualService шлюза mesh.
+	// Делегат наследует gateways корневого VirtualService, свои spec.gateways Istio игнорирует.
+	// Namespace владельца host: Service, затем ServiceEntry
+	rows, err := db.Query(`
+		WITH mesh_bound AS (
+			SELECT vs.namespace, vs.name FROM virtual_services vs
+			WHERE NOT EXISTS (
+				SELECT 1 FROM virtual_service_gateways g
+				WHERE g.namespace = vs.namespace AND g.name = vs.name
+			) OR EXISTS (
+				SELECT 1 FROM virtual_service_gateways g
+				WHERE g.namespace = vs.namespace AND g.name = vs.name
+				  AND g.gateway_namespace = '' AND g.gateway_name = 'mesh'
+			)
+		),
+		mesh_routes AS (
+			SELECT mb.namespace, mb.name FROM mesh_bound mb
+			WHERE NOT EXISTS (
+				SELECT 1 FROM virtual_service_delegates d
+				WHERE d.delegate_namespace = mb.namespace AND d.delegate_name = mb.name
+			)
+			UNION
+			SELECT d.delegate_namespace, d.delegate_name
+			FROM virtual_service_delegates d
+			JOIN mesh_bound mb ON mb.namespace = d.namespace AND mb.name = d.name
+		)
+		SELECT 'VirtualService', vd.namespace, vd.name, vd.canonical_host,
+			COALESCE(
+				(SELECT s.namespace FROM services s WHERE lower(s.host) = vd.canonical_host),
+				(SELECT se.namespace FROM service_entries se WHERE lower(se.host) = vd.canonical_host ORDER BY se.namespace LIMIT 1),
+				''
+			)
+		FROM virtual_service_destinations vd
+		JOIN mesh_routes mr ON mr.namespace = vd.namespace AND mr.name = vd.name
+		UNION
+		SELECT 'MeshService', ms.namespace, ms.name,
+			lower(ms.service_name || '.' || ms.service_namespace || '.' || ?), ms.service_namespace
+		FROM mesh_services ms
+		WHERE ms.service_name <> ''
+		ORDER BY 1, 2, 3, 4
+	`, clusterDomainSuffix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kind, ns, name, host, hostNamespace string
+		if err := rows.Scan(&kind, &ns, &name, &host, &hostNamespace); err != nil {
+			return nil, err
+		}
+		if hostNamespace == "" {
+			hostNamespace = hostServiceNamespace(host)
+		}
+
+		sidecar, ok := sidecars[ns]
+		if !ok {
+			sidecar, ok = sidecars[istioRootNamespace]
+		}
+		if !ok || sidecarImports(sidecar, ns, hostNamespace, host) {
+			continue
+		}
+
+		var egress []string
+		for _, egressHost := range sidecar.hosts {
+			egress = append(egress, egressHost.HostNamespace+"/"+egressHost.Host)
+		}
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "SidecarEgressViolation",
+			Resource: fmt.Sprintf("%s/%s/%s", kind, ns, name),
+			Message: fmt.Sprintf("Routes to host %s which Sidecar/%s/%s does not import (egress hosts: %s)",
+				host, sidecar.namespace, sidecar.name, strings.Join(egress, ", ")),
+			Severity: "Error",
+		})
+	}
+
+	return violations, rows.Err()
+}
+
+// loadNamespaceSidecars reads Sidecars without workloadSelector keyed by namespace.
+// When a namespace has several, Istio applies the oldest one, then the first by name.
+func loadNamespaceSidecars(db *sql.DB) (map[string]*sidecarEgress, error) {
+	rows, err := db.Query(`
+		SELECT sc.namespace, sc.name, sc.egress_listeners, eh.listener_index, eh.host_namespace, eh.host
+		FROM sidecars sc
+		LEFT JOIN sidecar_egress_hosts eh ON eh.namespace = sc.namespace AND eh.name = sc.name
+		WHERE sc.workload_selector = ''
+		ORDER BY sc.namespace, sc.created_at = '', sc.created_at, sc.name,
+			eh.listener_index, eh.host_namespace, eh.host
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sidecars := map[string]*sidecarEgress{}
+	for rows.Next() {
+		var ns, name string
+		var egressListeners int
+		var listenerIndex sql.NullInt64
+		var hostNamespace, host sql.NullString
+		if err := rows.Scan(&ns, &name, &egressListeners, &listenerIndex, &hostNamespace, &host); err != nil {
+			return nil, err
+		}
+		sidecar, ok := sidecars[ns]
+		if !ok {
+			sidecar = &sidecarEgress{namespace: ns, name: name, importAll: egressListeners == 0}
+			sidecars[ns] = sidecar
+		}
+		if sidecar.name != name || !host.Valid {
+			continue
+		}
+		sidecar.hosts = append(sidecar.hosts, SidecarEgressHostRecord{
+			Namespace:     ns,
+			Name:          name,
+			ListenerIndex: int(listenerIndex.Int64),
+			HostNamespace: hostNamespace.String,
+			Host:          host.String,
+		})
+	}
+	return sidecars, rows.Err()
+}
+
+// sidecarImports reports whether an egress host of the Sidecar admits host declared in
+// hostNamespace. "." refers to the namespace of the importing workload, also for the
+// root namespace default Sidecar, and "~" imports nothing. A Sidecar without egress
+// imports every host.
+func sidecarImports(sidecar *sidecarEgress, sourceNamespace, hostNamespace, host string) bool {
+	if sidecar.importAll {
+		return true
+	}
+	for _, egress := range sidecar.hosts {
+		switch egress.HostNamespace {
+		case "*":
+		case ".":
+			if hostNamespace != sourceNamespace {
+				continue
+			}
+		case "~":
+			continue
+		default:
+			if egress.HostNamespace != hostNamespace {
+				continue
+			}
+		}
+		if hostMatches(egress.Host, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostMatches matches a host against an Istio host pattern: "*" or a "*.suffix" wildcard
+func hostMatches(pattern, host string) bool {
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasPrefix(pattern, "*"):
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return pattern == host
+}
+
+// hostServiceNamespace returns the namespace of a <name>.<ns>.svc.cluster.local host
+func hostServiceNamespace(host string) string {
+	if !strings.HasSuffix(host, "."+clusterDomainSuffix) {
+		return ""
+	}
+	parts := strings.Split(host, ".")
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[1]
+}
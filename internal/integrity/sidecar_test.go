@@ -0,0 +1,78 @@
+// Тесты для проверки egress hosts Sidecar
+package integrity
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHostMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		host    string
+		want    bool
+	}{
+		{pattern: "*", host: "reviews.prod.svc.cluster.local", want: true},
+		{pattern: "*.example.com", host: "api.example.com", want: true},
+		{pattern: "*.example.com", host: "example.com", want: false},
+		{pattern: "reviews.prod.svc.cluster.local", host: "reviews.prod.svc.cluster.local", want: true},
+		{pattern: "reviews.prod.svc.cluster.local", host: "ratings.prod.svc.cluster.local", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := hostMatches(tt.pattern, tt.host); got != tt.want {
+			t.Errorf("hostMatches(%q, %q) = %v, want %v", tt.pattern, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestCheckSidecarEgressViolations(t *testing.T) {
+	model, err := parseYAMLResources("testdata/sidecar-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse YAML file: %v", err)
+	}
+
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	var egressHosts int
+	db.QueryRow("SELECT COUNT(*) FROM sidecar_egress_hosts WHERE namespace = 'shop' AND name = 'default'").Scan(&egressHosts)
+	if egressHosts != 3 {
+		t.Errorf("Expected 3 egress hosts for shop Sidecar, got %d", egressHosts)
+	}
+
+	violations, err := operator.checkSidecarEgressViolations(db)
+	if err != nil {
+		t.Fatalf("Failed to check sidecar egress violations: %v", err)
+	}
+
+	expected := map[string]string{
+		"VirtualService/shop/reviews":        "ratings.prod.svc.cluster.local which Sidecar/shop/default",
+		"VirtualService/shop/mixed-ratings":  "ratings.prod.svc.cluster.local which Sidecar/shop/default",
+		"VirtualService/frontend/to-billing": "billing.payments.svc.cluster.local which Sidecar/istio-system/default",
+		"MeshService/shop/cart":              "cart.checkout.svc.cluster.local which Sidecar/shop/default",
+	}
+	for _, violation := range violations {
+		t.Logf("⚠️ Violation: %s %s - %s", violation.Type, violation.Resource, violation.Message)
+		if violation.Type != "SidecarEgressViolation" {
+			t.Errorf("Expected violation type 'SidecarEgressViolation', got '%s'", violation.Type)
+		}
+		fragment, ok := expected[violation.Resource]
+		if !ok {
+			t.Errorf("Unexpected violation for %s", violation.Resource)
+			continue
+		}
+		if !strings.Contains(violation.Message, fragment) {
+			t.Errorf("Expected message for %s to contain %q, got %q", violation.Resource, fragment, violation.Message)
+		}
+		delete(expected, violation.Resource)
+	}
+
+	for resource := range expected {
+		t.Errorf("Expected sidecar egress violation for %s", resource)
+	}
+}
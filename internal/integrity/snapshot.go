@@ -0,0 +1,372 @@
+package integrity
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// SnapshotVersion is the version of the snapshot bundle format
+const SnapshotVersion = "mesh-integrity.snapshot/v1"
+
+// SnapshotFormat is the file format of a snapshot
+type SnapshotFormat string
+
+const (
+	// SnapshotSQLite is a SQLite database file with the tables of the model
+	SnapshotSQLite SnapshotFormat = "sqlite"
+	// SnapshotJSON is a bundle of the rows of every table
+	SnapshotJSON SnapshotFormat = "json"
+	// SnapshotYAML is the bundle as YAML
+	SnapshotYAML SnapshotFormat = "yaml"
+)
+
+// sqliteHeader starts every SQLite database file
+var sqliteHeader = []byte("SQLite format 3\x00")
+
+// Snapshot is the full relational model as rows of its tables
+type Snapshot struct {
+	Version string          `json:"version"`
+	Tables  []SnapshotTable `json:"tables"`
+}
+
+// SnapshotTable holds the rows of one table, values in the order of Columns
+type SnapshotTable struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Rows    [][]any  `json:"rows"`
+}
+
+// ParseSnapshotFormat parses a format name, an empty name is derived from the file extension
+func ParseSnapshotFormat(name, path string) (SnapshotFormat, error) {
+	if name == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json":
+			return SnapshotJSON, nil
+		case ".yaml", ".yml":
+			return SnapshotYAML, nil
+		default:
+			return SnapshotSQLite, nil
+		}
+	}
+	switch format := SnapshotFormat(name); format {
+	case SnapshotSQLite, SnapshotJSON, SnapshotYAML:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown snapshot format %q, expected %s, %s or %s", name, SnapshotSQLite, SnapshotJSON, SnapshotYAML)
+}
+
+// snapshotTables lists the tables of the model in name order
+func snapshotTables(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// snapshotSchema lists the tables and indexes of the model with the SQL that created them
+func snapshotSchema(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT type, name, coalesce(sql, '') FROM sqlite_master
+		WHERE name NOT LIKE 'sqlite_%'
+		ORDER BY type, name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schema []string
+	for rows.Next() {
+		var kind, name, statement string
+		if err := rows.Scan(&kind, &name, &statement); err != nil {
+			return nil, err
+		}
+		schema = append(schema, kind+" "+name+": "+statement)
+	}
+	return schema, rows.Err()
+}
+
+// sqliteFileDSN opens the file as a SQLite URI. The path is escaped, otherwise "?", "#"
+// and "%" in it would be read as the query, the fragment or an escape of the URI.
+func sqliteFileDSN(path, mode string) string {
+	return fmt.Sprintf("file:%s?mode=%s", url.PathEscape(path), mode)
+}
+
+// ExportSnapshot reads every table of the database
+func (o *SQLiteIntegrityOperator) ExportSnapshot(ctx context.Context, db *sql.DB) (*Snapshot, error) {
+	tables, err := snapshotTables(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	snapshot := &Snapshot{Version: SnapshotVersion}
+	for _, name := range tables {
+		table, err := exportTable(ctx, db, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", name, err)
+		}
+		snapshot.Tables = append(snapshot.Tables, *table)
+	}
+	return snapshot, nil
+}
+
+func exportTable(ctx context.Context, db *sql.DB, name string) (*SnapshotTable, error) {
+	// Имя таблицы получено из sqlite_master, rowid сохраняет порядок загрузки
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %q ORDER BY rowid", name))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	table := &SnapshotTable{Name: name, Columns: columns, Rows: [][]any{}}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		table.Rows = append(table.Rows, values)
+	}
+	return table, rows.Err()
+}
+
+// ImportSnapshot создает in-memory базу со схемой createSchema и загружает строки снимка.
+// Внешние ключи выключены на время загрузки: снимок воспроизводит состояние вместе с
+// висячими ссылками, которые и проверяет CheckIntegrity.
+func (o *SQLiteIntegrityOperator) ImportSnapshot(ctx context.Context, snapshot *Snapshot) (*sql.DB, error) {
+	if snapshot.Version != SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %q, expected %q", snapshot.Version, SnapshotVersion)
+	}
+	db, err := o.CreateInMemoryDB(&RelationalModel{})
+	if err != nil {
+		return nil, err
+	}
+	if err := importTables(ctx, db, snapshot.Tables); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func importTables(ctx context.Context, db *sql.DB, tables []SnapshotTable) error {
+	known, err := snapshotTables(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return fmt.Errorf("failed to disable foreign keys: %w", err)
+	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range tables {
+		if !containsString(known, table.Name) {
+			return fmt.Errorf("unknown table %q", table.Name)
+		}
+		if len(table.Rows) == 0 {
+			continue
+		}
+		columns := make([]string, len(table.Columns))
+		for i, column := range table.Columns {
+			columns[i] = fmt.Sprintf("%q", column)
+		}
+		stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %q (%s) VALUES (%s)", table.Name,
+			strings.Join(columns, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")))
+		if err != nil {
+			return fmt.Errorf("failed to prepare %s: %w", table.Name, err)
+		}
+		for i, row := range table.Rows {
+			if len(row) != len(columns) {
+				stmt.Close()
+				return fmt.Errorf("%s row %d: expected %d values, got %d", table.Name, i, len(columns), len(row))
+			}
+			values := make([]any, len(row))
+			for j, value := range row {
+				values[j] = snapshotValue(value)
+			}
+			if _, err := stmt.ExecContext(ctx, values...); err != nil {
+				stmt.Close()
+				return fmt.Errorf("%s row %d: %w", table.Name, i, err)
+			}
+		}
+		stmt.Close()
+	}
+	return tx.Commit()
+}
+
+// snapshotValue converts numbers decoded with json.Decoder.UseNumber back to int64
+func snapshotValue(value any) any {
+	number, ok := value.(json.Number)
+	if !ok {
+		return value
+	}
+	if i, err := number.Int64(); err == nil {
+		return i
+	}
+	f, _ := number.Float64()
+	return f
+}
+
+// SaveSnapshot writes the database to a file in the format, replacing an existing file
+func (o *SQLiteIntegrityOperator) SaveSnapshot(ctx context.Context, db *sql.DB, path string, format SnapshotFormat) error {
+	if format == SnapshotSQLite {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		file, err := sql.Open("sqlite3", sqliteFileDSN(path, "rwc"))
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		defer file.Close()
+		if err := backupDB(ctx, file, db); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		return file.Close()
+	}
+
+	var buf bytes.Buffer
+	if err := o.WriteSnapshot(ctx, &buf, db, format); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// WriteSnapshot writes the database as a JSON or YAML bundle
+func (o *SQLiteIntegrityOperator) WriteSnapshot(ctx context.Context, w io.Writer, db *sql.DB, format SnapshotFormat) error {
+	snapshot, err := o.ExportSnapshot(ctx, db)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	switch format {
+	case SnapshotJSON:
+		data = append(data, '\n')
+	case SnapshotYAML:
+		if data, err = yaml.JSONToYAML(data); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("snapshot format %s is not a bundle", format)
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// LoadSnapshot imports a snapshot file of any format into an in-memory database
+func (o *SQLiteIntegrityOperator) LoadSnapshot(ctx context.Context, path string) (*sql.DB, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, sqliteHeader) {
+		return o.openSQLiteSnapshot(ctx, path)
+	}
+	snapshot, err := ParseSnapshot(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return o.ImportSnapshot(ctx, snapshot)
+}
+
+// IsSnapshot reports whether the data is a SQLite snapshot or a snapshot bundle
+func IsSnapshot(data []byte) bool {
+	if bytes.HasPrefix(data, sqliteHeader) {
+		return true
+	}
+	var header struct {
+		Version string `json:"version"`
+	}
+	return yaml.Unmarshal(data, &header) == nil && header.Version == SnapshotVersion
+}
+
+// ParseSnapshot decodes a JSON or YAML bundle
+func ParseSnapshot(data []byte) (*Snapshot, error) {
+	raw, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var snapshot Snapshot
+	if err := decoder.Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// openSQLiteSnapshot копирует файл снимка в in-memory базу, файл открывается только для чтения
+func (o *SQLiteIntegrityOperator) openSQLiteSnapshot(ctx context.Context, path string) (*sql.DB, error) {
+	file, err := sql.Open("sqlite3", sqliteFileDSN(path, "ro"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	// Снимок другой версии схемы дал бы ложные нарушения: таблицы, их столбцы и индексы
+	// должны совпадать с createSchema
+	expected, err := o.CreateInMemoryDB(&RelationalModel{})
+	if err != nil {
+		return nil, err
+	}
+	defer expected.Close()
+	want, err := snapshotSchema(ctx, expected)
+	if err != nil {
+		return nil, err
+	}
+	got, err := snapshotSchema(ctx, file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if !slices.Equal(got, want) {
+		return nil, fmt.Errorf("%s is not a snapshot of this version: schema differs from the relational model", path)
+	}
+	return o.CopyDB(ctx, file)
+}
@@ -0,0 +1,150 @@
+// Тесты экспорта и импорта снимков реляционной модели
+package integrity
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	model, err := parseYAMLResources("testdata/gateway-api-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse YAML file: %v", err)
+	}
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	expected, err := operator.CheckIntegrity(db)
+	if err != nil {
+		t.Fatalf("Integrity check failed: %v", err)
+	}
+	exported, err := operator.ExportSnapshot(ctx, db)
+	if err != nil {
+		t.Fatalf("Failed to export snapshot: %v", err)
+	}
+
+	dir := t.TempDir()
+	for _, file := range []string{"mesh.db", "mesh.json", "mesh.yaml"} {
+		path := filepath.Join(dir, file)
+		format, err := ParseSnapshotFormat("", path)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		if err := operator.SaveSnapshot(ctx, db, path, format); err != nil {
+			t.Fatalf("%s: failed to save snapshot: %v", file, err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || !IsSnapshot(data) {
+			t.Fatalf("%s: expected a snapshot file (%v)", file, err)
+		}
+
+		imported, err := operator.LoadSnapshot(ctx, path)
+		if err != nil {
+			t.Fatalf("%s: failed to load snapshot: %v", file, err)
+		}
+		report, err := operator.CheckIntegrity(imported)
+		if err != nil {
+			t.Fatalf("%s: integrity check failed: %v", file, err)
+		}
+		if !reflect.DeepEqual(report.Violations, expected.Violations) {
+			t.Errorf("%s: expected the violations of the original model, got %v", file, report.Violations)
+		}
+
+		// Повторный экспорт совпадает с исходным снимком построчно
+		reexported, err := operator.ExportSnapshot(ctx, imported)
+		if err != nil {
+			t.Fatalf("%s: failed to export snapshot: %v", file, err)
+		}
+		var want, got bytes.Buffer
+		operator.WriteSnapshot(ctx, &want, db, SnapshotJSON)
+		operator.WriteSnapshot(ctx, &got, imported, SnapshotJSON)
+		if len(reexported.Tables) != len(exported.Tables) || want.String() != got.String() {
+			t.Errorf("%s: re-exported snapshot differs from the original", file)
+		}
+		imported.Close()
+		t.Logf("✅ %s: %d tables, %d violations", file, len(reexported.Tables), len(report.Violations))
+	}
+}
+
+func TestSQLiteSnapshotFile(t *testing.T) {
+	ctx := context.Background()
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(&RelationalModel{})
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	// "?", "#" и "%" в пути не должны читаться как части URI
+	path := filepath.Join(t.TempDir(), "mesh #1?mode=memory%20.db")
+	if err := operator.SaveSnapshot(ctx, db, path, SnapshotSQLite); err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Expected the snapshot at %s: %v", path, err)
+	}
+	imported, err := operator.LoadSnapshot(ctx, path)
+	if err != nil {
+		t.Fatalf("Failed to load snapshot: %v", err)
+	}
+	imported.Close()
+
+	// Те же таблицы с другими столбцами - снимок другой версии схемы
+	file, err := sql.Open("sqlite3", sqliteFileDSN(path, "rw"))
+	if err != nil {
+		t.Fatalf("Failed to open snapshot: %v", err)
+	}
+	_, err = file.Exec("ALTER TABLE gateways ADD COLUMN extra TEXT")
+	file.Close()
+	if err != nil {
+		t.Fatalf("Failed to alter snapshot: %v", err)
+	}
+	if imported, err := operator.LoadSnapshot(ctx, path); err == nil || !strings.Contains(err.Error(), "schema differs") {
+		if imported != nil {
+			imported.Close()
+		}
+		t.Errorf("Expected a snapshot with another schema to be rejected, got %v", err)
+	}
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	ctx := context.Background()
+	operator := &SQLiteIntegrityOperator{}
+
+	for _, tt := range []struct {
+		name string
+		data string
+	}{
+		{"version", `{"version": "v0", "tables": []}`},
+		{"table", `{"version": "` + SnapshotVersion + `", "tables": [{"name": "unknown", "columns": ["name"], "rows": [["x"]]}]}`},
+		{"row", `{"version": "` + SnapshotVersion + `", "tables": [{"name": "gateways", "columns": ["namespace", "name"], "rows": [["x"]]}]}`},
+		{"column", `{"version": "` + SnapshotVersion + `", "tables": [{"name": "gateways", "columns": ["missing"], "rows": [["x"]]}]}`},
+	} {
+		snapshot, err := ParseSnapshot([]byte(tt.data))
+		if err != nil {
+			t.Fatalf("%s: failed to parse snapshot: %v", tt.name, err)
+		}
+		if db, err := operator.ImportSnapshot(ctx, snapshot); err == nil {
+			db.Close()
+			t.Errorf("%s: expected the import to fail", tt.name)
+		}
+	}
+
+	if IsSnapshot([]byte("apiVersion: v1\nkind: Service\n")) {
+		t.Error("Expected a manifest not to be a snapshot")
+	}
+	if _, err := ParseSnapshotFormat("xml", "mesh.xml"); err == nil {
+		t.Error("Expected an unknown format to fail")
+	}
+}
@@ -0,0 +1,249 @@
+package integrity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+)
+
+// Traffic directions of a reference: the referenced resource is in front of the referencing
+// one (a VirtualService references its Gateway) or behind it (a Service selects its Pods)
+const (
+	trafficUpstream   = "up"
+	trafficDownstream = "down"
+)
+
+// referenceEdgesSQL selects every reference of the model as (kind, namespace, name,
+// target kind, target namespace, target name, reference, direction). Ссылки без направления
+// (DestinationRule на Service) не лежат на пути трафика и попадают только в Explain.
+const referenceEdgesSQL = `
+	SELECT 'VirtualService', namespace, name, 'Gateway', gateway_namespace, gateway_name, 'spec.gateways', 'up'
+	FROM virtual_service_gateways WHERE gateway_namespace <> ''
+	UNION
+	SELECT 'VirtualService', namespace, name, 'VirtualService', delegate_namespace, delegate_name,
+		'http[' || http_index || '].delegate', 'down'
+	FROM virtual_service_delegates
+	UNION
+	SELECT 'VirtualService', d.namespace, d.name, 'Service', s.namespace, s.name,
+		'http[' || d.http_index || '].route[' || d.route_index || '].destination.host', 'down'
+	FROM virtual_service_destinations d JOIN services s ON lower(s.host) = d.canonical_host
+	UNION
+	SELECT 'DestinationRule', dr.namespace, dr.name, 'Service', s.namespace, s.name, 'spec.host', ''
+	FROM destination_rules dr JOIN services s ON lower(s.host) = dr.canonical_host
+	UNION
+	SELECT 'MeshService', namespace, name, 'Service', service_namespace, service_name, 'spec.serviceName', 'down'
+	FROM mesh_services
+	UNION
+	SELECT 'MeshService', namespace, name, 'Gateway', gateway_namespace, gateway_name, 'spec.gateway', 'up'
+	FROM mesh_services WHERE gateway_name <> ''
+	UNION
+	SELECT kind, namespace, name, ?, parent_namespace, parent_name, 'parentRefs[' || ref_index || ']', 'up'
+	FROM gateway_api_parent_refs WHERE parent_group = ? AND parent_kind = 'Gateway'
+	UNION
+	SELECT kind, namespace, name, 'Service', backend_namespace, backend_name,
+		'rules[' || rule_index || '].backendRefs[' || ref_index || ']', 'down'
+	FROM gateway_api_backend_refs WHERE backend_group = '' AND backend_kind = 'Service'
+	UNION
+	SELECT 'Service', s.namespace, s.name, 'Pod', w.namespace, w.name, 'spec.selector', 'down'
+	FROM services s JOIN workloads w ON w.namespace = s.namespace AND s.selector <> ''
+	 AND ` + "%s" + `
+	ORDER BY 1, 2, 3, 4, 5, 6, 7
+`
+
+// referenceEdge is a reference of one resource to another
+type referenceEdge struct {
+	from, to  string // Kind/namespace/name
+	reference string
+	direction string
+}
+
+// referenceGraph holds the references of the model in both directions and the resources it has
+type referenceGraph struct {
+	outgoing  map[string][]referenceEdge
+	incoming  map[string][]referenceEdge
+	resources map[string]string
+}
+
+func loadReferenceGraph(ctx context.Context, db *sql.DB) (*referenceGraph, error) {
+	resources, err := modelResources(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	graph := &referenceGraph{outgoing: map[string][]referenceEdge{}, incoming: map[string][]referenceEdge{}, resources: resources}
+
+	query := fmt.Sprintf(referenceEdgesSQL, selectorMatchesSQL("Service", "s.namespace", "s.name", "w"))
+	rows, err := db.QueryContext(ctx, query, gatewayAPIGatewayKind, gatewayAPIGroup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read references: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var kind, namespace, name, toKind, toNamespace, toName string
+		edge := referenceEdge{}
+		if err := rows.Scan(&kind, &namespace, &name, &toKind, &toNamespace, &toName, &edge.reference, &edge.direction); err != nil {
+			return nil, err
+		}
+		edge.from = fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+		edge.to = fmt.Sprintf("%s/%s/%s", toKind, toNamespace, toName)
+		graph.outgoing[edge.from] = append(graph.outgoing[edge.from], edge)
+		graph.incoming[edge.to] = append(graph.incoming[edge.to], edge)
+	}
+	return graph, rows.Err()
+}
+
+// TrafficNode is a resource on the traffic path with the resources further along the path
+type TrafficNode struct {
+	Resource string `json:"resource"`
+	// Reference is the field of the referencing resource the edge to the parent goes through
+	Reference string `json:"reference"`
+	// Missing is set for referenced resources that are not in the model
+	Missing bool `json:"missing,omitempty"`
+	// Cycle is set when the resource is already on the path, its children are not repeated
+	Cycle    bool           `json:"cycle,omitempty"`
+	Children []*TrafficNode `json:"children,omitempty"`
+}
+
+// DependencyTree is the traffic path through a resource
+type DependencyTree struct {
+	Resource string `json:"resource"`
+	Missing  bool   `json:"missing,omitempty"`
+	// Upstream leads from the resource towards the gateways that receive the traffic
+	Upstream []*TrafficNode `json:"upstream"`
+	// Downstream leads from the resource towards the pods that serve the traffic
+	Downstream []*TrafficNode `json:"downstream"`
+}
+
+// BuildDependencyTree строит путь трафика через ресурс по ссылкам модели: вверх до Gateway
+// (Istio и Gateway API), вниз до Pod. resource имеет вид Kind/namespace/name, как
+// ConstraintViolation.Resource, Gateway API Gateway обозначается Gateway.gateway.networking.k8s.io.
+func (o *SQLiteIntegrityOperator) BuildDependencyTree(ctx context.Context, db *sql.DB, resource string) (*DependencyTree, error) {
+	graph, err := loadReferenceGraph(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	_, exists := graph.resources[resource]
+	path := map[string]bool{resource: true}
+	return &DependencyTree{
+		Resource:   resource,
+		Missing:    !exists,
+		Upstream:   graph.walk(resource, trafficUpstream, path),
+		Downstream: graph.walk(resource, trafficDownstream, path),
+	}, nil
+}
+
+// neighbours returns the resources next to the resource in the direction with the reference
+// field of each edge
+func (g *referenceGraph) neighbours(resource, direction string) []referenceEdge {
+	var result []referenceEdge
+	// Ссылка в направлении трафика ведет к соседу, ссылка в обратном направлении - от него
+	for _, edge := range g.outgoing[resource] {
+		if edge.direction == direction {
+			result = append(result, referenceEdge{from: resource, to: edge.to, reference: edge.reference})
+		}
+	}
+	reverse := trafficDownstream
+	if direction == trafficDownstream {
+		reverse = trafficUpstream
+	}
+	for _, edge := range g.incoming[resource] {
+		if edge.direction == reverse {
+			result = append(result, referenceEdge{from: resource, to: edge.from, reference: edge.reference})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].to < result[j].to })
+	return result
+}
+
+func (g *referenceGraph) walk(resource, direction string, path map[string]bool) []*TrafficNode {
+	var nodes []*TrafficNode
+	for _, edge := range g.neighbours(resource, direction) {
+		_, exists := g.resources[edge.to]
+		node := &TrafficNode{Resource: edge.to, Reference: edge.reference, Missing: !exists}
+		if path[edge.to] {
+			node.Cycle = true
+		} else {
+			path[edge.to] = true
+			node.Children = g.walk(edge.to, direction, path)
+			delete(path, edge.to)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// Reference is a reference between the explained resource and another resource
+type Reference struct {
+	Resource  string `json:"resource"`
+	Reference string `json:"reference"` // поле ссылающегося ресурса
+	Missing   bool   `json:"missing,omitempty"`
+}
+
+// Explanation collects everything the model knows about one resource
+type Explanation struct {
+	Resource string `json:"resource"`
+	Missing  bool   `json:"missing,omitempty"`
+	// Violations are the violations of the resource
+	Violations []meshv1alpha1.ConstraintViolation `json:"violations"`
+	// Referencing are violations of other resources that name the resource
+	Referencing []meshv1alpha1.ConstraintViolation `json:"referencing"`
+	RepairPlans []meshv1alpha1.RepairAction        `json:"repairPlans"`
+	// References are the resources the resource references, ReferencedBy the ones referencing it
+	References   []Reference `json:"references"`
+	ReferencedBy []Reference `json:"referencedBy"`
+}
+
+// Explain отвечает на вопрос "почему": нарушения ресурса и нарушения других ресурсов,
+// в сообщении которых он упомянут, планы исправления и ссылки в обе стороны.
+func (o *SQLiteIntegrityOperator) Explain(ctx context.Context, db *sql.DB, report *IntegrityReport, resource string) (*Explanation, error) {
+	graph, err := loadReferenceGraph(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	_, exists := graph.resources[resource]
+	explanation := &Explanation{Resource: resource, Missing: !exists}
+
+	for _, violation := range report.Violations {
+		if violation.Resource == resource {
+			explanation.Violations = append(explanation.Violations, violation)
+		} else if mentions(violation.Message, resource) {
+			explanation.Referencing = append(explanation.Referencing, violation)
+		}
+	}
+	for _, repair := range report.RepairPlans {
+		if repair.Resource == resource {
+			explanation.RepairPlans = append(explanation.RepairPlans, repair)
+		}
+	}
+	for _, edge := range graph.outgoing[resource] {
+		_, exists := graph.resources[edge.to]
+		explanation.References = append(explanation.References, Reference{Resource: edge.to, Reference: edge.reference, Missing: !exists})
+	}
+	for _, edge := range graph.incoming[resource] {
+		explanation.ReferencedBy = append(explanation.ReferencedBy, Reference{Resource: edge.from, Reference: edge.reference})
+	}
+	return explanation, nil
+}
+
+// mentions reports whether the message names the resource as a whole word
+func mentions(message, resource string) bool {
+	for rest := message; ; {
+		index := strings.Index(rest, resource)
+		if index < 0 {
+			return false
+		}
+		end := index + len(resource)
+		if (index == 0 || !isNameByte(rest[index-1])) && (end == len(rest) || !isNameByte(rest[end])) {
+			return true
+		}
+		rest = rest[index+1:]
+	}
+}
+
+// isNameByte reports whether the byte can be part of a Kind/namespace/name reference
+func isNameByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '-' || b == '.' || b == '/'
+}
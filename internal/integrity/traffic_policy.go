@@ -0,0 +1,100 @@
+package integrity
+
+import (
+	"database/sql"
+	"fmt"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+)
+
+// checkTrafficPolicyViolations ищет противоречия в trafficPolicy DestinationRule
+func (o *SQLiteIntegrityOperator) checkTrafficPolicyViolations(db *sql.DB) ([]meshv1alpha1.ConstraintViolation, error) {
+	var violations []meshv1alpha1.ConstraintViolation
+
+	// 1. consistentHash на subset, который получает трафик через weighted split:
+	// VirtualService делит трафик случайно до балансировщика, и session affinity теряется.
+	// Subset без собственного loadBalancer наследует политику верхнего уровня.
+	rows, err := db.Query(`
+		SELECT DISTINCT dr.namespace, dr.name, vd.subset, tp.lb_consistent_hash, vd.namespace, vd.name, vd.weight
+		FROM virtual_service_destinations vd
+		JOIN destination_rules dr ON dr.canonical_host = vd.canonical_host
+		JOIN traffic_policies tp ON tp.namespace = dr.namespace AND tp.name = dr.name AND tp.port = 0
+		 AND (tp.subset = vd.subset OR (tp.subset = '' AND NOT EXISTS (
+			SELECT 1 FROM traffic_policies sp
+			WHERE sp.namespace = dr.namespace AND sp.name = dr.name AND sp.subset = vd.subset AND sp.port = 0
+			  AND (sp.lb_simple <> '' OR sp.lb_consistent_hash <> '')
+		 )))
+		WHERE tp.lb_consistent_hash <> ''
+		  AND vd.weight > 0 AND vd.weight < 100
+		ORDER BY dr.namespace, dr.name, vd.subset, vd.namespace, vd.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var ns, name, subset, hashKey, vsNs, vsName string
+		var weight int32
+		if err := rows.Scan(&ns, &name, &subset, &hashKey, &vsNs, &vsName, &weight); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		target := "host"
+		if subset != "" {
+			target = fmt.Sprintf("subset %s", subset)
+		}
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "TrafficPolicyViolation",
+			Resource: fmt.Sprintf("DestinationRule/%s/%s", ns, name),
+			Message: fmt.Sprintf("consistentHash (%s) on %s contradicts weighted split traffic from VirtualService/%s/%s (weight %d): session affinity is not preserved",
+				hashKey, target, vsNs, vsName, weight),
+			Severity: "Warning",
+		})
+	}
+	rows.Close()
+
+	// 2. ISTIO_MUTUAL в сторону хоста ServiceEntry вне mesh: у внешнего сервиса нет sidecar
+	// и сертификата Istio, TLS handshake не пройдет
+	rows, err = db.Query(`
+		SELECT DISTINCT dr.namespace, dr.name, tp.subset, tp.port, se.namespace, se.name, se.host
+		FROM destination_rules dr
+		JOIN traffic_policies tp ON tp.namespace = dr.namespace AND tp.name = dr.name
+		JOIN service_entries se ON lower(se.host) = dr.canonical_host
+		WHERE tp.tls_mode = 'ISTIO_MUTUAL'
+		  AND se.location = 'MESH_EXTERNAL'
+		ORDER BY dr.namespace, dr.name, tp.subset, tp.port
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var ns, name, subset, seNs, seName, host string
+		var port int32
+		if err := rows.Scan(&ns, &name, &subset, &port, &seNs, &seName, &host); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "TrafficPolicyViolation",
+			Resource: fmt.Sprintf("DestinationRule/%s/%s", ns, name),
+			Message: fmt.Sprintf("ISTIO_MUTUAL TLS%s towards host %s of ServiceEntry/%s/%s which is MESH_EXTERNAL",
+				policyScope(subset, port), host, seNs, seName),
+			Severity: "Error",
+		})
+	}
+	rows.Close()
+
+	return violations, nil
+}
+
+// policyScope describes where in a DestinationRule a traffic policy is defined
+func policyScope(subset string, port int32) string {
+	switch {
+	case subset != "" && port != 0:
+		return fmt.Sprintf(" (subset %s, port %d)", subset, port)
+	case subset != "":
+		return fmt.Sprintf(" (subset %s)", subset)
+	case port != 0:
+		return fmt.Sprintf(" (port %d)", port)
+	}
+	return ""
+}
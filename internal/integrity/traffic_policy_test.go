@@ -0,0 +1,130 @@
+// Тесты для trafficPolicy DestinationRule
+package integrity
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadTrafficPolicies(t *testing.T) {
+	model, err := parseYAMLResources("testdata/traffic-policy-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse YAML file: %v", err)
+	}
+
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	// traffic_policy больше не теряется при загрузке
+	var rawPolicy string
+	if err := db.QueryRow("SELECT traffic_policy FROM destination_rules WHERE namespace = 'prod' AND name = 'reviews'").Scan(&rawPolicy); err != nil {
+		t.Fatalf("Failed to read traffic_policy: %v", err)
+	}
+	if rawPolicy == "" {
+		t.Error("Expected traffic_policy to be stored")
+	}
+
+	// Верхний уровень
+	var lbSimple, interval, ejection string
+	var maxConnections, pending, consecutive int32
+	err = db.QueryRow(`
+		SELECT lb_simple, max_connections, http1_max_pending_requests, outlier_consecutive_5xx_errors, outlier_interval, outlier_base_ejection_time
+		FROM traffic_policies WHERE namespace = 'prod' AND name = 'reviews' AND subset = '' AND port = 0
+	`).Scan(&lbSimple, &maxConnections, &pending, &consecutive, &interval, &ejection)
+	if err != nil {
+		t.Fatalf("Failed to read top-level traffic policy: %v", err)
+	}
+	if lbSimple != "LEAST_REQUEST" || maxConnections != 100 || pending != 10 || consecutive != 5 || interval != "10s" || ejection != "30s" {
+		t.Errorf("Unexpected top-level policy: lb=%s maxConnections=%d pending=%d consecutive5xx=%d interval=%s ejection=%s",
+			lbSimple, maxConnections, pending, consecutive, interval, ejection)
+	}
+
+	// portLevelSettings
+	var portTLS string
+	if err := db.QueryRow("SELECT tls_mode FROM traffic_policies WHERE name = 'reviews' AND subset = '' AND port = 9080").Scan(&portTLS); err != nil {
+		t.Fatalf("Failed to read port-level traffic policy: %v", err)
+	}
+	if portTLS != "ISTIO_MUTUAL" {
+		t.Errorf("Expected port-level TLS mode ISTIO_MUTUAL, got %s", portTLS)
+	}
+
+	// subset override
+	var hashKey string
+	if err := db.QueryRow("SELECT lb_consistent_hash FROM traffic_policies WHERE name = 'reviews' AND subset = 'v1' AND port = 0").Scan(&hashKey); err != nil {
+		t.Fatalf("Failed to read subset traffic policy: %v", err)
+	}
+	if hashKey != "httpHeaderName:x-user-id" {
+		t.Errorf("Expected subset consistentHash httpHeaderName:x-user-id, got %s", hashKey)
+	}
+
+	// localityLbSetting без simple не записывается как политика simple
+	var localitySimple, localityHash string
+	if err := db.QueryRow("SELECT lb_simple, lb_consistent_hash FROM traffic_policies WHERE name = 'reviews' AND subset = 'v3' AND port = 0").Scan(&localitySimple, &localityHash); err != nil {
+		t.Fatalf("Failed to read locality traffic policy: %v", err)
+	}
+	if localitySimple != "" || localityHash != "" {
+		t.Errorf("Expected no simple or consistentHash policy for localityLbSetting, got %q %q", localitySimple, localityHash)
+	}
+
+	var destinations int
+	db.QueryRow("SELECT COUNT(*) FROM virtual_service_destinations").Scan(&destinations)
+	if destinations != 2 {
+		t.Errorf("Expected 2 virtual service destinations, got %d", destinations)
+	}
+}
+
+func TestCheckTrafficPolicyViolations(t *testing.T) {
+	model, err := parseYAMLResources("testdata/traffic-policy-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse YAML file: %v", err)
+	}
+
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	violations, err := operator.checkTrafficPolicyViolations(db)
+	if err != nil {
+		t.Fatalf("Failed to check traffic policy violations: %v", err)
+	}
+
+	var foundConsistentHash, foundIstioMutual bool
+	for _, violation := range violations {
+		t.Logf("⚠️ Violation: %s %s - %s", violation.Type, violation.Resource, violation.Message)
+		if violation.Type != "TrafficPolicyViolation" {
+			t.Errorf("Expected violation type 'TrafficPolicyViolation', got '%s'", violation.Type)
+		}
+		switch violation.Resource {
+		case "DestinationRule/prod/reviews":
+			if strings.Contains(violation.Message, "subset v1") && strings.Contains(violation.Message, "VirtualService/prod/reviews-canary") {
+				foundConsistentHash = true
+			}
+			if strings.Contains(violation.Message, "subset v2") {
+				t.Errorf("Subset v2 inherits LEAST_REQUEST and must not be reported: %s", violation.Message)
+			}
+		case "DestinationRule/prod/payments-provider":
+			if strings.Contains(violation.Message, "ServiceEntry/prod/payments-provider") {
+				foundIstioMutual = true
+			}
+		default:
+			t.Errorf("Unexpected violation for %s", violation.Resource)
+		}
+	}
+
+	if !foundConsistentHash {
+		t.Error("Expected consistentHash on weighted subset to be reported")
+	}
+	if !foundIstioMutual {
+		t.Error("Expected ISTIO_MUTUAL towards MESH_EXTERNAL ServiceEntry to be reported")
+	}
+	if len(violations) != 2 {
+		t.Errorf("Expected 2 traffic policy violations, got %d", len(violations))
+	}
+}
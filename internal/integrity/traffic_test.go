@@ -0,0 +1,168 @@
+// Тесты дерева зависимостей и объяснения нарушений ресурса
+package integrity
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// formatTree prints the nodes one per line, indented by depth
+func formatTree(nodes []*TrafficNode, depth int, lines *[]string) {
+	for _, node := range nodes {
+		line := strings.Repeat("  ", depth) + node.Resource
+		if node.Missing {
+			line += " (missing)"
+		}
+		if node.Cycle {
+			line += " (cycle)"
+		}
+		*lines = append(*lines, line)
+		formatTree(node.Children, depth+1, lines)
+	}
+}
+
+func TestBuildDependencyTree(t *testing.T) {
+	model, err := parseYAMLResources("testdata/traffic-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse YAML file: %v", err)
+	}
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		resource   string
+		upstream   []string
+		downstream []string
+	}{
+		{"Service/shop/cart", []string{
+			"VirtualService/shop/cart",
+			"  VirtualService/shop/shop",
+			"    Gateway/istio-system/internal (missing)",
+			"    Gateway/istio-system/public",
+		}, []string{
+			"Pod/shop/cart-1",
+		}},
+		{"Gateway/istio-system/public", nil, []string{
+			"VirtualService/shop/shop",
+			"  Service/shop/web",
+			"    Pod/shop/web-1",
+			"  VirtualService/shop/cart",
+			"    Service/shop/cart",
+			"      Pod/shop/cart-1",
+		}},
+		{"Pod/shop/web-1", []string{
+			"Service/shop/web",
+			"  HTTPRoute/shop/web",
+			"    Gateway.gateway.networking.k8s.io/infra/public",
+			"  VirtualService/shop/shop",
+			"    Gateway/istio-system/internal (missing)",
+			"    Gateway/istio-system/public",
+		}, nil},
+	}
+	for _, tt := range tests {
+		tree, err := operator.BuildDependencyTree(context.Background(), db, tt.resource)
+		if err != nil {
+			t.Fatalf("%s: failed to build dependency tree: %v", tt.resource, err)
+		}
+		var upstream, downstream []string
+		formatTree(tree.Upstream, 0, &upstream)
+		formatTree(tree.Downstream, 0, &downstream)
+		if fmt.Sprint(upstream) != fmt.Sprint(tt.upstream) || fmt.Sprint(downstream) != fmt.Sprint(tt.downstream) || tree.Missing {
+			t.Errorf("%s: expected\n%s\n--\n%s\ngot\n%s\n--\n%s", tt.resource, strings.Join(tt.upstream, "\n"),
+				strings.Join(tt.downstream, "\n"), strings.Join(upstream, "\n"), strings.Join(downstream, "\n"))
+		}
+	}
+
+	tree, err := operator.BuildDependencyTree(context.Background(), db, "Gateway/istio-system/internal")
+	if err != nil || !tree.Missing || len(tree.Downstream) != 1 {
+		t.Errorf("Expected the missing Gateway with the VirtualService bound to it, got %+v (%v)", tree, err)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	model, err := parseYAMLResources("testdata/traffic-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse YAML file: %v", err)
+	}
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+	report, err := operator.CheckIntegrity(db)
+	if err != nil {
+		t.Fatalf("Integrity check failed: %v", err)
+	}
+	if report.RepairPlans, err = operator.ComputeRepairPlans(db, report); err != nil {
+		t.Fatalf("Failed to compute repair plans: %v", err)
+	}
+
+	explanation, err := operator.Explain(context.Background(), db, report, "VirtualService/shop/shop")
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if len(explanation.Violations) == 0 || !strings.Contains(explanation.Violations[0].Message, "Gateway/istio-system/internal") {
+		t.Errorf("Expected the violation of the missing Gateway, got %v", explanation.Violations)
+	}
+	if len(explanation.RepairPlans) == 0 {
+		t.Error("Expected repair plans of the VirtualService")
+	}
+	var references []string
+	for _, reference := range explanation.References {
+		references = append(references, fmt.Sprintf("%s %s %v", reference.Resource, reference.Reference, reference.Missing))
+	}
+	expected := []string{
+		"Gateway/istio-system/internal spec.gateways true",
+		"Gateway/istio-system/public spec.gateways false",
+		"Service/shop/web http[1].route[0].destination.host false",
+		"VirtualService/shop/cart http[0].delegate false",
+	}
+	if fmt.Sprint(references) != fmt.Sprint(expected) {
+		t.Errorf("Expected references\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(references, "\n"))
+	}
+
+	// Отсутствующий ресурс объясняется нарушениями, которые на него ссылаются
+	explanation, err = operator.Explain(context.Background(), db, report, "Gateway/istio-system/internal")
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if !explanation.Missing || len(explanation.Referencing) == 0 || len(explanation.ReferencedBy) != 1 {
+		t.Errorf("Expected a missing Gateway referenced by VirtualService/shop/shop, got %+v", explanation)
+	}
+	explanation, err = operator.Explain(context.Background(), db, report, "Service/shop/cart")
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	var referencedBy []string
+	for _, reference := range explanation.ReferencedBy {
+		referencedBy = append(referencedBy, reference.Resource)
+	}
+	if fmt.Sprint(referencedBy) != "[DestinationRule/shop/cart VirtualService/shop/cart]" {
+		t.Errorf("Expected the DestinationRule and the VirtualService, got %v", referencedBy)
+	}
+}
+
+func TestMentions(t *testing.T) {
+	for _, tt := range []struct {
+		message, resource string
+		expected          bool
+	}{
+		{"References non-existent Service/shop/web", "Service/shop/web", true},
+		{"References non-existent Service/shop/web-v2", "Service/shop/web", false},
+		{"References non-existent VirtualService/shop/web", "Service/shop/web", false},
+		{"references non-existent listener admin of Gateway.gateway.networking.k8s.io/infra/public", "Gateway.gateway.networking.k8s.io/infra/public", true},
+		{"references non-existent listener admin of Gateway.gateway.networking.k8s.io/infra/public", "Gateway/infra/public", false},
+		{"References non-existent Gateway/infra/public", "Gateway.gateway.networking.k8s.io/infra/public", false},
+	} {
+		if got := mentions(tt.message, tt.resource); got != tt.expected {
+			t.Errorf("mentions(%q, %q) = %v, expected %v", tt.message, tt.resource, got, tt.expected)
+		}
+	}
+}
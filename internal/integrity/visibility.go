@@ -0,0 +1,139 @@
+package integrity
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+)
+
+// exportNamespaces expands an exportTo list into the namespaces a resource is visible from.
+// An empty list means the resource is exported to every namespace ("*"),
+// "." stands for the resource's own namespace and "~" hides it from everyone.
+func exportNamespaces(namespace, exportTo string) []string {
+	if exportTo == "" {
+		return []string{"*"}
+	}
+
+	var namespaces []string
+	for _, entry := range strings.Split(exportTo, ",") {
+		switch entry = strings.TrimSpace(entry); entry {
+		case "", "~":
+			continue
+		case ".":
+			namespaces = append(namespaces, namespace)
+		default:
+			namespaces = append(namespaces, entry)
+		}
+	}
+	return namespaces
+}
+
+// insertExports stores the expanded exportTo list of a resource in the exports table
+func insertExports(tx *sql.Tx, kind, namespace, name, exportTo string) error {
+	for _, target := range exportNamespaces(namespace, exportTo) {
+		if _, err := tx.Exec(
+			"INSERT OR IGNORE INTO exports (kind, namespace, name, export_namespace) VALUES (?, ?, ?, ?)",
+			kind, namespace, name, target,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkVisibilityViolations проверяет, что существующие ссылки видны из namespace ссылающегося ресурса
+func (o *SQLiteIntegrityOperator) checkVisibilityViolations(db *sql.DB) ([]meshv1alpha1.ConstraintViolation, error) {
+	var violations []meshv1alpha1.ConstraintViolation
+
+	// 1. VirtualService -> Service: сервис должен быть экспортирован в namespace VirtualService
+	rows, err := db.Query(`
+		SELECT vs.namespace, vs.name, s.namespace, s.name, s.export_to
+		FROM virtual_services vs
+		JOIN services s ON vs.service_namespace = s.namespace AND vs.service_name = s.name
+		WHERE NOT EXISTS (
+			SELECT 1 FROM exports e
+			WHERE e.kind = 'Service' AND e.namespace = s.namespace AND e.name = s.name
+			  AND e.export_namespace IN ('*', vs.namespace)
+		)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var ns, name, svcNs, svcName, exportTo string
+		if err := rows.Scan(&ns, &name, &svcNs, &svcName, &exportTo); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "VisibilityViolation",
+			Resource: fmt.Sprintf("VirtualService/%s/%s", ns, name),
+			Message:  fmt.Sprintf("References Service/%s/%s not visible from namespace %s (exportTo: %s)", svcNs, svcName, ns, exportTo),
+			Severity: "Error",
+		})
+	}
+	rows.Close()
+
+	// 2. DestinationRule -> Service
+	rows, err = db.Query(`
+		SELECT dr.namespace, dr.name, s.namespace, s.name, s.export_to
+		FROM destination_rules dr
+		JOIN services s ON dr.service_namespace = s.namespace AND dr.service_name = s.name
+		WHERE NOT EXISTS (
+			SELECT 1 FROM exports e
+			WHERE e.kind = 'Service' AND e.namespace = s.namespace AND e.name = s.name
+			  AND e.export_namespace IN ('*', dr.namespace)
+		)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var ns, name, svcNs, svcName, exportTo string
+		if err := rows.Scan(&ns, &name, &svcNs, &svcName, &exportTo); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "VisibilityViolation",
+			Resource: fmt.Sprintf("DestinationRule/%s/%s", ns, name),
+			Message:  fmt.Sprintf("References Service/%s/%s not visible from namespace %s (exportTo: %s)", svcNs, svcName, ns, exportTo),
+			Severity: "Error",
+		})
+	}
+	rows.Close()
+
+	// 3. VirtualService -> Gateway: у Gateway нет exportTo, но сам VirtualService
+	// должен быть экспортирован в namespace шлюза, иначе шлюз его не увидит
+	rows, err = db.Query(`
+		SELECT vs.namespace, vs.name, gw.namespace, gw.name, vs.export_to
+		FROM virtual_services vs
+		JOIN gateways gw ON vs.gateway_namespace = gw.namespace AND vs.gateway_name = gw.name
+		WHERE NOT EXISTS (
+			SELECT 1 FROM exports e
+			WHERE e.kind = 'VirtualService' AND e.namespace = vs.namespace AND e.name = vs.name
+			  AND e.export_namespace IN ('*', gw.namespace)
+		)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var ns, name, gwNs, gwName, exportTo string
+		if err := rows.Scan(&ns, &name, &gwNs, &gwName, &exportTo); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "VisibilityViolation",
+			Resource: fmt.Sprintf("VirtualService/%s/%s", ns, name),
+			Message:  fmt.Sprintf("Bound to Gateway/%s/%s but not visible from namespace %s (exportTo: %s)", gwNs, gwName, gwNs, exportTo),
+			Severity: "Error",
+		})
+	}
+	rows.Close()
+
+	return violations, nil
+}
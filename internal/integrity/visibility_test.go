@@ -0,0 +1,107 @@
+// Тесты для exportTo visibility
+package integrity
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportNamespaces(t *testing.T) {
+	tests := []struct {
+		name     string
+		exportTo string
+		want     []string
+	}{
+		{name: "omitted means everywhere", exportTo: "", want: []string{"*"}},
+		{name: "dot is own namespace", exportTo: ".", want: []string{"payments"}},
+		{name: "tilde hides", exportTo: "~", want: nil},
+		{name: "explicit list", exportTo: ".,shop, istio-system", want: []string{"payments", "shop", "istio-system"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exportNamespaces("payments", tt.exportTo); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("exportNamespaces() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckVisibilityViolations(t *testing.T) {
+	operator := &SQLiteIntegrityOperator{}
+
+	model := &RelationalModel{
+		Services: []ServiceRecord{
+			{Namespace: "payments", Name: "api", Host: "api.payments.svc.cluster.local", Port: 8080, Protocol: "TCP", ExportTo: "."},
+			{Namespace: "payments", Name: "public", Host: "public.payments.svc.cluster.local", Port: 8080, Protocol: "TCP"},
+			{Namespace: "catalog", Name: "items", Host: "items.catalog.svc.cluster.local", Port: 8080, Protocol: "TCP", ExportTo: ".,shop"},
+		},
+		Gateways: []GatewayRecord{
+			{Namespace: "istio-system", Name: "public-gateway"},
+		},
+		VirtualServices: []VirtualServiceRecord{
+			// ❌ payments/api экспортирован только в "."
+			{Namespace: "shop", Name: "checkout", GatewayNamespace: "istio-system", GatewayName: "public-gateway",
+				Host: "checkout.example.com", ServiceNamespace: "payments", ServiceName: "api"},
+			// ✅ сервис без exportTo виден везде
+			{Namespace: "shop", Name: "public", GatewayNamespace: "istio-system", GatewayName: "public-gateway",
+				Host: "public.example.com", ServiceNamespace: "payments", ServiceName: "public"},
+			// ✅ catalog/items экспортирован в shop
+			{Namespace: "shop", Name: "items", GatewayNamespace: "istio-system", GatewayName: "public-gateway",
+				Host: "items.example.com", ServiceNamespace: "catalog", ServiceName: "items"},
+			// ❌ сам VirtualService не виден шлюзу из istio-system
+			{Namespace: "payments", Name: "private", GatewayNamespace: "istio-system", GatewayName: "public-gateway",
+				Host: "private.example.com", ServiceNamespace: "payments", ServiceName: "api", ExportTo: "."},
+		},
+		DestinationRules: []DestinationRuleRecord{
+			// ❌ DestinationRule в другом namespace не видит payments/api
+			{Namespace: "shop", Name: "api-dr", Host: "api.payments.svc.cluster.local", ServiceNamespace: "payments", ServiceName: "api"},
+			// ✅ DestinationRule в том же namespace
+			{Namespace: "payments", Name: "api-dr", Host: "api.payments.svc.cluster.local", ServiceNamespace: "payments", ServiceName: "api"},
+		},
+	}
+
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	violations, err := operator.checkVisibilityViolations(db)
+	if err != nil {
+		t.Fatalf("Failed to check visibility violations: %v", err)
+	}
+
+	expected := map[string]string{
+		"VirtualService/shop/checkout":    "References Service/payments/api not visible from namespace shop",
+		"DestinationRule/shop/api-dr":     "References Service/payments/api not visible from namespace shop",
+		"VirtualService/payments/private": "not visible from namespace istio-system",
+	}
+	if len(violations) != len(expected) {
+		t.Errorf("Expected %d visibility violations, got %d", len(expected), len(violations))
+	}
+	for _, violation := range violations {
+		t.Logf("⚠️ Violation: %s %s - %s", violation.Type, violation.Resource, violation.Message)
+		if violation.Type != "VisibilityViolation" {
+			t.Errorf("Expected violation type 'VisibilityViolation', got '%s'", violation.Type)
+		}
+		want, ok := expected[violation.Resource]
+		if !ok {
+			t.Errorf("Unexpected violation for %s", violation.Resource)
+			continue
+		}
+		if !strings.Contains(violation.Message, want) {
+			t.Errorf("Expected message for %s to contain %q, got %q", violation.Resource, want, violation.Message)
+		}
+	}
+
+	// VisibilityViolation входит в общий отчет
+	report, err := operator.CheckIntegrity(db)
+	if err != nil {
+		t.Fatalf("Failed to check integrity: %v", err)
+	}
+	if report.IsConsistent {
+		t.Error("Expected model with invisible references to be inconsistent")
+	}
+}
@@ -0,0 +1,221 @@
+package integrity
+
+import (
+	"database/sql"
+	"fmt"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+)
+
+// totalWeight is the sum Istio expects from the weights of a traffic split
+const totalWeight = 100
+
+// routeWeightsSQL aggregates the destinations of every http[] route; a route with a single
+// destination and no weight sends all traffic to it
+const routeWeightsSQL = `
+	route_weights AS (
+		SELECT namespace, name, http_index, COUNT(*) AS destinations, SUM(weight) AS total,
+			group_concat(host || CASE WHEN subset <> '' THEN ':' || subset ELSE '' END || '=' || weight, ', ') AS weights
+		FROM (SELECT * FROM virtual_service_destinations ORDER BY namespace, name, http_index, route_index)
+		GROUP BY namespace, name, http_index
+	)
+`
+
+// checkWeightViolations проверяет weighted split в VirtualService http[].route[] и MeshService subsets:
+// сумма весов равна 100, destinations с весом 0 не получают трафик, а subset с весом
+// выбирает хотя бы один pod сервиса
+func (o *SQLiteIntegrityOperator) checkWeightViolations(db *sql.DB) ([]meshv1alpha1.ConstraintViolation, error) {
+	var violations []meshv1alpha1.ConstraintViolation
+
+	// 1. Сумма весов route. Единственный destination может не указывать weight.
+	rows, err := db.Query(`
+		WITH `+routeWeightsSQL+`
+		SELECT namespace, name, http_index, total, weights
+		FROM route_weights
+		WHERE total <> ? AND NOT (destinations = 1 AND total = 0)
+		ORDER BY namespace, name, http_index
+	`, totalWeight)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var ns, name, weights string
+		var httpIndex, total int
+		if err := rows.Scan(&ns, &name, &httpIndex, &total, &weights); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "WeightViolation",
+			Resource: fmt.Sprintf("VirtualService/%s/%s", ns, name),
+			Message:  fmt.Sprintf("http[%d].route weights sum to %d, expected %d (%s)", httpIndex, total, totalWeight, weights),
+			Severity: "Error",
+		})
+	}
+	rows.Close()
+
+	// 2. Destination с весом 0 рядом с взвешенными destinations не получает трафик
+	rows, err = db.Query(`
+		WITH ` + routeWeightsSQL + `
+		SELECT vd.namespace, vd.name, vd.http_index, vd.route_index, vd.host, vd.subset
+		FROM virtual_service_destinations vd
+		JOIN route_weights rw ON rw.namespace = vd.namespace AND rw.name = vd.name AND rw.http_index = vd.http_index
+		WHERE vd.weight = 0 AND rw.destinations > 1 AND rw.total > 0
+		ORDER BY vd.namespace, vd.name, vd.http_index, vd.route_index
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var ns, name, host, subset string
+		var httpIndex, routeIndex int
+		if err := rows.Scan(&ns, &name, &httpIndex, &routeIndex, &host, &subset); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "WeightViolation",
+			Resource: fmt.Sprintf("VirtualService/%s/%s", ns, name),
+			Message:  fmt.Sprintf("http[%d].route[%d] to %s receives no traffic (weight 0)", httpIndex, routeIndex, destinationLabel(host, subset)),
+			Severity: "Warning",
+		})
+	}
+	rows.Close()
+
+	// 3. Subset с трафиком, labels которого не выбирают ни одного pod сервиса.
+	// Проверяется только для Service с selector в namespace, где известны pods.
+	rows, err = db.Query(`
+		WITH `+routeWeightsSQL+`
+		SELECT DISTINCT vd.namespace, vd.name, vd.http_index, vd.route_index, vd.host, vd.subset,
+			CASE WHEN vd.weight = 0 THEN ? ELSE vd.weight END,
+			dr.namespace, dr.name, dss.labels, s.namespace, s.name
+		FROM virtual_service_destinations vd
+		JOIN route_weights rw ON rw.namespace = vd.namespace AND rw.name = vd.name AND rw.http_index = vd.http_index
+		JOIN destination_rules dr ON dr.canonical_host = vd.canonical_host
+		JOIN destination_rule_subsets dss ON dss.namespace = dr.namespace AND dss.name = dr.name AND dss.subset = vd.subset
+		JOIN services s ON lower(s.host) = vd.canonical_host
+		WHERE vd.subset <> '' AND s.selector <> ''
+		  AND (vd.weight > 0 OR (rw.destinations = 1 AND rw.total = 0))
+		  AND EXISTS (SELECT 1 FROM workloads w0 WHERE w0.namespace = s.namespace)
+		  AND NOT EXISTS (
+			SELECT 1 FROM workloads w
+			WHERE w.namespace = s.namespace
+			  AND `+selectorMatchesSQL("Service", "s.namespace", "s.name", "w")+`
+			  AND `+selectorMatchesSQL("DestinationRuleSubset", "dss.namespace", "dss.name || '/' || dss.subset", "w")+`
+		  )
+		ORDER BY vd.namespace, vd.name, vd.http_index, vd.route_index, dr.namespace, dr.name
+	`, totalWeight)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var ns, name, host, subset, drNs, drName, labels, svcNs, svcName string
+		var httpIndex, routeIndex, weight int
+		if err := rows.Scan(&ns, &name, &httpIndex, &routeIndex, &host, &subset, &weight,
+			&drNs, &drName, &labels, &svcNs, &svcName); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "WeightViolation",
+			Resource: fmt.Sprintf("VirtualService/%s/%s", ns, name),
+			Message: fmt.Sprintf("http[%d].route[%d] sends %d%% to %s, but subset labels {%s} of DestinationRule/%s/%s select no pods of Service/%s/%s",
+				httpIndex, routeIndex, weight, destinationLabel(host, subset), labels, drNs, drName, svcNs, svcName),
+			Severity: "Error",
+		})
+	}
+	rows.Close()
+
+	// 4. MeshService subsets: если веса заданы, их сумма равна 100
+	rows, err = db.Query(`
+		SELECT namespace, name, SUM(weight),
+			group_concat(subset || '=' || weight, ', ')
+		FROM (SELECT * FROM mesh_service_subsets ORDER BY namespace, name, subset)
+		GROUP BY namespace, name
+		HAVING SUM(weight) > 0 AND SUM(weight) <> ?
+		ORDER BY namespace, name
+	`, totalWeight)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var ns, name, weights string
+		var total int
+		if err := rows.Scan(&ns, &name, &total, &weights); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		violations = append(violations, meshv1alpha1.ConstraintViolation{
+			Type:     "WeightViolation",
+			Resource: fmt.Sprintf("MeshService/%s/%s", ns, name),
+			Message:  fmt.Sprintf("subset weights sum to %d, expected %d (%s)", total, totalWeight, weights),
+			Severity: "Error",
+		})
+	}
+	rows.Close()
+
+	// 5. MeshService subsets с весом 0 и subsets с трафиком без pods
+	rows, err = db.Query(`
+		WITH subset_weights AS (
+			SELECT namespace, name, COUNT(*) AS subsets, SUM(weight) AS total
+			FROM mesh_service_subsets
+			GROUP BY namespace, name
+		)
+		SELECT mss.namespace, mss.name, mss.subset, mss.labels, mss.weight, sw.subsets, sw.total,
+			ms.service_namespace, ms.service_name,
+			s.namespace IS NOT NULL AND s.selector <> ''
+			  AND EXISTS (SELECT 1 FROM workloads w0 WHERE w0.namespace = s.namespace)
+			  AND NOT EXISTS (
+				SELECT 1 FROM workloads w
+				WHERE w.namespace = s.namespace
+				  AND ` + selectorMatchesSQL("Service", "s.namespace", "s.name", "w") + `
+				  AND ` + selectorMatchesSQL("MeshServiceSubset", "mss.namespace", "mss.name || '/' || mss.subset", "w") + `
+			  )
+		FROM mesh_service_subsets mss
+		JOIN subset_weights sw ON sw.namespace = mss.namespace AND sw.name = mss.name
+		JOIN mesh_services ms ON ms.namespace = mss.namespace AND ms.name = mss.name
+		LEFT JOIN services s ON s.namespace = ms.service_namespace AND s.name = ms.service_name
+		ORDER BY mss.namespace, mss.name, mss.subset
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ns, name, subset, labels, svcNs, svcName string
+		var weight, subsets, total int
+		var empty bool
+		if err := rows.Scan(&ns, &name, &subset, &labels, &weight, &subsets, &total, &svcNs, &svcName, &empty); err != nil {
+			return nil, err
+		}
+		resource := fmt.Sprintf("MeshService/%s/%s", ns, name)
+		switch {
+		case weight == 0 && subsets > 1 && total > 0:
+			violations = append(violations, meshv1alpha1.ConstraintViolation{
+				Type:     "WeightViolation",
+				Resource: resource,
+				Message:  fmt.Sprintf("subset %s receives no traffic (weight 0)", subset),
+				Severity: "Warning",
+			})
+		case empty && (weight > 0 || subsets == 1):
+			violations = append(violations, meshv1alpha1.ConstraintViolation{
+				Type:     "WeightViolation",
+				Resource: resource,
+				Message: fmt.Sprintf("subset %s receives traffic, but its labels {%s} select no pods of Service/%s/%s",
+					subset, labels, svcNs, svcName),
+				Severity: "Error",
+			})
+		}
+	}
+
+	return violations, rows.Err()
+}
+
+// destinationLabel formats a route destination as host or host subset <name>
+func destinationLabel(host, subset string) string {
+	if subset == "" {
+		return host
+	}
+	return fmt.Sprintf("%s subset %s", host, subset)
+}
@@ -0,0 +1,67 @@
+// Тесты для weighted split VirtualService и MeshService subsets
+package integrity
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckWeightViolations(t *testing.T) {
+	model, err := parseYAMLResources("testdata/weight-resources.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse YAML file: %v", err)
+	}
+
+	operator := &SQLiteIntegrityOperator{}
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer db.Close()
+
+	var subsets int
+	db.QueryRow("SELECT COUNT(*) FROM destination_rule_subsets").Scan(&subsets)
+	if subsets != 3 {
+		t.Errorf("Expected 3 DestinationRule subsets, got %d", subsets)
+	}
+
+	violations, err := operator.checkWeightViolations(db)
+	if err != nil {
+		t.Fatalf("Failed to check weight violations: %v", err)
+	}
+
+	expected := []struct {
+		violationType string
+		resource      string
+		severity      string
+		fragment      string
+	}{
+		{"WeightViolation", "VirtualService/bookinfo/reviews-split", "Error", "http[0].route weights sum to 110, expected 100 (reviews:v1=80, reviews:v2=30)"},
+		{"WeightViolation", "VirtualService/bookinfo/reviews-split", "Warning", "http[1].route[1] to reviews subset v2 receives no traffic (weight 0)"},
+		{"WeightViolation", "VirtualService/bookinfo/reviews-split", "Error", "http[2].route[0] sends 100% to reviews subset v3, but subset labels {version=v3} of DestinationRule/bookinfo/reviews select no pods of Service/bookinfo/reviews"},
+		{"WeightViolation", "VirtualService/bookinfo/reviews-split", "Error", "http[3].route[1] sends 50% to reviews subset v3"},
+		{"WeightViolation", "MeshService/bookinfo/reviews", "Error", "subset weights sum to 110, expected 100 (canary=20, stable=90)"},
+		{"WeightViolation", "MeshService/bookinfo/reviews", "Error", "subset canary receives traffic, but its labels {version=v3} select no pods of Service/bookinfo/reviews"},
+		{"WeightViolation", "MeshService/bookinfo/ratings", "Warning", "subset canary receives no traffic (weight 0)"},
+	}
+
+	for _, violation := range violations {
+		t.Logf("⚠️ Violation: %s %s - %s", violation.Type, violation.Resource, violation.Message)
+	}
+	for _, want := range expected {
+		found := false
+		for _, violation := range violations {
+			if violation.Type == want.violationType && violation.Resource == want.resource &&
+				violation.Severity == want.severity && strings.Contains(violation.Message, want.fragment) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Expected %s %s for %s containing %q", want.severity, want.violationType, want.resource, want.fragment)
+		}
+	}
+	if len(violations) != len(expected) {
+		t.Errorf("Expected %d weight violations, got %d", len(expected), len(violations))
+	}
+}
@@ -0,0 +1,269 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+	"github.com/mdarin/istio-integrity-operator/internal/integrity/loader"
+)
+
+var integritylog = logf.Log.WithName("integrity-webhook")
+
+// Policy defines how the webhook treats violations introduced by a change
+type Policy string
+
+const (
+	// PolicyDeny rejects changes that introduce Error violations, Warnings are returned as admission warnings
+	PolicyDeny Policy = "deny"
+	// PolicyWarn admits every change and returns all introduced violations as admission warnings
+	PolicyWarn Policy = "warn"
+)
+
+// DeletionOverrideAnnotation allows deleting an object other resources still reference.
+// Without it DELETE is refused with the list of dependents regardless of the policy.
+const DeletionOverrideAnnotation = "mesh.operator.istio.io/allow-delete-with-dependents"
+
+// ParsePolicy validates the value of the --integrity-webhook-policy flag
+func ParsePolicy(value string) (Policy, error) {
+	switch policy := Policy(strings.ToLower(value)); policy {
+	case PolicyDeny, PolicyWarn:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown integrity webhook policy %q, expected %q or %q", value, PolicyDeny, PolicyWarn)
+}
+
+// integrityWebhookPaths are the paths the integrity webhook is served on, one per kind
+var integrityWebhookPaths = []string{
+	"/validate-networking-istio-io-v1beta1-virtualservice",
+	"/validate-networking-istio-io-v1beta1-destinationrule",
+	"/validate-networking-istio-io-v1beta1-gateway",
+	"/validate--v1-service",
+}
+
+// SetupIntegrityWebhookWithManager registers the integrity webhook for VirtualService,
+// DestinationRule, Gateway and Service. Objects are decoded by the manifest loader, so every
+// version of a kind the CLI reads is admitted the same way.
+func SetupIntegrityWebhookWithManager(mgr ctrl.Manager, policy Policy) error {
+	validator := &IntegrityValidator{Client: mgr.GetClient(), Policy: policy}
+	for _, path := range integrityWebhookPaths {
+		mgr.GetWebhookServer().Register(path, &webhook.Admission{Handler: validator})
+	}
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-networking-istio-io-v1beta1-virtualservice,mutating=false,failurePolicy=ignore,sideEffects=None,groups=networking.istio.io,resources=virtualservices,verbs=create;update;delete,versions=v1alpha3;v1beta1;v1,name=vvirtualservice-v1beta1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-networking-istio-io-v1beta1-destinationrule,mutating=false,failurePolicy=ignore,sideEffects=None,groups=networking.istio.io,resources=destinationrules,verbs=create;update;delete,versions=v1alpha3;v1beta1;v1,name=vdestinationrule-v1beta1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-networking-istio-io-v1beta1-gateway,mutating=false,failurePolicy=ignore,sideEffects=None,groups=networking.istio.io,resources=gateways,verbs=create;update;delete,versions=v1alpha3;v1beta1;v1,name=vgateway-v1beta1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate--v1-service,mutating=false,failurePolicy=ignore,sideEffects=None,groups="",resources=services,verbs=create;update;delete,versions=v1,name=vservice-v1.kb.io,admissionReviewVersions=v1
+
+// The model is built from the manager cache, so the webhook reads every kind it is built from.
+// +kubebuilder:rbac:groups=core,resources=namespaces;serviceaccounts;services;pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=networking.istio.io,resources=virtualservices;destinationrules;gateways;serviceentries;sidecars,verbs=get;list;watch
+// +kubebuilder:rbac:groups=security.istio.io,resources=peerauthentications;authorizationpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways;httproutes;grpcroutes;referencegrants,verbs=get;list;watch
+
+// IntegrityValidator applies the incoming object to the relational model of the cluster
+// and rejects the change when CheckIntegrity reports violations the cluster did not have.
+// Deleting an object other resources reference is refused unless DeletionOverrideAnnotation is set.
+type IntegrityValidator struct {
+	Client client.Client
+	Policy Policy
+}
+
+var _ admission.CustomValidator = &IntegrityValidator{}
+var _ admission.Handler = &IntegrityValidator{}
+
+// Handle implements admission.Handler: the object is decoded with loader.Decode, as manifests
+// are, and validated as a create, update or delete
+func (v *IntegrityValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	raw := req.Object.Raw
+	if req.Operation == admissionv1.Delete {
+		raw = req.OldObject.Raw
+	}
+	obj, err := loader.Decode(raw)
+	if err != nil {
+		// Как и при ошибке построения модели, webhook не блокирует изменение
+		integritylog.Error(err, "integrity check skipped", "kind", req.Kind, "namespace", req.Namespace, "name", req.Name)
+		return admission.Allowed("").WithWarnings(fmt.Sprintf("integrity check skipped: %v", err))
+	}
+
+	var warnings admission.Warnings
+	switch req.Operation {
+	case admissionv1.Create:
+		warnings, err = v.ValidateCreate(ctx, obj)
+	case admissionv1.Update:
+		warnings, err = v.ValidateUpdate(ctx, nil, obj)
+	case admissionv1.Delete:
+		warnings, err = v.ValidateDelete(ctx, obj)
+	default:
+		return admission.Allowed("")
+	}
+	if err != nil {
+		return admission.Denied(err.Error()).WithWarnings(warnings...)
+	}
+	return admission.Allowed("").WithWarnings(warnings...)
+}
+
+// ValidateCreate implements admission.CustomValidator
+func (v *IntegrityValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return v.validate(ctx, obj, false)
+}
+
+// ValidateUpdate implements admission.CustomValidator
+func (v *IntegrityValidator) ValidateUpdate(ctx context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	return v.validate(ctx, newObj, false)
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (v *IntegrityValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return v.validate(ctx, obj, true)
+}
+
+// validate сравнивает нарушения модели кластера до и после изменения
+func (v *IntegrityValidator) validate(ctx context.Context, obj runtime.Object, remove bool) (admission.Warnings, error) {
+	object, ok := obj.(client.Object)
+	if !ok {
+		return nil, fmt.Errorf("expected a Kubernetes object but got %T", obj)
+	}
+	log := integritylog.WithValues("kind", fmt.Sprintf("%T", obj), "namespace", object.GetNamespace(), "name", object.GetName())
+
+	introduced, dependents, err := v.introducedViolations(ctx, object, remove)
+	if err != nil {
+		// Webhook не должен блокировать кластер, если модель не удалось построить
+		log.Error(err, "integrity check skipped")
+		return admission.Warnings{fmt.Sprintf("integrity check skipped: %v", err)}, nil
+	}
+
+	// Защита от удаления объекта, на который ссылаются другие ресурсы
+	if len(dependents) > 0 {
+		if !deletionOverridden(object) {
+			log.Info("Deletion refused, object has dependents", "dependents", len(dependents))
+			return nil, dependentsError(dependents)
+		}
+		log.Info("Deletion of object with dependents allowed by annotation", "dependents", len(dependents))
+		warnings, _ := decide(PolicyWarn, introduced)
+		return append(admission.Warnings{dependentsMessage(dependents)}, warnings...), nil
+	}
+
+	if len(introduced) > 0 {
+		log.Info("Change introduces integrity violations", "violations", len(introduced), "policy", v.Policy)
+	}
+	return decide(v.Policy, introduced)
+}
+
+// introducedViolations checks the model before and after applying the object. When the
+// object is removed it also returns the resources that reference it.
+func (v *IntegrityValidator) introducedViolations(ctx context.Context, obj client.Object, remove bool) ([]meshv1alpha1.ConstraintViolation, []integrity.Dependent, error) {
+	operator := integrity.NewSQLiteIntegrityOperator(v.Client)
+	model, err := operator.BuildRelationalModel(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var dependents []integrity.Dependent
+	before, err := checkModel(operator, model, func(db *sql.DB) error {
+		kind := integrity.DependencyKind(obj)
+		if !remove || kind == "" {
+			return nil
+		}
+		dependents, err = operator.FindDependents(db, kind, obj.GetNamespace(), obj.GetName())
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if remove {
+		model.RemoveObject(obj)
+	} else if err := model.AddObject(obj); err != nil {
+		return nil, nil, err
+	}
+
+	after, err := checkModel(operator, model, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return integrity.DiffViolations(before.Violations, after.Violations), dependents, nil
+}
+
+// checkModel loads the model into a database and checks it; inspect, when given, runs
+// against the same database before it is closed
+func checkModel(operator *integrity.SQLiteIntegrityOperator, model *integrity.RelationalModel, inspect func(*sql.DB) error) (*integrity.IntegrityReport, error) {
+	db, err := operator.CreateInMemoryDB(model)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	if inspect != nil {
+		if err := inspect(db); err != nil {
+			return nil, err
+		}
+	}
+	return operator.CheckIntegrity(db)
+}
+
+// deletionOverridden reports whether the object carries DeletionOverrideAnnotation
+func deletionOverridden(obj client.Object) bool {
+	return strings.EqualFold(obj.GetAnnotations()[DeletionOverrideAnnotation], "true")
+}
+
+// dependentsMessage lists the dependents with the fields they reference the object through
+func dependentsMessage(dependents []integrity.Dependent) string {
+	items := make([]string, 0, len(dependents))
+	for _, dependent := range dependents {
+		items = append(items, fmt.Sprintf("%s (%s)", dependent.Resource(), strings.Join(dependent.References, ", ")))
+	}
+	return fmt.Sprintf("object is referenced by %d resource(s): %s", len(dependents), strings.Join(items, "; "))
+}
+
+func dependentsError(dependents []integrity.Dependent) error {
+	return fmt.Errorf("%s; set annotation %s=true to delete it anyway", dependentsMessage(dependents), DeletionOverrideAnnotation)
+}
+
+// decide turns introduced violations into admission warnings and, under PolicyDeny,
+// a rejection listing the Error violations
+func decide(policy Policy, introduced []meshv1alpha1.ConstraintViolation) (admission.Warnings, error) {
+	var warnings admission.Warnings
+	var errors []string
+	for _, violation := range introduced {
+		message := fmt.Sprintf("%s %s: %s", violation.Type, violation.Resource, violation.Message)
+		if violation.Severity == "Error" && policy == PolicyDeny {
+			errors = append(errors, message)
+			continue
+		}
+		warnings = append(warnings, message)
+	}
+
+	if len(errors) > 0 {
+		return warnings, fmt.Errorf("change introduces %d integrity violation(s): %s", len(errors), strings.Join(errors, "; "))
+	}
+	return warnings, nil
+}
@@ -0,0 +1,172 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	networking "istio.io/api/networking/v1beta1"
+	istio "istio.io/client-go/pkg/apis/networking/v1beta1"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/mdarin/istio-integrity-operator/internal/integrity"
+)
+
+func newTestValidator(t *testing.T, policy Policy, objects ...client.Object) *IntegrityValidator {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to register core types: %v", err)
+	}
+	if err := integrity.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to register mesh types: %v", err)
+	}
+	return &IntegrityValidator{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+		Policy: policy,
+	}
+}
+
+func testGateway() *istio.Gateway {
+	return &istio.Gateway{ObjectMeta: metav1.ObjectMeta{Namespace: "istio-system", Name: "public-gateway"}}
+}
+
+func testService() *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "web",
+			Annotations: map[string]string{"mesh.operator.istio.io/managed": "true"},
+		},
+		Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80}}},
+	}
+}
+
+func testVirtualService(gateway string) *istio.VirtualService {
+	return &istio.VirtualService{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+		Spec: networking.VirtualService{
+			Hosts:    []string{"web.example.com"},
+			Gateways: []string{gateway},
+			Http: []*networking.HTTPRoute{{
+				Route: []*networking.HTTPRouteDestination{{
+					Destination: &networking.Destination{Host: "web.default.svc.cluster.local"},
+				}},
+			}},
+		},
+	}
+}
+
+func TestIntegrityValidatorCreate(t *testing.T) {
+	ctx := context.Background()
+	validator := newTestValidator(t, PolicyDeny, testGateway(), testService())
+
+	if _, err := validator.ValidateCreate(ctx, testVirtualService("istio-system/public-gateway")); err != nil {
+		t.Errorf("Expected consistent VirtualService to be admitted, got %v", err)
+	}
+
+	_, err := validator.ValidateCreate(ctx, testVirtualService("istio-system/missing-gateway"))
+	if err == nil || !strings.Contains(err.Error(), "ForeignKeyViolation VirtualService/default/web") {
+		t.Errorf("Expected VirtualService with missing Gateway to be denied, got %v", err)
+	}
+}
+
+func TestIntegrityValidatorDelete(t *testing.T) {
+	ctx := context.Background()
+	service := testService()
+
+	// Service без ссылок удаляется без предупреждений
+	validator := newTestValidator(t, PolicyDeny, service)
+	if warnings, err := validator.ValidateDelete(ctx, service); err != nil || len(warnings) != 0 {
+		t.Errorf("Expected unreferenced Service to be deleted, got %v, %v", warnings, err)
+	}
+}
+
+func TestIntegrityValidatorDeleteWithDependents(t *testing.T) {
+	ctx := context.Background()
+	gateway := testGateway()
+
+	// Удаление запрещено независимо от политики
+	for _, policy := range []Policy{PolicyDeny, PolicyWarn} {
+		validator := newTestValidator(t, policy, gateway, testService(), testVirtualService("istio-system/public-gateway"))
+		_, err := validator.ValidateDelete(ctx, gateway)
+		if err == nil || !strings.Contains(err.Error(), "VirtualService/default/web") || !strings.Contains(err.Error(), "spec.gateways") {
+			t.Errorf("Expected deleting a referenced Gateway to be refused under %q policy, got %v", policy, err)
+		}
+	}
+
+	service := testService()
+	validator := newTestValidator(t, PolicyDeny, testGateway(), service, testVirtualService("istio-system/public-gateway"))
+	_, err := validator.ValidateDelete(ctx, service)
+	if err == nil || !strings.Contains(err.Error(), "http[0].route[0].destination.host") {
+		t.Errorf("Expected deleting a routed Service to be refused, got %v", err)
+	}
+
+	// Аннотация разрешает удаление, нарушения возвращаются предупреждениями
+	gateway.Annotations = map[string]string{DeletionOverrideAnnotation: "true"}
+	validator = newTestValidator(t, PolicyDeny, gateway, testService(), testVirtualService("istio-system/public-gateway"))
+	warnings, err := validator.ValidateDelete(ctx, gateway)
+	if err != nil {
+		t.Errorf("Expected override annotation to admit the deletion, got %v", err)
+	}
+	if len(warnings) < 2 || !strings.Contains(warnings[0], "referenced by 1 resource(s)") || !strings.Contains(warnings[1], "ForeignKeyViolation") {
+		t.Errorf("Expected dependents and ForeignKeyViolation warnings, got %v", warnings)
+	}
+}
+
+func TestIntegrityValidatorIgnoresExistingViolations(t *testing.T) {
+	ctx := context.Background()
+	// VirtualService уже ссылается на несуществующий Gateway: нарушение не вносится изменением Service
+	validator := newTestValidator(t, PolicyDeny, testService(), testVirtualService("istio-system/missing-gateway"))
+
+	service := testService()
+	service.Spec.Ports = append(service.Spec.Ports, corev1.ServicePort{Name: "grpc", Port: 9090})
+	if _, err := validator.ValidateUpdate(ctx, testService(), service); err != nil {
+		t.Errorf("Expected update not introducing violations to be admitted, got %v", err)
+	}
+}
+
+func TestIntegrityValidatorHandle(t *testing.T) {
+	ctx := context.Background()
+	validator := newTestValidator(t, PolicyDeny, testGateway(), testService())
+	request := func(operation admissionv1.Operation, object string) admission.Request {
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: operation,
+			Object:    runtime.RawExtension{Raw: []byte(object)},
+		}}
+	}
+
+	// networking.istio.io/v1 декодируется загрузчиком манифестов, как и v1beta1
+	response := validator.Handle(ctx, request(admissionv1.Create, `{
+		"apiVersion": "networking.istio.io/v1", "kind": "VirtualService",
+		"metadata": {"namespace": "default", "name": "web"},
+		"spec": {"hosts": ["web.example.com"], "gateways": ["istio-system/missing-gateway"]}
+	}`))
+	if response.Allowed || !strings.Contains(response.Result.Message, "ForeignKeyViolation VirtualService/default/web") {
+		t.Errorf("Expected networking.istio.io/v1 VirtualService with missing Gateway to be denied, got %+v", response.Result)
+	}
+
+	// Объект, который не удалось декодировать, пропускается с предупреждением
+	response = validator.Handle(ctx, request(admissionv1.Create, `{"apiVersion": "networking.istio.io/v2", "kind": "VirtualService", "metadata": {"name": "web"}}`))
+	if !response.Allowed || len(response.Warnings) != 1 || !strings.Contains(response.Warnings[0], "unsupported version") {
+		t.Errorf("Expected undecodable object to be admitted with a warning, got %+v", response)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for value, want := range map[string]Policy{"deny": PolicyDeny, "Warn": PolicyWarn} {
+		if got, err := ParsePolicy(value); err != nil || got != want {
+			t.Errorf("ParsePolicy(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	if _, err := ParsePolicy("audit"); err == nil {
+		t.Error("Expected unknown policy to be rejected")
+	}
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+)
+
+var meshservicelog = logf.Log.WithName("meshservice-webhook")
+
+// defaultProtocol is the protocol of MeshService ports that do not set one
+const defaultProtocol = "TCP"
+
+// portProtocols lists the Istio protocols of MeshService ports. Istio compares them
+// case-insensitively, the defaulter normalizes them to these spellings.
+var portProtocols = []string{"HTTP", "HTTPS", "HTTP2", "GRPC", "GRPC-Web", "TCP", "TLS", "MONGO", "MYSQL", "REDIS", "UDP"}
+
+// simpleLoadBalancers lists the values Istio accepts for loadBalancer.simple
+var simpleLoadBalancers = []string{"UNSPECIFIED", "RANDOM", "PASSTHROUGH", "ROUND_ROBIN", "LEAST_REQUEST", "LEAST_CONN"}
+
+// subsetWeightTotal is the sum subset weights must reach when any of them is set
+const subsetWeightTotal = 100
+
+// SetupMeshServiceWebhookWithManager registers the defaulting and validating webhooks for MeshService
+func SetupMeshServiceWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&meshv1alpha1.MeshService{}).
+		WithDefaulter(&MeshServiceCustomDefaulter{}).
+		WithValidator(&MeshServiceCustomValidator{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-mesh-istio-operator-v1alpha1-meshservice,mutating=true,failurePolicy=fail,sideEffects=None,groups=mesh.istio.operator,resources=meshservices,verbs=create;update,versions=v1alpha1,name=mmeshservice-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// MeshServiceCustomDefaulter fills the optional fields of MeshServiceSpec
+type MeshServiceCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &MeshServiceCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter
+func (d *MeshServiceCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	meshService, ok := obj.(*meshv1alpha1.MeshService)
+	if !ok {
+		return fmt.Errorf("expected a MeshService object but got %T", obj)
+	}
+	meshservicelog.V(1).Info("Defaulting MeshService", "namespace", meshService.Namespace, "name", meshService.Name)
+
+	// Service по умолчанию в namespace самого MeshService
+	if meshService.Spec.Namespace == "" {
+		meshService.Spec.Namespace = meshService.Namespace
+	}
+	for i := range meshService.Spec.Ports {
+		port := &meshService.Spec.Ports[i]
+		if port.Protocol == "" {
+			port.Protocol = defaultProtocol
+		}
+		if protocol, ok := portProtocol(port.Protocol); ok {
+			port.Protocol = protocol
+		}
+		if port.TargetPort == 0 {
+			port.TargetPort = port.Port
+		}
+		// Имя по соглашению Istio <protocol>-<suffix>, например http-80
+		if port.Name == "" {
+			port.Name = fmt.Sprintf("%s-%d", strings.ToLower(port.Protocol), port.Port)
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-mesh-istio-operator-v1alpha1-meshservice,mutating=false,failurePolicy=fail,sideEffects=None,groups=mesh.istio.operator,resources=meshservices,verbs=create;update,versions=v1alpha1,name=vmeshservice-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// MeshServiceCustomValidator validates MeshServiceSpec beyond what the CRD schema can express
+type MeshServiceCustomValidator struct{}
+
+var _ webhook.CustomValidator = &MeshServiceCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator
+func (v *MeshServiceCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, validateMeshService(obj)
+}
+
+// ValidateUpdate implements webhook.CustomValidator
+func (v *MeshServiceCustomValidator) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	return nil, validateMeshService(newObj)
+}
+
+// ValidateDelete implements webhook.CustomValidator
+func (v *MeshServiceCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func validateMeshService(obj runtime.Object) error {
+	meshService, ok := obj.(*meshv1alpha1.MeshService)
+	if !ok {
+		return fmt.Errorf("expected a MeshService object but got %T", obj)
+	}
+
+	errs := validateMeshServiceSpec(&meshService.Spec, field.NewPath("spec"))
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: meshv1alpha1.GroupVersion.Group, Kind: "MeshService"},
+		meshService.Name, errs)
+}
+
+// validateMeshServiceSpec проверяет ссылки на Service и Gateway, порты, hosts, subsets и load balancer
+func validateMeshServiceSpec(spec *meshv1alpha1.MeshServiceSpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	errs = append(errs, validateName(spec.ServiceName, validation.IsDNS1035Label, path.Child("serviceName"))...)
+	if spec.Namespace != "" {
+		errs = append(errs, validateName(spec.Namespace, validation.IsDNS1123Label, path.Child("namespace"))...)
+	}
+
+	// Порты
+	portsPath := path.Child("ports")
+	if len(spec.Ports) == 0 {
+		errs = append(errs, field.Required(portsPath, "at least one port is required"))
+	}
+	portNames := map[string]bool{}
+	for i, port := range spec.Ports {
+		portPath := portsPath.Index(i)
+		if port.Name == "" {
+			errs = append(errs, field.Required(portPath.Child("name"), ""))
+		} else if portNames[port.Name] {
+			errs = append(errs, field.Duplicate(portPath.Child("name"), port.Name))
+		}
+		portNames[port.Name] = true
+		for _, msg := range validation.IsValidPortNum(int(port.Port)) {
+			errs = append(errs, field.Invalid(portPath.Child("port"), port.Port, msg))
+		}
+		if port.TargetPort != 0 {
+			for _, msg := range validation.IsValidPortNum(int(port.TargetPort)) {
+				errs = append(errs, field.Invalid(portPath.Child("targetPort"), port.TargetPort, msg))
+			}
+		}
+		if _, ok := portProtocol(port.Protocol); port.Protocol != "" && !ok {
+			errs = append(errs, field.NotSupported(portPath.Child("protocol"), port.Protocol, portProtocols))
+		}
+	}
+
+	// Hosts: DNS-имена, допускается wildcard "*" и префикс "*."
+	for i, host := range spec.Hosts {
+		if host == "*" {
+			continue
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(strings.TrimPrefix(host, "*.")) {
+			errs = append(errs, field.Invalid(path.Child("hosts").Index(i), host, msg))
+		}
+	}
+
+	// Gateway
+	gatewayPath := path.Child("gateway")
+	if spec.Gateway.Name == "" {
+		errs = append(errs, field.Required(gatewayPath.Child("name"), "gateway name is required"))
+	} else {
+		errs = append(errs, validateName(spec.Gateway.Name, validation.IsDNS1123Subdomain, gatewayPath.Child("name"))...)
+	}
+	if spec.Gateway.Namespace != "" {
+		errs = append(errs, validateName(spec.Gateway.Namespace, validation.IsDNS1123Label, gatewayPath.Child("namespace"))...)
+	}
+
+	// Load balancer
+	if spec.TrafficPolicy != nil && spec.TrafficPolicy.LoadBalancer != nil {
+		if simple := spec.TrafficPolicy.LoadBalancer.Simple; simple != "" {
+			if !slices.Contains(simpleLoadBalancers, simple) {
+				errs = append(errs, field.NotSupported(path.Child("trafficPolicy", "loadBalancer", "simple"), simple, simpleLoadBalancers))
+			}
+		}
+	}
+
+	// Subsets: уникальные имена, веса 0..100 и в сумме 100, если хотя бы один задан
+	subsetsPath := path.Child("subsets")
+	subsetNames := map[string]bool{}
+	var total int32
+	for i, subset := range spec.Subsets {
+		subsetPath := subsetsPath.Index(i)
+		if subset.Name == "" {
+			errs = append(errs, field.Required(subsetPath.Child("name"), ""))
+		} else if subsetNames[subset.Name] {
+			errs = append(errs, field.Duplicate(subsetPath.Child("name"), subset.Name))
+		}
+		subsetNames[subset.Name] = true
+		if subset.Weight < 0 || subset.Weight > subsetWeightTotal {
+			errs = append(errs, field.Invalid(subsetPath.Child("weight"), subset.Weight, fmt.Sprintf("must be between 0 and %d", subsetWeightTotal)))
+		}
+		total += subset.Weight
+	}
+	if total != 0 && total != subsetWeightTotal {
+		errs = append(errs, field.Invalid(subsetsPath, total, fmt.Sprintf("subset weights must sum to %d", subsetWeightTotal)))
+	}
+
+	return errs
+}
+
+// portProtocol returns the spelling of the protocol in portProtocols, ignoring case
+func portProtocol(protocol string) (string, bool) {
+	for _, known := range portProtocols {
+		if strings.EqualFold(known, protocol) {
+			return known, true
+		}
+	}
+	return "", false
+}
+
+// validateName reports the messages of a k8s.io/apimachinery name validation function as field errors
+func validateName(value string, validate func(string) []string, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for _, msg := range validate(value) {
+		errs = append(errs, field.Invalid(path, value, msg))
+	}
+	return errs
+}
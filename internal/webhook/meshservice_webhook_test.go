@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	meshv1alpha1 "github.com/mdarin/istio-integrity-operator/api/v1alpha1"
+)
+
+func testMeshService() *meshv1alpha1.MeshService {
+	return &meshv1alpha1.MeshService{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "reviews"},
+		Spec: meshv1alpha1.MeshServiceSpec{
+			ServiceName: "reviews",
+			Ports:       []meshv1alpha1.ServicePort{{Name: "http", Port: 9080}},
+			Hosts:       []string{"reviews.example.com", "*.shop.example.com"},
+			Gateway:     meshv1alpha1.GatewayReference{Name: "public", Namespace: "istio-system"},
+			Subsets: []meshv1alpha1.Subset{
+				{Name: "v1", Labels: map[string]string{"version": "v1"}, Weight: 90},
+				{Name: "v2", Labels: map[string]string{"version": "v2"}, Weight: 10},
+			},
+		},
+	}
+}
+
+func TestMeshServiceDefault(t *testing.T) {
+	meshService := testMeshService()
+	meshService.Spec.Ports = append(meshService.Spec.Ports,
+		meshv1alpha1.ServicePort{Name: "grpc", Port: 9090, TargetPort: 19090, Protocol: "GRPC"},
+		meshv1alpha1.ServicePort{Port: 8080, Protocol: "http"})
+
+	if err := (&MeshServiceCustomDefaulter{}).Default(context.Background(), meshService); err != nil {
+		t.Fatalf("Failed to default MeshService: %v", err)
+	}
+	if meshService.Spec.Namespace != "shop" {
+		t.Errorf("Expected namespace to default to shop, got %q", meshService.Spec.Namespace)
+	}
+	if port := meshService.Spec.Ports[0]; port.Protocol != "TCP" || port.TargetPort != 9080 {
+		t.Errorf("Expected TCP protocol and targetPort 9080, got %s %d", port.Protocol, port.TargetPort)
+	}
+	if port := meshService.Spec.Ports[1]; port.Protocol != "GRPC" || port.TargetPort != 19090 {
+		t.Errorf("Expected explicit protocol and targetPort to be kept, got %s %d", port.Protocol, port.TargetPort)
+	}
+	if port := meshService.Spec.Ports[2]; port.Name != "http-8080" || port.Protocol != "HTTP" {
+		t.Errorf("Expected name http-8080 and protocol HTTP, got %s %s", port.Name, port.Protocol)
+	}
+}
+
+func TestMeshServiceValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(*meshv1alpha1.MeshServiceSpec)
+		expected string // фрагмент ошибки, "" для корректного spec
+	}{
+		{"valid", func(*meshv1alpha1.MeshServiceSpec) {}, ""},
+		{"no subset weights", func(spec *meshv1alpha1.MeshServiceSpec) {
+			spec.Subsets[0].Weight, spec.Subsets[1].Weight = 0, 0
+		}, ""},
+		{"empty ports", func(spec *meshv1alpha1.MeshServiceSpec) { spec.Ports = nil }, "spec.ports: Required value"},
+		{"duplicate port name", func(spec *meshv1alpha1.MeshServiceSpec) {
+			spec.Ports = append(spec.Ports, meshv1alpha1.ServicePort{Name: "http", Port: 8080})
+		}, `spec.ports[1].name: Duplicate value: "http"`},
+		{"port out of range", func(spec *meshv1alpha1.MeshServiceSpec) { spec.Ports[0].Port = 70000 }, "spec.ports[0].port: Invalid value: 70000"},
+		{"lowercase protocol", func(spec *meshv1alpha1.MeshServiceSpec) { spec.Ports[0].Protocol = "grpc-web" }, ""},
+		{"unknown protocol", func(spec *meshv1alpha1.MeshServiceSpec) { spec.Ports[0].Protocol = "QUIC" }, `spec.ports[0].protocol: Unsupported value: "QUIC"`},
+		{"invalid host", func(spec *meshv1alpha1.MeshServiceSpec) { spec.Hosts = append(spec.Hosts, "Reviews_Example") }, `spec.hosts[2]: Invalid value: "Reviews_Example"`},
+		{"gateway without name", func(spec *meshv1alpha1.MeshServiceSpec) { spec.Gateway.Name = "" }, "spec.gateway.name: Required value"},
+		{"weights not summing to 100", func(spec *meshv1alpha1.MeshServiceSpec) { spec.Subsets[1].Weight = 20 }, "spec.subsets: Invalid value: 110: subset weights must sum to 100"},
+		{"unknown load balancer", func(spec *meshv1alpha1.MeshServiceSpec) {
+			spec.TrafficPolicy = &meshv1alpha1.TrafficPolicy{LoadBalancer: &meshv1alpha1.LoadBalancerSettings{Simple: "FASTEST"}}
+		}, `spec.trafficPolicy.loadBalancer.simple: Unsupported value: "FASTEST"`},
+	}
+
+	validator := &MeshServiceCustomValidator{}
+	for _, tt := range tests {
+		meshService := testMeshService()
+		tt.mutate(&meshService.Spec)
+
+		_, err := validator.ValidateCreate(context.Background(), meshService)
+		switch {
+		case tt.expected == "" && err != nil:
+			t.Errorf("%s: expected MeshService to be valid, got %v", tt.name, err)
+		case tt.expected != "" && (err == nil || !strings.Contains(err.Error(), tt.expected)):
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.expected, err)
+		}
+	}
+}